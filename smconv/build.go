@@ -0,0 +1,94 @@
+// SNESMOD
+// (C) 2025 Mukunda Johnson (mukunda.com)
+// Licensed under MIT
+
+package main
+
+import (
+	"flag"
+	"os"
+
+	"go.mukunda.com/snesmod/smconv/clog"
+	"go.mukunda.com/snesmod/smconv/smconv"
+)
+
+// Entry point for "smconv build". Builds the soundbank described by a manifest file.
+func buildCli(args []string) int {
+	flags := flag.NewFlagSet(os.Args[0]+" build", flag.ContinueOnError)
+	verbose := flags.Bool("v", false, "Verbose output")
+	flags.BoolVar(verbose, "verbose", false, "Verbose output")
+	werror := flags.Bool("werror", false, "Treat warnings as errors")
+	format := flags.String("diagnostics", "text", "Diagnostics format (text or json)")
+	spcModule := flags.String("module", "", "Module to write an SPC file for")
+	spcPosition := flags.Int("position", 0, "Sequence position to start the SPC at")
+	allSpc := flags.Bool("all-spc", false, "Write an SPC file for every module")
+	tags := smconv.ModuleTags{}
+	addTagFlags(flags, &tags)
+
+	if err := flags.Parse(args); err != nil {
+		clog.Errorf("%v\n", err)
+		return 1
+	}
+
+	if *format != "text" && *format != "json" {
+		clog.Errorf("invalid diagnostics format: %s\n", *format)
+		return 1
+	}
+
+	if err := tags.Validate(); err != nil {
+		clog.Errorf("%v\n", err)
+		return 1
+	}
+
+	if (*spcPosition != 0 || tags != smconv.ModuleTags{}) && *spcModule == "" && !*allSpc {
+		clog.Errorln("--position and SPC tags need --module or --all-spc.")
+		return 1
+	}
+
+	if flags.NArg() != 1 {
+		clog.Errorln("Expected one manifest file.")
+		return 1
+	}
+
+	manifest, err := smconv.LoadManifest(flags.Arg(0))
+	if err != nil {
+		clog.Errorf("Error loading manifest: %v\n", err)
+		return 1
+	}
+
+	clog.Infoln("Building soundbank:", flags.Arg(0))
+	bank, err := manifest.Build()
+	if err != nil {
+		clog.Errorf("%v\n", err)
+		return 1
+	}
+
+	if *verbose {
+		for _, mod := range bank.Modules {
+			clog.Infoln("Module:", mod.Filename)
+			logModuleReport(bank, mod)
+		}
+	}
+
+	diags := bank.Diagnostics()
+	printDiagnostics(diags, *format)
+
+	if smconv.HasErrors(diags) || (*werror && len(diags) > 0) {
+		clog.Errorln("Conversion failed due to diagnostics.")
+		return 1
+	}
+
+	clog.Infoln("Exporting sound bank.")
+	if err := exportLockedSoundbank(bank, manifest.Output, manifest.HiRom, manifest.Lock); err != nil {
+		clog.Errorf("Error exporting sound bank: %v\n", err)
+		return 1
+	}
+
+	spcOpts := smconv.SpcOptions{Position: *spcPosition, Tags: tags}
+	if err := exportBankSpcFiles(bank, manifest.Output, *spcModule, *allSpc, spcOpts); err != nil {
+		clog.Errorf("Error writing SPC file: %v\n", err)
+		return 1
+	}
+
+	return 0
+}
@@ -14,6 +14,10 @@ func Infoln(a ...any) {
 	fmt.Fprintln(os.Stderr, append([]any{"INFO"}, a...)...)
 }
 
+func Warnln(a ...any) {
+	fmt.Fprintln(os.Stderr, append([]any{"WARN"}, a...)...)
+}
+
 func Errorln(a ...any) {
 	fmt.Fprintln(os.Stderr, append([]any{"ERR "}, a...)...)
 }
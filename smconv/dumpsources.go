@@ -0,0 +1,109 @@
+// SNESMOD
+// (C) 2025 Mukunda Johnson (mukunda.com)
+// Licensed under MIT
+
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.mukunda.com/modlib"
+	"go.mukunda.com/snesmod/smconv/clog"
+	"go.mukunda.com/snesmod/smconv/smconv"
+)
+
+// Entry point for "smconv dump-sources". Converts modules and sound effects, or reads an
+// exported soundbank, and writes each BRR source decoded to a WAV file.
+func dumpSourcesCli(args []string) int {
+	flags := flag.NewFlagSet(os.Args[0]+" dump-sources", flag.ContinueOnError)
+	outputDir := flags.String("o", "", "Output directory")
+	flags.StringVar(outputDir, "output", "", "Output directory")
+	effectFiles := stringList{}
+	flags.Var(&effectFiles, "e", "Sound effect input")
+	flags.Var(&effectFiles, "effects", "Sound effect input")
+	effectRate := flags.Int("rate", 0, "Sample rate for WAV sound effects")
+	hirom := flags.Bool("h", false, "The soundbank uses HIROM mapping")
+	flags.BoolVar(hirom, "hirom", false, "The soundbank uses HIROM mapping")
+
+	if err := flags.Parse(args); err != nil {
+		clog.Errorf("%v\n", err)
+		return 1
+	}
+
+	if *outputDir == "" {
+		clog.Errorln("Output directory (-o) is required.")
+		return 1
+	}
+
+	if *effectRate < 0 {
+		clog.Errorf("invalid sample rate: %d\n", *effectRate)
+		return 1
+	}
+
+	inputFiles := flags.Args()
+	if len(inputFiles) == 0 && len(effectFiles) == 0 {
+		clog.Errorln("No input files specified.")
+		return 1
+	}
+
+	var bank *smconv.SoundBank
+	if len(inputFiles) == 1 && strings.EqualFold(filepath.Ext(inputFiles[0]), ".smbank") {
+		if len(effectFiles) > 0 {
+			clog.Errorln("Sound effects (-e) can't be added to an exported soundbank.")
+			return 1
+		}
+
+		file, err := os.Open(inputFiles[0])
+		if err != nil {
+			clog.Errorf("%v\n", err)
+			return 1
+		}
+		defer file.Close()
+
+		if bank, err = smconv.ReadSoundBank(file, *hirom); err != nil {
+			clog.Errorf("Error reading soundbank %s: %v\n", inputFiles[0], err)
+			return 1
+		}
+	} else {
+		bank = &smconv.SoundBank{}
+		for _, input := range inputFiles {
+			inputFile, id := splitInputId(input)
+			clog.Infoln("Loading module:", inputFile)
+			mod, err := modlib.LoadModule(inputFile)
+			if err != nil {
+				clog.Errorf("Error loading module %s: %v\n", inputFile, err)
+				return 1
+			}
+
+			err = bank.AddModuleWithOptions(mod, inputFile, smconv.ModuleOptions{Id: id})
+			if err != nil {
+				clog.Errorf("Error converting module %s: %v\n", inputFile, err)
+				return 1
+			}
+		}
+
+		for _, effect := range effectFiles {
+			effectFile, id := splitInputId(effect)
+			clog.Infoln("Loading sound effects:", effectFile)
+			err := bank.AddSoundEffectFile(effectFile, smconv.EffectOptions{Id: id, Rate: *effectRate})
+			if err != nil {
+				clog.Errorf("Error converting sound effects %s: %v\n", effectFile, err)
+				return 1
+			}
+		}
+	}
+
+	sources, err := bank.DumpSources(*outputDir)
+	if err != nil {
+		clog.Errorf("Error writing sources: %v\n", err)
+		return 1
+	}
+	for _, source := range sources {
+		clog.Infoln(source)
+	}
+
+	return 0
+}
@@ -0,0 +1,85 @@
+// SNESMOD
+// (C) 2025 Mukunda Johnson (mukunda.com)
+// Licensed under MIT
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"go.mukunda.com/modlib"
+	"go.mukunda.com/snesmod/smconv/clog"
+	"go.mukunda.com/snesmod/smconv/smconv"
+)
+
+// Changes made to one module, for the JSON output.
+type moduleFidelity struct {
+	File    string                  `json:"file"`
+	Changes []smconv.FidelityChange `json:"changes"`
+}
+
+// Entry point for "smconv fidelity". Prints the changes that conversion makes to each
+// module. Returns 1 only if a module fails to load, since the changes aren't errors.
+func fidelityCli(args []string) int {
+	flags := flag.NewFlagSet(os.Args[0]+" fidelity", flag.ContinueOnError)
+	format := flags.String("format", "text", "Output format (text or json)")
+
+	if err := flags.Parse(args); err != nil {
+		clog.Errorf("%v\n", err)
+		return 1
+	}
+
+	if *format != "text" && *format != "json" {
+		clog.Errorf("invalid format: %s\n", *format)
+		return 1
+	}
+
+	inputFiles := flags.Args()
+	if len(inputFiles) == 0 {
+		clog.Errorln("No input files specified.")
+		return 1
+	}
+
+	status := 0
+	reports := []moduleFidelity{}
+
+	for _, inputFile := range inputFiles {
+		mod, err := modlib.LoadModule(inputFile)
+		if err != nil {
+			clog.Errorf("Error loading module %s: %v\n", inputFile, err)
+			status = 1
+			continue
+		}
+
+		changes := smconv.FidelityReport(mod)
+		if changes == nil {
+			changes = []smconv.FidelityChange{}
+		}
+		reports = append(reports, moduleFidelity{File: inputFile, Changes: changes})
+	}
+
+	if *format == "json" {
+		data, err := json.MarshalIndent(reports, "", "  ")
+		if err != nil {
+			clog.Errorf("%v\n", err)
+			return 1
+		}
+		fmt.Println(string(data))
+		return status
+	}
+
+	for _, report := range reports {
+		if len(report.Changes) == 0 {
+			fmt.Printf("%s: converts without changes\n", report.File)
+			continue
+		}
+		for _, change := range report.Changes {
+			fmt.Printf("%s: %s\n", report.File, change)
+		}
+	}
+
+	return status
+}
@@ -3,10 +3,12 @@ module go.mukunda.com/snesmod/smconv
 go 1.23.5
 
 require (
+	github.com/BurntSushi/toml v1.5.0
 	github.com/stretchr/testify v1.10.0
 	go.mukunda.com/errorcat v0.2.0
 	go.mukunda.com/modlib v0.1.0
 	go.mukunda.com/snesbrr/v2 v2.0.1
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -15,5 +17,4 @@ require (
 	github.com/go-audio/riff v1.0.0 // indirect
 	github.com/go-audio/wav v1.1.0 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -0,0 +1,69 @@
+// SNESMOD
+// (C) 2025 Mukunda Johnson (mukunda.com)
+// Licensed under MIT
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"go.mukunda.com/snesmod/smconv/clog"
+	"go.mukunda.com/snesmod/smconv/smconv"
+)
+
+// Entry point for "smconv inspect". Prints the contents of a soundbank and returns 1 if
+// it has any problems.
+func inspectCli(args []string) int {
+	flags := flag.NewFlagSet(os.Args[0]+" inspect", flag.ContinueOnError)
+	hirom := flags.Bool("h", false, "The soundbank uses HIROM mapping")
+	flags.BoolVar(hirom, "hirom", false, "The soundbank uses HIROM mapping")
+	format := flags.String("format", "text", "Output format (text or json)")
+	soundRegion := flags.Int("sound-region", 0, "Sound region size in 256-byte pages")
+
+	if err := flags.Parse(args); err != nil {
+		clog.Errorf("%v\n", err)
+		return 1
+	}
+
+	if *format != "text" && *format != "json" {
+		clog.Errorf("invalid output format: %s\n", *format)
+		return 1
+	}
+
+	if flags.NArg() != 1 {
+		clog.Errorln("Expected one soundbank file.")
+		return 1
+	}
+
+	file, err := os.Open(flags.Arg(0))
+	if err != nil {
+		clog.Errorf("%v\n", err)
+		return 1
+	}
+	defer file.Close()
+
+	info, err := smconv.InspectSoundBank(file, *hirom, *soundRegion)
+	if err != nil {
+		clog.Errorf("%v\n", err)
+		return 1
+	}
+
+	if *format == "json" {
+		data, err := json.MarshalIndent(info, "", "  ")
+		if err != nil {
+			clog.Errorf("%v\n", err)
+			return 1
+		}
+		fmt.Println(string(data))
+	} else {
+		fmt.Println(info)
+	}
+
+	if len(info.Problems) > 0 {
+		return 1
+	}
+	return 0
+}
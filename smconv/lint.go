@@ -0,0 +1,69 @@
+// SNESMOD
+// (C) 2025 Mukunda Johnson (mukunda.com)
+// Licensed under MIT
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"go.mukunda.com/modlib"
+	"go.mukunda.com/snesmod/smconv/clog"
+	"go.mukunda.com/snesmod/smconv/smconv"
+)
+
+// Entry point for "smconv lint". Returns 1 if any module fails to load or breaks a
+// composition rule.
+func lintCli(args []string) int {
+	flags := flag.NewFlagSet(os.Args[0]+" lint", flag.ContinueOnError)
+	format := flags.String("diagnostics", "text", "Diagnostics format (text or json)")
+
+	if err := flags.Parse(args); err != nil {
+		clog.Errorf("%v\n", err)
+		return 1
+	}
+
+	if *format != "text" && *format != "json" {
+		clog.Errorf("invalid diagnostics format: %s\n", *format)
+		return 1
+	}
+
+	inputFiles := flags.Args()
+	if len(inputFiles) == 0 {
+		clog.Errorln("No input files specified.")
+		return 1
+	}
+
+	status := 0
+	diags := []smconv.Diagnostic{}
+
+	for _, inputFile := range inputFiles {
+		mod, err := modlib.LoadModule(inputFile)
+		if err != nil {
+			clog.Errorf("Error loading module %s: %v\n", inputFile, err)
+			status = 1
+			continue
+		}
+
+		for _, d := range smconv.LintModule(mod) {
+			d.Location.File = inputFile
+			diags = append(diags, d)
+		}
+	}
+
+	if *format == "json" {
+		printDiagnostics(diags, *format)
+	} else {
+		for _, d := range diags {
+			fmt.Println(d)
+		}
+	}
+
+	if len(diags) > 0 {
+		status = 1
+	}
+
+	return status
+}
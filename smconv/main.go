@@ -9,9 +9,12 @@ included in a game.
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 
 	"go.mukunda.com/modlib"
@@ -20,11 +23,99 @@ import (
 )
 
 const shortUsage = `Usage: smconv [options] input
+       smconv lint input...
+       smconv build [options] manifest
+       smconv inspect [options] soundbank
+       smconv spc-tag [options] file.spc...
+       smconv render [options] input
+       smconv dump-sources [options] input...
+       smconv preview [options] input
+       smconv fidelity [options] input...
 Use --help for more info.`
 
 const usage = `SNESMOD (C) 2025 Mukunda Johnson (mukunda.com)
 
 Usage: smconv [options] input
+       smconv lint input...
+       smconv build [options] manifest
+       smconv inspect [options] soundbank
+       smconv spc-tag [options] file.spc...
+       smconv render [options] input
+       smconv dump-sources [options] input...
+       smconv preview [options] input
+       smconv fidelity [options] input...
+
+Commands
+--------
+
+lint
+   Check modules against the SNESMOD composition rules
+   without converting them. See doc/snesmod_music.txt.
+   Exits with an error status if any problems are found.
+   Accepts --diagnostics.
+
+build
+   Build a soundbank from a manifest file (.yaml, .json,
+   or .toml) that lists the output, mapping mode, modules,
+   and sound effects. Accepts -v, --werror,
+   --diagnostics, --module, --position, --all-spc, and
+   the SPC tag options.
+   See README.md for the manifest format.
+
+inspect
+   Print the contents of a .smbank file: the pointer
+   tables, module headers, and which modules share which
+   sources. Checks the pointers, sizes, and BRR data, and
+   exits with an error status if there are problems.
+   Accepts -h/--hirom for HIROM soundbanks,
+   --sound-region, and --format=<text|json>.
+
+spc-tag
+   Set the tags in existing SPC files without converting
+   them again. Accepts the SPC tag options, and only the
+   tags that are given are changed. The ID666 tags are
+   rewritten in the text format, and the extended tags
+   are updated if the file has them. The rest of the file
+   is kept as is.
+
+render
+   Play a module or an SPC file in the built-in SNES
+   emulator and write the audio to a WAV file, so a song
+   can be heard without an SNES emulator. Modules are
+   played by the real SNESMOD driver. The output is named
+   after the input unless -o is given. Accepts --position
+   (modules only), --length, and --fade. By default, a
+   module plays its intro and one loop.
+
+dump-sources
+   Write every BRR source in a soundbank to a WAV file in
+   the directory given with -o, decoded the way the SNES
+   plays it, to hear the compression and loop seams. The
+   inputs are modules and -e sound effects like soundbank
+   mode, or one .smbank file (with -h for HIROM). The loop
+   and tuning are saved in a smpl chunk. Module samples
+   play at C-5 at the WAV rate, and sound effects at the
+   default pitch (32000 Hz).
+
+preview
+   Convert a module and write it back to an IT file with
+   only what the SNES plays, to hear the conversion losses
+   in a tracker: the decoded BRR samples, one sample per
+   instrument (the middle of the note map), the truncated
+   volume envelopes, the fadeout at the driver's
+   resolution, and the first 8 channels without the
+   effects the driver ignores. The output is named
+   <input>-preview.it unless -o is given.
+
+fidelity
+   List the changes that conversion makes to modules and
+   how they sound different on the SNES: rounded fadeouts,
+   truncated envelopes, dropped envelopes and channels,
+   8-bit and stereo samples, and unrolled or resampled
+   loops with the tuning error. These aren't rule
+   violations (see lint), so the exit status is only
+   an error if a module can't be loaded. Accepts
+   --format=<text|json>.
 
 Options
 -------
@@ -35,6 +126,21 @@ Options
    it is "SPC" creation mode and one input file can be
    specified to be converted to SPC.
   
+-e, --effects <file>
+   Soundbank mode only. Adds every sample in the module
+   <file> to the soundbank as a sound effect, named
+   SFX_<sample name> in the definitions file. A .wav or
+   .brr file is added as a single effect named
+   SFX_<file name>. BRR data is used as is. Can be used
+   more than once. A WAV smpl loop is kept; loops in
+   module samples and BRR files are dropped.
+
+--rate <hz>
+   Resample WAV sound effects to this rate to save memory.
+   Effects play at 32000 Hz at the default pitch (8).
+   The unity note in a WAV smpl chunk is tuned to C-5 at
+   this rate, or at the file's own rate without --rate.
+
 -o, --output
    For soundbank creation mode, this is the base filename.
    Required for soundbank creation.
@@ -46,7 +152,75 @@ Options
    Use HIROM mapping mode for the soundbank.
 
 -v, --verbose
-   Enable verbose output.
+   Enable verbose output. Prints a conversion report with
+   the SPC memory used by each module.
+
+--lock <file>
+   Soundbank mode only. Pins the indices of modules and
+   sound effects in the definitions file to those in the
+   lockfile, so adding inputs doesn't renumber existing
+   IDs. New IDs are appended and saved to the lockfile,
+   which is created if it doesn't exist. Removed IDs are
+   reported and keep their index reserved until they are
+   deleted from the lockfile.
+
+--module-prefix <prefix>, --effect-prefix <prefix>
+   Soundbank mode only. Prefixes for IDs made from file
+   and sample names. The defaults are MOD_ and SFX_.
+
+--id-collisions=<error|rename>
+   Soundbank mode only. What to do when two inputs get the
+   same ID, e.g. town/theme.it and battle/theme.it. "error"
+   (default) fails the conversion. "rename" adds a number
+   to the later IDs (MOD_THEME_2). IDs given explicitly are
+   never renamed.
+
+--sound-region <pages>
+   Soundbank mode only. The size of the sound region that
+   the game allocates with spcAllocateSoundRegion, in
+   256-byte pages. The sound region and the echo buffer
+   below it are at the top of SPC memory, and conversion
+   fails if a module and its samples would overlap them.
+
+--position <order>
+   Start the SPC at this position in the sequence instead
+   of the beginning.
+
+--module <id|index>
+   Soundbank mode only. Also write an SPC file for this
+   module, named <output>-<ID>.spc. The module is
+   exported from the final soundbank, so it matches the
+   shipped data exactly.
+
+--all-spc
+   Soundbank mode only. Write an SPC file for every module
+   in the soundbank, named like --module. All of them get
+   the same tags and date.
+
+--title, --game, --artist, --dumper, --comment <text>
+   ID666 tags for the SPC files. By default, the title is
+   the module title, the artist and game come from the
+   "author" and "game" commands in the song message, the
+   comment is the first line of the song message, and the
+   game and dumper are "SNESMOD".
+
+--date <YYYY-MM-DD>
+   Date for the SPC files. Defaults to SOURCE_DATE_EPOCH
+   if it's set, or the current date.
+
+--length <seconds>, --fade <ms>
+   Play time before fading out, and the fade length. By
+   default, the play time covers the song's intro and one
+   loop, measured from the start position.
+
+--werror
+   Treat warnings as errors. Conversion fails if any
+   diagnostics are reported.
+
+--diagnostics=<text|json>
+   Format for warnings and errors found during conversion.
+   "text" (default) prints them to stderr. "json" prints
+   them to stdout as a JSON array, for CI annotations.
 
 --help
    Show Help
@@ -54,8 +228,49 @@ Options
 Example to create a soundbank for a project:
   smconv -s -o build/soundbank -h input1.it input2.it
 
+Inputs and effects can be given an explicit ID with
+<file>=<ID>:
+  smconv -s -o build/soundbank town/theme.it=MOD_TOWN
+
+Example to create a soundbank with sound effects:
+  smconv -s -o build/soundbank -e sfx.it input1.it
+
 Example to convert IT to SPC:
-  smconv input.it`
+  smconv input.it
+
+Example to tag an SPC for a soundtrack release:
+  smconv --game "Quest" --artist "Someone" -o town.spc \
+    town.it
+
+Example to listen to a song in a soundbank from its
+third position:
+  smconv -s -o build/soundbank --module MOD_TOWN \
+    --position 2 town.it battle.it
+
+Example to check a module before conversion:
+  smconv lint input.it
+
+Example to build a soundbank from a manifest:
+  smconv build soundbank.yaml
+
+Example to check an exported soundbank:
+  smconv inspect build/soundbank.smbank
+
+Example to fix the artist in a soundtrack release:
+  smconv spc-tag --artist "Someone" ost/*.spc
+
+Example to listen to a module as the SNES plays it:
+  smconv render -o town.wav town.it
+
+Example to listen to the samples in a soundbank:
+  smconv dump-sources -o build/sources -e sfx.it \
+    town.it battle.it
+
+Example to hear the conversion losses in a tracker:
+  smconv preview town.it
+
+Example to see why a song sounds different on the SNES:
+  smconv fidelity town.it`
 
 type programArgs struct {
 	Help          bool
@@ -63,9 +278,34 @@ type programArgs struct {
 	OutputFile    string
 	HiRom         bool
 	VerboseMode   bool
+	Werror        bool
+	Diagnostics   string
+	EffectFiles   stringList
+	EffectRate    int
+	LockFile      string
+	ModulePrefix  string
+	EffectPrefix  string
+	IdCollisions  string
+	SoundRegion   int
+	SpcModule     string
+	SpcPosition   int
+	AllSpc        bool
+	Tags          smconv.ModuleTags
 	InputFiles    []string
 }
 
+// A flag that can be given more than once.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
 func parseArgs(argStrings []string) (*programArgs, error) {
 	cfg := &programArgs{}
 
@@ -74,12 +314,26 @@ func parseArgs(argStrings []string) (*programArgs, error) {
 	// Define flags
 	flags.BoolVar(&cfg.SoundbankMode, "s", false, "Soundbank creation mode")
 	flags.BoolVar(&cfg.SoundbankMode, "soundbank", false, "Soundbank creation mode")
+	flags.Var(&cfg.EffectFiles, "e", "Sound effect input")
+	flags.Var(&cfg.EffectFiles, "effects", "Sound effect input")
+	flags.IntVar(&cfg.EffectRate, "rate", 0, "Sample rate for WAV sound effects")
+	flags.StringVar(&cfg.LockFile, "lock", "", "ID lockfile")
+	flags.StringVar(&cfg.ModulePrefix, "module-prefix", "", "Prefix for module IDs")
+	flags.StringVar(&cfg.EffectPrefix, "effect-prefix", "", "Prefix for sound effect IDs")
+	flags.StringVar(&cfg.IdCollisions, "id-collisions", "error", "ID collision handling (error or rename)")
+	flags.IntVar(&cfg.SoundRegion, "sound-region", 0, "Sound region size in 256-byte pages")
+	flags.StringVar(&cfg.SpcModule, "module", "", "Module to write an SPC file for")
+	flags.IntVar(&cfg.SpcPosition, "position", 0, "Sequence position to start the SPC at")
+	flags.BoolVar(&cfg.AllSpc, "all-spc", false, "Write an SPC file for every module")
+	addTagFlags(flags, &cfg.Tags)
 	flags.StringVar(&cfg.OutputFile, "o", "", "Output file")
 	flags.StringVar(&cfg.OutputFile, "output", "", "Output file")
 	flags.BoolVar(&cfg.HiRom, "h", false, "Use HIROM mapping (larger banks)")
 	flags.BoolVar(&cfg.HiRom, "hirom", false, "Use HIROM mapping (larger banks)")
 	flags.BoolVar(&cfg.VerboseMode, "v", false, "Verbose output")
 	flags.BoolVar(&cfg.VerboseMode, "verbose", false, "Verbose output")
+	flags.BoolVar(&cfg.Werror, "werror", false, "Treat warnings as errors")
+	flags.StringVar(&cfg.Diagnostics, "diagnostics", "text", "Diagnostics format (text or json)")
 	flags.BoolVar(&cfg.Help, "?", false, "Show help")
 	flags.BoolVar(&cfg.Help, "help", false, "Show help")
 
@@ -88,6 +342,36 @@ func parseArgs(argStrings []string) (*programArgs, error) {
 		return nil, err
 	}
 
+	if cfg.Diagnostics != "text" && cfg.Diagnostics != "json" {
+		return nil, fmt.Errorf("invalid diagnostics format: %s", cfg.Diagnostics)
+	}
+
+	if cfg.IdCollisions != "error" && cfg.IdCollisions != "rename" {
+		return nil, fmt.Errorf("invalid id collision mode: %s", cfg.IdCollisions)
+	}
+
+	for _, prefix := range []string{cfg.ModulePrefix, cfg.EffectPrefix} {
+		if prefix != "" && !smconv.IsValidId(prefix) {
+			return nil, fmt.Errorf("invalid id prefix: %s", prefix)
+		}
+	}
+
+	if cfg.SpcPosition < 0 || cfg.SpcPosition > 255 {
+		return nil, fmt.Errorf("invalid position: %d", cfg.SpcPosition)
+	}
+
+	if err := cfg.Tags.Validate(); err != nil {
+		return nil, err
+	}
+
+	if cfg.SoundRegion < 0 || cfg.SoundRegion > 255 {
+		return nil, fmt.Errorf("invalid sound region size: %d", cfg.SoundRegion)
+	}
+
+	if cfg.EffectRate < 0 {
+		return nil, fmt.Errorf("invalid sample rate: %d", cfg.EffectRate)
+	}
+
 	// Get remaining arguments as input files
 	cfg.InputFiles = flags.Args()
 
@@ -96,6 +380,27 @@ func parseArgs(argStrings []string) (*programArgs, error) {
 
 // Main entry point.
 func smconvCli(args []string) int {
+	if len(args) > 0 {
+		switch args[0] {
+		case "lint":
+			return lintCli(args[1:])
+		case "build":
+			return buildCli(args[1:])
+		case "inspect":
+			return inspectCli(args[1:])
+		case "spc-tag":
+			return spcTagCli(args[1:])
+		case "render":
+			return renderCli(args[1:])
+		case "dump-sources":
+			return dumpSourcesCli(args[1:])
+		case "preview":
+			return previewCli(args[1:])
+		case "fidelity":
+			return fidelityCli(args[1:])
+		}
+	}
+
 	cfg, err := parseArgs(args)
 
 	if err != nil {
@@ -110,17 +415,51 @@ func smconvCli(args []string) int {
 	}
 
 	// Validate arguments
-	if len(cfg.InputFiles) == 0 {
+	if len(cfg.InputFiles) == 0 && len(cfg.EffectFiles) == 0 {
 		clog.Errorln("No input files specified.")
 		return 1
 	}
 
+	if !cfg.SoundbankMode && len(cfg.EffectFiles) > 0 {
+		clog.Errorln("Sound effects (-e) can only be used in soundbank mode.")
+		return 1
+	}
+
+	if !cfg.SoundbankMode && cfg.LockFile != "" {
+		clog.Errorln("A lockfile (--lock) can only be used in soundbank mode.")
+		return 1
+	}
+
+	if !cfg.SoundbankMode && (cfg.SpcModule != "" || cfg.AllSpc) {
+		clog.Errorln("--module and --all-spc can only be used in soundbank mode.")
+		return 1
+	}
+
+	if !cfg.SoundbankMode && cfg.SoundRegion != 0 {
+		clog.Errorln("A sound region (--sound-region) can only be used in soundbank mode.")
+		return 1
+	}
+
+	if cfg.SoundbankMode && cfg.SpcPosition != 0 && cfg.SpcModule == "" && !cfg.AllSpc {
+		clog.Errorln("--position needs --module or --all-spc in soundbank mode.")
+		return 1
+	}
+
+	if cfg.SoundbankMode && cfg.Tags != (smconv.ModuleTags{}) && cfg.SpcModule == "" && !cfg.AllSpc {
+		clog.Errorln("SPC tags need --module or --all-spc in soundbank mode.")
+		return 1
+	}
+
 	if cfg.SoundbankMode && cfg.OutputFile == "" {
 		clog.Errorln("Output file (-o) is required for soundbank mode.")
 		return 1
 	}
 
-	bank := smconv.SoundBank{}
+	bank := smconv.SoundBank{
+		ModulePrefix: cfg.ModulePrefix,
+		EffectPrefix: cfg.EffectPrefix,
+		SoundRegion:  cfg.SoundRegion,
+	}
 	if !cfg.SoundbankMode && len(cfg.InputFiles) != 1 {
 		clog.Errorln("SPC conversion mod requires exactly one input file.")
 		return 1
@@ -132,7 +471,8 @@ func smconvCli(args []string) int {
 		clog.Infoln("Loading input file.")
 	}
 
-	for _, inputFile := range cfg.InputFiles {
+	for _, input := range cfg.InputFiles {
+		inputFile, id := splitInputId(input)
 		clog.Infoln("Loading module:", inputFile)
 		mod, err := modlib.LoadModule(inputFile)
 		if err != nil {
@@ -140,25 +480,65 @@ func smconvCli(args []string) int {
 			return 1
 		}
 
-		err = bank.AddModule(mod, inputFile)
+		err = bank.AddModuleWithOptions(mod, inputFile, smconv.ModuleOptions{Id: id})
 		if err != nil {
 			clog.Errorf("Error converting module %s: %v\n", inputFile, err)
 			return 1
 		}
+
+		if cfg.VerboseMode {
+			logModuleReport(&bank, bank.Modules[len(bank.Modules)-1])
+		}
+	}
+
+	for _, effect := range cfg.EffectFiles {
+		effectFile, id := splitInputId(effect)
+		clog.Infoln("Loading sound effects:", effectFile)
+		err := bank.AddSoundEffectFile(effectFile, smconv.EffectOptions{Id: id, Rate: cfg.EffectRate})
+		if err != nil {
+			clog.Errorf("Error converting sound effects %s: %v\n", effectFile, err)
+			return 1
+		}
+	}
+
+	if cfg.SoundbankMode {
+		if err := bank.ResolveIds(cfg.IdCollisions == "rename"); err != nil {
+			clog.Errorf("%v\n", err)
+			return 1
+		}
+	}
+
+	diags := bank.Diagnostics()
+	printDiagnostics(diags, cfg.Diagnostics)
+
+	if smconv.HasErrors(diags) || (cfg.Werror && len(diags) > 0) {
+		clog.Errorln("Conversion failed due to diagnostics.")
+		return 1
 	}
 
 	if cfg.SoundbankMode {
 		clog.Infoln("Exporting sound bank.")
-		outputFile := strings.TrimSuffix(cfg.OutputFile, ".smbank")
+		if err := exportLockedSoundbank(&bank, cfg.OutputFile, cfg.HiRom, cfg.LockFile); err != nil {
+			clog.Errorf("Error exporting sound bank: %v\n", err)
+			return 1
+		}
 
-		bank.Export(outputFile+".smbank", cfg.HiRom)
-		bank.ExportAssembly(outputFile+".asm", outputFile+".smbank")
-		bank.ExportAssemblyInclude(outputFile + ".inc")
+		spcOpts := smconv.SpcOptions{Position: cfg.SpcPosition, Tags: cfg.Tags}
+		if err := exportBankSpcFiles(&bank, cfg.OutputFile, cfg.SpcModule, cfg.AllSpc, spcOpts); err != nil {
+			clog.Errorf("Error writing SPC file: %v\n", err)
+			return 1
+		}
 
 	} else {
 		clog.Infoln("Writing SPC file.")
+		outputFile := cfg.OutputFile
+		if outputFile == "" {
+			inputFile, _ := splitInputId(cfg.InputFiles[0])
+			outputFile = strings.TrimSuffix(inputFile, filepath.Ext(inputFile)) + ".spc"
+		}
+
 		// Export to SPC
-		err := bank.WriteSpcFile(cfg.OutputFile)
+		err := bank.WriteSpcFileWithOptions(outputFile, smconv.SpcOptions{Position: cfg.SpcPosition, Tags: cfg.Tags})
 		if err != nil {
 			clog.Errorf("Error writing SPC file: %v\n", err)
 			return 1
@@ -168,6 +548,136 @@ func smconvCli(args []string) int {
 	return 0
 }
 
+// Print the conversion report and SPC memory layout of a module.
+func logModuleReport(bank *smconv.SoundBank, mod *smconv.SmModule) {
+	text := bank.Report(mod).String()
+	if layout, err := bank.SpcLayout(mod, bank.SoundRegion); err == nil {
+		text += "\n" + layout.String()
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		clog.Infoln(line)
+	}
+}
+
+// Split an input argument into the file and an explicit ID, given as <file>=<ID>. The ID
+// is empty when there isn't one. Anything after "=" that looks like part of a path is
+// taken as part of the filename.
+func splitInputId(input string) (filename string, id string) {
+	eq := strings.LastIndex(input, "=")
+	if eq <= 0 || strings.ContainsAny(input[eq+1:], "./\\") || eq == len(input)-1 {
+		return input, ""
+	}
+	return input[:eq], input[eq+1:]
+}
+
+// Write the soundbank binary, assembly, and include files. `outputFile` is the base
+// filename.
+func exportSoundbank(bank *smconv.SoundBank, outputFile string, hirom bool) error {
+	outputFile = strings.TrimSuffix(outputFile, ".smbank")
+
+	if err := bank.Export(outputFile+".smbank", hirom); err != nil {
+		return err
+	}
+	if err := bank.ExportAssembly(outputFile+".asm", outputFile+".smbank"); err != nil {
+		return err
+	}
+	return bank.ExportAssemblyInclude(outputFile + ".inc")
+}
+
+// Same as exportSoundbank, but pins the IDs to a lockfile first if one is given. The
+// lockfile is updated after the soundbank is written.
+func exportLockedSoundbank(bank *smconv.SoundBank, outputFile string, hirom bool, lockFile string) error {
+	if lockFile == "" {
+		return exportSoundbank(bank, outputFile, hirom)
+	}
+
+	lock, err := smconv.LoadIdLock(lockFile)
+	if err != nil {
+		return err
+	}
+
+	removed, err := bank.ApplyLock(lock)
+	if err != nil {
+		return err
+	}
+	for _, id := range removed {
+		clog.Warnln("Locked ID is no longer in the soundbank:", id)
+	}
+
+	if err := exportSoundbank(bank, outputFile, hirom); err != nil {
+		return err
+	}
+	return lock.Save(lockFile)
+}
+
+// Write SPC files for modules in an exported soundbank: the one given by `module` (an ID
+// or index), or every module if `all` is set. The files are named after the soundbank
+// output and the module ID. Placeholders for removed IDs are skipped. `opts` gives the
+// start position and tags for all of them.
+func exportBankSpcFiles(bank *smconv.SoundBank, outputFile string, module string, all bool, opts smconv.SpcOptions) error {
+	indices := []int{}
+	if all {
+		for i, mod := range bank.Modules {
+			if mod.Id != "" {
+				indices = append(indices, i)
+			}
+		}
+	} else if module != "" {
+		index, err := bank.FindModule(module)
+		if err != nil {
+			return err
+		}
+		indices = append(indices, index)
+	}
+
+	outputFile = strings.TrimSuffix(outputFile, ".smbank")
+
+	// One date for the whole set, unless it has a date tag.
+	date, err := smconv.BuildDate()
+	if err != nil {
+		return err
+	}
+	opts.Date = date
+
+	for _, index := range indices {
+		name := bank.Modules[index].Id
+		if name == "" {
+			name = strconv.Itoa(index)
+		}
+		filename := outputFile + "-" + name + ".spc"
+
+		clog.Infoln("Writing SPC file:", filename)
+		opts.Module = index
+		if err := bank.WriteSpcFileWithOptions(filename, opts); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// Print diagnostics in the given format, "text" or "json".
+func printDiagnostics(diags []smconv.Diagnostic, format string) {
+	if format == "json" {
+		data, err := json.MarshalIndent(diags, "", "  ")
+		if err != nil {
+			clog.Errorf("%v\n", err)
+			return
+		}
+		fmt.Println(string(data))
+		return
+	}
+
+	for _, d := range diags {
+		if d.Severity == smconv.SeverityError {
+			clog.Errorln(d)
+		} else {
+			clog.Warnln(d)
+		}
+	}
+}
+
 // Entry point forwards to our inner function so we can overwrite args during testing.
 func main() {
 	os.Exit(smconvCli(os.Args[1:]))
@@ -1,10 +1,14 @@
 package main
 
 import (
+	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"go.mukunda.com/snesmod/smconv/spc"
 )
 
 func fileExists(filename string) bool {
@@ -32,3 +36,242 @@ func TestToSoundbank(t *testing.T) {
 	assert.True(t, fileExists(".testdata-pollen.inc"))
 	// TODO:  verify things
 }
+
+func TestLint(t *testing.T) {
+	assert.NotZero(t, smconvCli([]string{"lint"}))
+	assert.NotZero(t, smconvCli([]string{"lint", "test/missing.it"}))
+}
+
+func TestDiagnosticsFlag(t *testing.T) {
+	assert.NotZero(t, smconvCli([]string{"--diagnostics=xml", "test/pollen8.it"}))
+	assert.Zero(t, smconvCli([]string{"--werror", "--diagnostics=json", "-o", ".testdata-pollen.spc", "test/pollen8.it"}))
+}
+
+func TestBuild(t *testing.T) {
+	manifest := `output: .testdata-build
+hirom: true
+modules:
+  - file: test/pollen8.it
+    id: MOD_POLLEN
+    echo:
+      delay: 2
+effects:
+  - file: ../example/sound/tada.brr
+`
+	assert.NoError(t, os.WriteFile(".testdata-build.yaml", []byte(manifest), 0644))
+	assert.Zero(t, smconvCli([]string{"build", ".testdata-build.yaml"}))
+	assert.True(t, fileExists(".testdata-build.smbank"))
+	assert.True(t, fileExists(".testdata-build.asm"))
+
+	inc, err := os.ReadFile(".testdata-build.inc")
+	assert.NoError(t, err)
+	assert.Contains(t, string(inc), "MOD_POLLEN")
+	assert.Contains(t, string(inc), "SFX_TADA")
+
+	assert.NotZero(t, smconvCli([]string{"build"}))
+	assert.NotZero(t, smconvCli([]string{"build", ".testdata-missing.yaml"}))
+}
+
+func TestLockFlag(t *testing.T) {
+	os.Remove(".testdata-ids.lock")
+	assert.Zero(t, smconvCli([]string{"-s", "-o", ".testdata-locked", "--lock", ".testdata-ids.lock", "test/pollen8.it"}))
+	assert.True(t, fileExists(".testdata-ids.lock"))
+
+	// Adding an effect keeps the module's ID.
+	assert.Zero(t, smconvCli([]string{"-s", "-o", ".testdata-locked", "--lock", ".testdata-ids.lock",
+		"-e", "../example/sound/tada.brr", "test/pollen8.it"}))
+	lock, err := os.ReadFile(".testdata-ids.lock")
+	assert.NoError(t, err)
+	assert.Contains(t, string(lock), `"MOD_POLLEN8": 0`)
+	assert.Contains(t, string(lock), `"SFX_TADA"`)
+
+	assert.NotZero(t, smconvCli([]string{"--lock", ".testdata-ids.lock", "test/pollen8.it"}))
+}
+
+func TestInputIds(t *testing.T) {
+	file, id := splitInputId("music/theme.it=MOD_TOWN")
+	assert.Equal(t, "music/theme.it", file)
+	assert.Equal(t, "MOD_TOWN", id)
+
+	file, id = splitInputId("odd=name/theme.it")
+	assert.Equal(t, "odd=name/theme.it", file)
+	assert.Empty(t, id)
+
+	file, id = splitInputId("theme.it=")
+	assert.Equal(t, "theme.it=", file)
+	assert.Empty(t, id)
+
+	// The same module twice collides unless renamed or given an ID.
+	assert.NotZero(t, smconvCli([]string{"-s", "-o", ".testdata-ids", "test/pollen8.it", "test/pollen8.it"}))
+	assert.Zero(t, smconvCli([]string{"-s", "-o", ".testdata-ids", "--id-collisions=rename", "test/pollen8.it", "test/pollen8.it"}))
+	assert.Zero(t, smconvCli([]string{"-s", "-o", ".testdata-ids", "--module-prefix", "SONG_",
+		"test/pollen8.it", "test/pollen8.it=SONG_OTHER"}))
+
+	inc, err := os.ReadFile(".testdata-ids.inc")
+	assert.NoError(t, err)
+	assert.Contains(t, string(inc), "SONG_POLLEN8")
+	assert.Contains(t, string(inc), "SONG_OTHER")
+
+	assert.NotZero(t, smconvCli([]string{"-s", "-o", ".testdata-ids", "--module-prefix", "9-", "test/pollen8.it"}))
+}
+
+func TestInspect(t *testing.T) {
+	assert.Zero(t, smconvCli([]string{"-s", "-o", ".testdata-inspect", "test/pollen8.it"}))
+	assert.Zero(t, smconvCli([]string{"inspect", ".testdata-inspect.smbank"}))
+	assert.Zero(t, smconvCli([]string{"inspect", "--format=json", ".testdata-inspect.smbank"}))
+	assert.NotZero(t, smconvCli([]string{"inspect", "--hirom", ".testdata-inspect.smbank"}))
+	assert.NotZero(t, smconvCli([]string{"inspect", "--format=xml", ".testdata-inspect.smbank"}))
+	assert.NotZero(t, smconvCli([]string{"inspect"}))
+}
+
+func TestSpcExportOptions(t *testing.T) {
+	assert.Zero(t, smconvCli([]string{"--position", "2", "-o", ".testdata-position.spc", "test/pollen8.it"}))
+	assert.True(t, fileExists(".testdata-position.spc"))
+	assert.NotZero(t, smconvCli([]string{"--position", "200", "-o", ".testdata-position.spc", "test/pollen8.it"}))
+
+	os.Remove(".testdata-multi-MOD_POLLEN.spc")
+	os.Remove(".testdata-multi-MOD_REFLECTION.spc")
+	assert.Zero(t, smconvCli([]string{"-s", "-o", ".testdata-multi", "--module", "MOD_REFLECTION",
+		"test/pollen8.it=MOD_POLLEN", "smconv/test/reflection.it"}))
+	assert.True(t, fileExists(".testdata-multi-MOD_REFLECTION.spc"))
+	assert.False(t, fileExists(".testdata-multi-MOD_POLLEN.spc"))
+
+	assert.Zero(t, smconvCli([]string{"-s", "-o", ".testdata-multi", "--all-spc",
+		"test/pollen8.it=MOD_POLLEN", "smconv/test/reflection.it"}))
+	assert.True(t, fileExists(".testdata-multi-MOD_POLLEN.spc"))
+
+	assert.NotZero(t, smconvCli([]string{"-s", "-o", ".testdata-multi", "--module", "MOD_MISSING", "test/pollen8.it"}))
+	assert.NotZero(t, smconvCli([]string{"-s", "-o", ".testdata-multi", "--position", "1", "test/pollen8.it"}))
+	assert.NotZero(t, smconvCli([]string{"--module", "0", "-o", ".testdata-position.spc", "test/pollen8.it"}))
+}
+
+func TestSoundRegionFlag(t *testing.T) {
+	assert.Zero(t, smconvCli([]string{"-s", "-o", ".testdata-region", "--sound-region", "16", "-v", "test/pollen8.it"}))
+	assert.NotZero(t, smconvCli([]string{"-s", "-o", ".testdata-region", "--sound-region", "200", "test/pollen8.it"}))
+	assert.NotZero(t, smconvCli([]string{"-s", "-o", ".testdata-region", "--sound-region", "256", "test/pollen8.it"}))
+	assert.NotZero(t, smconvCli([]string{"--sound-region", "16", "-o", ".testdata-region.spc", "test/pollen8.it"}))
+	assert.Zero(t, smconvCli([]string{"inspect", "--sound-region", "16", ".testdata-region.smbank"}))
+	assert.NotZero(t, smconvCli([]string{"inspect", "--sound-region", "200", ".testdata-region.smbank"}))
+}
+
+func TestTagFlags(t *testing.T) {
+	assert.Zero(t, smconvCli([]string{"--title", "Pollen", "--game", "Quest", "--artist", "Someone",
+		"--date", "2025-03-14", "--length", "90", "--fade", "2000", "-o", ".testdata-tags.spc", "test/pollen8.it"}))
+
+	f, err := os.Open(".testdata-tags.spc")
+	assert.NoError(t, err)
+	defer f.Close()
+	spcf := spc.NewSpcFile()
+	assert.NoError(t, spcf.Read(f))
+	assert.Equal(t, "Quest", strings.TrimRight(string(spcf.Header.Tags.GameTitle[:]), "\x00"))
+	assert.Equal(t, "Someone", strings.TrimRight(string(spcf.Header.Tags.Composer[:]), "\x00"))
+	assert.Equal(t, "03/14/2025", strings.TrimRight(string(spcf.Header.Tags.DateDumped[:]), "\x00"))
+	assert.Equal(t, "90", strings.TrimRight(string(spcf.Header.Tags.SongDuration[:]), "\x00"))
+
+	assert.NotZero(t, smconvCli([]string{"--date", "today", "-o", ".testdata-tags.spc", "test/pollen8.it"}))
+	assert.NotZero(t, smconvCli([]string{"--length", "x", "-o", ".testdata-tags.spc", "test/pollen8.it"}))
+	assert.NotZero(t, smconvCli([]string{"-s", "-o", ".testdata-tags", "--game", "Quest", "test/pollen8.it"}))
+	assert.Zero(t, smconvCli([]string{"-s", "-o", ".testdata-tags", "--game", "Quest", "--all-spc", "test/pollen8.it"}))
+}
+
+func TestSourceDateEpoch(t *testing.T) {
+	t.Setenv("SOURCE_DATE_EPOCH", "1741910400")
+	assert.Zero(t, smconvCli([]string{"-o", ".testdata-epoch1.spc", "test/pollen8.it"}))
+	assert.Zero(t, smconvCli([]string{"-o", ".testdata-epoch2.spc", "test/pollen8.it"}))
+
+	first, err := os.ReadFile(".testdata-epoch1.spc")
+	assert.NoError(t, err)
+	second, err := os.ReadFile(".testdata-epoch2.spc")
+	assert.NoError(t, err)
+	assert.Equal(t, first, second)
+	assert.Contains(t, string(first), "03/14/2025")
+
+	t.Setenv("SOURCE_DATE_EPOCH", "x")
+	assert.NotZero(t, smconvCli([]string{"-o", ".testdata-epoch1.spc", "test/pollen8.it"}))
+}
+
+func TestSpcTag(t *testing.T) {
+	assert.Zero(t, smconvCli([]string{"--title", "Pollen", "-o", ".testdata-spctag.spc", "test/pollen8.it"}))
+	before, err := os.ReadFile(".testdata-spctag.spc")
+	assert.NoError(t, err)
+
+	// Options can follow the files.
+	assert.Zero(t, smconvCli([]string{"spc-tag", ".testdata-spctag.spc", "--artist", "Someone", "--length", "60"}))
+	after, err := os.ReadFile(".testdata-spctag.spc")
+	assert.NoError(t, err)
+	assert.Equal(t, before[0x100:0x10200], after[0x100:0x10200])
+
+	f, err := os.Open(".testdata-spctag.spc")
+	assert.NoError(t, err)
+	defer f.Close()
+	spcf := &spc.SpcFile{}
+	assert.NoError(t, spcf.Read(f))
+	tags := spcf.Id666()
+	assert.Equal(t, "Pollen", tags.SongTitle)
+	assert.Equal(t, "Someone", tags.Artist)
+	assert.Equal(t, 60, tags.Duration)
+	assert.Equal(t, "Someone", spcf.Xid6.Artist)
+	assert.Equal(t, "Pollen", spcf.Xid6.SongTitle)
+
+	assert.NotZero(t, smconvCli([]string{"spc-tag", ".testdata-spctag.spc"}))
+	assert.NotZero(t, smconvCli([]string{"spc-tag", "--artist", "Someone"}))
+	assert.NotZero(t, smconvCli([]string{"spc-tag", "--date", "today", ".testdata-spctag.spc"}))
+	assert.NotZero(t, smconvCli([]string{"spc-tag", "--artist", "Someone", "test/pollen8.it"}))
+	assert.NotZero(t, smconvCli([]string{"spc-tag", "--artist", "Someone", ".testdata-missing.spc"}))
+}
+
+func TestRender(t *testing.T) {
+	assert.Zero(t, smconvCli([]string{"render", "--length", "2", "--fade", "0", "-o", ".testdata-render.wav", "test/pollen8.it"}))
+	info, err := os.Stat(".testdata-render.wav")
+	assert.NoError(t, err)
+	assert.EqualValues(t, 44+2*32000*4, info.Size())
+
+	// SPC files play for their tagged time unless it's overridden.
+	assert.Zero(t, smconvCli([]string{"-o", ".testdata-render.spc", "test/pollen8.it"}))
+	assert.Zero(t, smconvCli([]string{"render", "--length", "1", "--fade", "500", ".testdata-render.spc"}))
+	info, err = os.Stat(".testdata-render.wav")
+	assert.NoError(t, err)
+	assert.EqualValues(t, 44+1500*32*4, info.Size())
+
+	assert.NotZero(t, smconvCli([]string{"render", "--position", "1", ".testdata-render.spc"}))
+	assert.NotZero(t, smconvCli([]string{"render", "--length", "x", "test/pollen8.it"}))
+	assert.NotZero(t, smconvCli([]string{"render"}))
+	assert.NotZero(t, smconvCli([]string{"render", ".testdata-missing.spc"}))
+}
+
+func TestDumpSources(t *testing.T) {
+	dir := t.TempDir()
+	assert.Zero(t, smconvCli([]string{"dump-sources", "-o", dir, "-e", "../example/sound/tada.brr", "test/pollen8.it"}))
+	files, err := filepath.Glob(filepath.Join(dir, "*.wav"))
+	assert.NoError(t, err)
+	assert.NotEmpty(t, files)
+	assert.FileExists(t, filepath.Join(dir, fmt.Sprintf("%03d-SFX_TADA.wav", len(files)-1)))
+
+	// Sources in an exported soundbank are named after the modules that use them.
+	assert.Zero(t, smconvCli([]string{"-s", "-o", ".testdata-dump", "test/pollen8.it"}))
+	dir = t.TempDir()
+	assert.Zero(t, smconvCli([]string{"dump-sources", "-o", dir, ".testdata-dump.smbank"}))
+	assert.FileExists(t, filepath.Join(dir, "000-MODULE0_S1.wav"))
+
+	assert.NotZero(t, smconvCli([]string{"dump-sources", "test/pollen8.it"}))
+	assert.NotZero(t, smconvCli([]string{"dump-sources", "-o", dir}))
+	assert.NotZero(t, smconvCli([]string{"dump-sources", "-o", dir, "-e", "../example/sound/tada.brr", ".testdata-dump.smbank"}))
+}
+
+func TestPreview(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "pollen8.it")
+	assert.Zero(t, smconvCli([]string{"preview", "-o", output, "test/pollen8.it"}))
+	assert.Zero(t, smconvCli([]string{output}))
+
+	assert.NotZero(t, smconvCli([]string{"preview"}))
+	assert.NotZero(t, smconvCli([]string{"preview", "-o", output, ".testdata-missing.it"}))
+}
+
+func TestFidelity(t *testing.T) {
+	assert.Zero(t, smconvCli([]string{"fidelity", "test/pollen8.it"}))
+	assert.Zero(t, smconvCli([]string{"fidelity", "--format=json", "test/pollen8.it"}))
+
+	assert.NotZero(t, smconvCli([]string{"fidelity"}))
+	assert.NotZero(t, smconvCli([]string{"fidelity", "--format=xml", "test/pollen8.it"}))
+	assert.NotZero(t, smconvCli([]string{"fidelity", "test/missing.it"}))
+}
@@ -0,0 +1,60 @@
+// SNESMOD
+// (C) 2025 Mukunda Johnson (mukunda.com)
+// Licensed under MIT
+
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.mukunda.com/modlib"
+	"go.mukunda.com/snesmod/smconv/clog"
+	"go.mukunda.com/snesmod/smconv/smconv"
+)
+
+// Entry point for "smconv preview". Converts a module and writes it back to an IT file
+// with only what the SNES plays.
+func previewCli(args []string) int {
+	flags := flag.NewFlagSet(os.Args[0]+" preview", flag.ContinueOnError)
+	outputFile := flags.String("o", "", "Output IT file")
+	flags.StringVar(outputFile, "output", "", "Output IT file")
+
+	if err := flags.Parse(args); err != nil {
+		clog.Errorf("%v\n", err)
+		return 1
+	}
+
+	if flags.NArg() != 1 {
+		clog.Errorln("Expected one module.")
+		return 1
+	}
+	inputFile := flags.Arg(0)
+
+	if *outputFile == "" {
+		*outputFile = strings.TrimSuffix(inputFile, filepath.Ext(inputFile)) + "-preview.it"
+	}
+
+	clog.Infoln("Loading module:", inputFile)
+	mod, err := modlib.LoadModule(inputFile)
+	if err != nil {
+		clog.Errorf("Error loading module %s: %v\n", inputFile, err)
+		return 1
+	}
+
+	bank := smconv.SoundBank{}
+	if err := bank.AddModule(mod, inputFile); err != nil {
+		clog.Errorf("Error converting module %s: %v\n", inputFile, err)
+		return 1
+	}
+
+	clog.Infoln("Writing preview:", *outputFile)
+	if err := bank.WritePreviewModule(*outputFile, 0); err != nil {
+		clog.Errorf("Error writing preview: %v\n", err)
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,113 @@
+// SNESMOD
+// (C) 2025 Mukunda Johnson (mukunda.com)
+// Licensed under MIT
+
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.mukunda.com/modlib"
+	"go.mukunda.com/snesmod/smconv/clog"
+	"go.mukunda.com/snesmod/smconv/smconv"
+	"go.mukunda.com/snesmod/smconv/spc"
+)
+
+// Entry point for "smconv render". Plays a module or an SPC file in the emulator and
+// writes the audio to a WAV file.
+func renderCli(args []string) int {
+	flags := flag.NewFlagSet(os.Args[0]+" render", flag.ContinueOnError)
+	outputFile := flags.String("o", "", "Output WAV file")
+	flags.StringVar(outputFile, "output", "", "Output WAV file")
+	position := flags.Int("position", 0, "Sequence position to start at")
+	tags := smconv.ModuleTags{}
+	addPlayTimeFlags(flags, &tags)
+
+	if err := flags.Parse(args); err != nil {
+		clog.Errorf("%v\n", err)
+		return 1
+	}
+
+	if err := tags.Validate(); err != nil {
+		clog.Errorf("%v\n", err)
+		return 1
+	}
+
+	if flags.NArg() != 1 {
+		clog.Errorln("Expected one module or SPC file.")
+		return 1
+	}
+	inputFile := flags.Arg(0)
+	isSpc := strings.EqualFold(filepath.Ext(inputFile), ".spc")
+
+	if isSpc && *position != 0 {
+		clog.Errorln("--position can't be used with SPC files.")
+		return 1
+	}
+
+	if *outputFile == "" {
+		*outputFile = strings.TrimSuffix(inputFile, filepath.Ext(inputFile)) + ".wav"
+	}
+
+	var spcf *spc.SpcFile
+	if isSpc {
+		data, err := os.Open(inputFile)
+		if err != nil {
+			clog.Errorf("%v\n", err)
+			return 1
+		}
+		defer data.Close()
+
+		spcf = &spc.SpcFile{}
+		if err := spcf.Read(data); err != nil {
+			clog.Errorf("Error reading SPC file %s: %v\n", inputFile, err)
+			return 1
+		}
+	} else {
+		clog.Infoln("Loading module:", inputFile)
+		mod, err := modlib.LoadModule(inputFile)
+		if err != nil {
+			clog.Errorf("Error loading module %s: %v\n", inputFile, err)
+			return 1
+		}
+
+		bank := smconv.SoundBank{}
+		if err := bank.AddModule(mod, inputFile); err != nil {
+			clog.Errorf("Error converting module %s: %v\n", inputFile, err)
+			return 1
+		}
+
+		spcf, err = bank.BuildSpcFile(smconv.SpcOptions{Position: *position, Tags: tags})
+		if err != nil {
+			clog.Errorf("%v\n", err)
+			return 1
+		}
+	}
+
+	length, fade := smconv.SpcPlayTime(spcf)
+	if tags.Length != nil {
+		length = time.Duration(*tags.Length) * time.Second
+	}
+	if tags.Fade != nil {
+		fade = time.Duration(*tags.Fade) * time.Millisecond
+	}
+
+	file, err := os.Create(*outputFile)
+	if err != nil {
+		clog.Errorf("%v\n", err)
+		return 1
+	}
+	defer file.Close()
+
+	clog.Infoln("Rendering", (length + fade).Round(time.Millisecond), "to", *outputFile)
+	if err := smconv.RenderSpc(file, spcf, length, fade); err != nil {
+		clog.Errorf("Error rendering: %v\n", err)
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,163 @@
+// SNESMOD
+// (C) 2025 Mukunda Johnson (mukunda.com)
+// Licensed under MIT
+
+package smconv
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mukunda.com/modlib"
+	"go.mukunda.com/snesmod/smconv/spc"
+	"go.mukunda.com/snesmod/smconv/spc/emu"
+)
+
+// Fingerprints of the driver playing each test module, made with
+// `go test -run TestDriverAudio -update`.
+const kAudioFingerprintFile = "test/audio-fingerprints.txt"
+
+const (
+	kAudioTestSeconds  = 5
+	kFingerprintWindow = emu.SampleRate / 10
+)
+
+var audioTestModules = []string{"../test/pollen8.it", "test/reflection.it"}
+
+// Convert a module by itself, export it to an SPC, and play it in the emulator.
+func renderTestModule(t *testing.T, path string) []int16 {
+	mod, err := modlib.LoadModule(path)
+	assert.NoError(t, err)
+	bank := &SoundBank{}
+	assert.NoError(t, bank.AddModule(mod, path))
+
+	filename := filepath.Join(t.TempDir(), "song.spc")
+	assert.NoError(t, bank.WriteSpcFile(filename))
+	f, err := os.Open(filename)
+	assert.NoError(t, err)
+	defer f.Close()
+	spcf := &spc.SpcFile{}
+	assert.NoError(t, spcf.Read(f))
+
+	return emu.New(spcf).RenderSeconds(kAudioTestSeconds)
+}
+
+// Summarize stereo audio as lines of "<window> <rms L> <rms R> <crossings L> <crossings R>"
+// for each tenth of a second. The levels catch changes in volume and envelopes, and the
+// zero crossings catch changes in pitch and timing.
+func fingerprintAudio(pcm []int16) []string {
+	lines := []string{}
+	for window := 0; (window+1)*kFingerprintWindow*2 <= len(pcm); window++ {
+		frames := pcm[window*kFingerprintWindow*2 : (window+1)*kFingerprintWindow*2]
+		var rms, crossings [2]int
+		for ch := range 2 {
+			sum := 0.0
+			for i := ch; i < len(frames); i += 2 {
+				sum += float64(frames[i]) * float64(frames[i])
+				if i >= 2 && (frames[i] < 0) != (frames[i-2] < 0) {
+					crossings[ch]++
+				}
+			}
+			rms[ch] = int(math.Round(math.Sqrt(sum / kFingerprintWindow)))
+		}
+		lines = append(lines, fmt.Sprintf("%d %d %d %d %d", window, rms[0], rms[1], crossings[0], crossings[1]))
+	}
+	return lines
+}
+
+// Reads the fingerprint file into lines for each module. Returns nil if the file doesn't
+// exist.
+func readAudioFingerprints(t *testing.T) map[string][]string {
+	text, err := os.ReadFile(kAudioFingerprintFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	assert.NoError(t, err)
+
+	fingerprints := map[string][]string{}
+	module := ""
+	for _, line := range strings.Split(strings.TrimSpace(string(text)), "\n") {
+		if name, ok := strings.CutPrefix(line, "# "); ok {
+			module = name
+			continue
+		}
+		fingerprints[module] = append(fingerprints[module], line)
+	}
+	return fingerprints
+}
+
+// Any change in the conversion or the driver that changes what's heard should fail this
+// test. If the change is intended, update the fingerprints with -update.
+func TestDriverAudio(t *testing.T) {
+	rendered := map[string][]string{}
+	for _, path := range audioTestModules {
+		name := filepath.Base(path)
+		pcm := renderTestModule(t, path)
+		rendered[name] = fingerprintAudio(pcm)
+
+		// The song starts playing within the first second.
+		peak := 0
+		for _, s := range pcm[:emu.SampleRate*2] {
+			peak = max(peak, int(s), -int(s))
+		}
+		assert.Greater(t, peak, 0, name)
+
+		// Keep the audio to listen to when it doesn't match.
+		t.Cleanup(func() {
+			if t.Failed() {
+				f, err := os.Create(".testdata-audio-" + strings.TrimSuffix(name, ".it") + ".wav")
+				if err == nil {
+					WriteWav(f, pcm, 2, emu.SampleRate)
+					f.Close()
+				}
+			}
+		})
+	}
+
+	if *updateGolden {
+		text := ""
+		for _, path := range audioTestModules {
+			name := filepath.Base(path)
+			text += "# " + name + "\n" + strings.Join(rendered[name], "\n") + "\n"
+		}
+		assert.NoError(t, os.WriteFile(kAudioFingerprintFile, []byte(text), 0644))
+	}
+
+	golden := readAudioFingerprints(t)
+	if golden == nil {
+		t.Fatalf("%s is missing; run with -update to create it", kAudioFingerprintFile)
+	}
+
+	for name, lines := range rendered {
+		expected := golden[name]
+		if !assert.Len(t, lines, len(expected), name) {
+			continue
+		}
+		for i := range lines {
+			if lines[i] != expected[i] {
+				assert.Failf(t, "audio changed", "%s at %.1fs: expected %q, got %q", name,
+					float64(i)*kFingerprintWindow/emu.SampleRate, expected[i], lines[i])
+				break
+			}
+		}
+	}
+}
+
+func TestFingerprintAudio(t *testing.T) {
+	// A 1000 Hz square wave on the left, silence on the right.
+	pcm := make([]int16, kFingerprintWindow*2*2)
+	for i := range kFingerprintWindow * 2 {
+		if i/16%2 == 0 {
+			pcm[i*2] = 1000
+		} else {
+			pcm[i*2] = -1000
+		}
+	}
+
+	assert.Equal(t, []string{"0 1000 0 199 0", "1 1000 0 199 0"}, fingerprintAudio(pcm))
+}
@@ -0,0 +1,83 @@
+// SNESMOD
+// (C) 2025 Mukunda Johnson (mukunda.com)
+// Licensed under MIT
+
+// This file reads pre-encoded BRR files, which are added to the soundbank as they are.
+// Both raw BRR and BRR with a 2-byte loop offset header are accepted.
+
+package smconv
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+)
+
+const (
+	kBrrBlockSize = 9
+
+	// Flags in the BRR block header.
+	kBrrEndFlag  = 0x01
+	kBrrLoopFlag = 0x02
+)
+
+var ErrInvalidBrr = errors.New("invalid brr data")
+
+// Load a BRR file from disk.
+func LoadBrr(filename string) (*Source, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return ReadBrr(data)
+}
+
+// Create a source from BRR data. If the length is 2 more than a multiple of 9, the data
+// starts with a loop offset in bytes. Otherwise, a looping sample loops back to the
+// start. The data must end with the end flag in the last block only, and a loop offset
+// must point to a block.
+func ReadBrr(data []byte) (*Source, error) {
+	loop := 0
+	hasHeader := len(data)%kBrrBlockSize == 2
+	if hasHeader {
+		loop = int(binary.LittleEndian.Uint16(data))
+		data = data[2:]
+	}
+
+	if len(data) == 0 || len(data)%kBrrBlockSize != 0 {
+		return nil, fmt.Errorf("%w: length %d is not a multiple of %d", ErrInvalidBrr, len(data), kBrrBlockSize)
+	}
+
+	last := len(data) - kBrrBlockSize
+	for pos := 0; pos < last; pos += kBrrBlockSize {
+		if data[pos]&kBrrEndFlag != 0 {
+			return nil, fmt.Errorf("%w: end flag set in block %d before the end", ErrInvalidBrr, pos/kBrrBlockSize)
+		}
+	}
+
+	if data[last]&kBrrEndFlag == 0 {
+		return nil, fmt.Errorf("%w: end flag not set in the last block", ErrInvalidBrr)
+	}
+
+	if data[last]&kBrrLoopFlag == 0 {
+		// The loop offset in the header is meaningless when the sample doesn't loop.
+		loop = 0
+	} else if loop%kBrrBlockSize != 0 || loop > last {
+		return nil, fmt.Errorf("%w: loop offset %d does not point to a block", ErrInvalidBrr, loop)
+	}
+
+	source := &Source{
+		Loop:         loop,
+		Data:         append([]byte(nil), data...),
+		TuningFactor: 1.0,
+	}
+	source.updateHash()
+
+	return source, nil
+}
+
+// Returns true if the source loops when played.
+func (source *Source) Loops() bool {
+	return len(source.Data) >= kBrrBlockSize && source.Data[len(source.Data)-kBrrBlockSize]&kBrrLoopFlag != 0
+}
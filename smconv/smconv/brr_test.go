@@ -0,0 +1,98 @@
+// SNESMOD
+// (C) 2025 Mukunda Johnson (mukunda.com)
+// Licensed under MIT
+
+package smconv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Builds BRR data with `blocks` silent blocks and the given flags on the last block.
+func buildBrr(blocks int, flags byte) []byte {
+	data := make([]byte, blocks*kBrrBlockSize)
+	data[len(data)-kBrrBlockSize] = 0xC0 | flags
+	return data
+}
+
+func TestReadBrr(t *testing.T) {
+	source, err := LoadBrr("../../example/sound/tada.brr")
+	assert.NoError(t, err)
+	assert.Len(t, source.Data, 8739)
+	assert.Equal(t, 0, source.Loop)
+	assert.False(t, source.Loops())
+	assert.NotEmpty(t, source.Hash)
+
+	// Raw looping data loops to the start.
+	source, err = ReadBrr(buildBrr(4, kBrrEndFlag|kBrrLoopFlag))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, source.Loop)
+	assert.True(t, source.Loops())
+
+	// With a loop header.
+	data := append([]byte{18, 0}, buildBrr(4, kBrrEndFlag|kBrrLoopFlag)...)
+	source, err = ReadBrr(data)
+	assert.NoError(t, err)
+	assert.Equal(t, 18, source.Loop)
+	assert.Len(t, source.Data, 36)
+
+	// The header is ignored when the sample doesn't loop.
+	data = append([]byte{18, 0}, buildBrr(4, kBrrEndFlag)...)
+	source, err = ReadBrr(data)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, source.Loop)
+
+	// Same data, same hash.
+	source2, err := ReadBrr(buildBrr(4, kBrrEndFlag))
+	assert.NoError(t, err)
+	assert.Equal(t, source.Hash, source2.Hash)
+}
+
+func TestReadBrrErrors(t *testing.T) {
+	_, err := ReadBrr(nil)
+	assert.ErrorIs(t, err, ErrInvalidBrr)
+
+	_, err = ReadBrr(make([]byte, 10))
+	assert.ErrorIs(t, err, ErrInvalidBrr, "misaligned")
+
+	_, err = ReadBrr(buildBrr(2, 0))
+	assert.ErrorIs(t, err, ErrInvalidBrr, "no end flag")
+
+	data := buildBrr(3, kBrrEndFlag)
+	data[kBrrBlockSize] |= kBrrEndFlag
+	_, err = ReadBrr(data)
+	assert.ErrorIs(t, err, ErrInvalidBrr, "early end flag")
+
+	_, err = ReadBrr(append([]byte{10, 0}, buildBrr(2, kBrrEndFlag|kBrrLoopFlag)...))
+	assert.ErrorIs(t, err, ErrInvalidBrr, "misaligned loop")
+
+	_, err = ReadBrr(append([]byte{18, 0}, buildBrr(2, kBrrEndFlag|kBrrLoopFlag)...))
+	assert.ErrorIs(t, err, ErrInvalidBrr, "loop past the end")
+}
+
+func TestAddBrrSoundEffect(t *testing.T) {
+	bank := SoundBank{}
+
+	source, err := LoadBrr("../../example/sound/wilhelm.brr")
+	assert.NoError(t, err)
+	index, err := bank.AddBrrSoundEffect(source, "SFX_WILHELM")
+	assert.NoError(t, err)
+	assert.Equal(t, "SFX_WILHELM", bank.Sources[index].Id)
+	assert.Equal(t, source.Data, bank.Sources[index].Data)
+
+	// Adding the same data under the same name is deduplicated.
+	index2, err := bank.AddBrrSoundEffect(source, "SFX_WILHELM")
+	assert.NoError(t, err)
+	assert.Equal(t, index, index2)
+
+	// Looping data is made one-shot without touching the original.
+	looped, err := ReadBrr(buildBrr(4, kBrrEndFlag|kBrrLoopFlag))
+	assert.NoError(t, err)
+	index, err = bank.AddBrrSoundEffect(looped, "SFX_LOOP")
+	assert.NoError(t, err)
+	assert.False(t, bank.Sources[index].Loops())
+	assert.True(t, looped.Loops())
+	assert.NotEqual(t, looped.Hash, bank.Sources[index].Hash)
+}
@@ -30,3 +30,9 @@ func pseek(cat eC, w io.Seeker, offset int64, whence int) {
 	_, err := w.Seek(offset, whence)
 	cat.Catch(err)
 }
+
+// Binary read
+func bread(cat eC, r io.Reader, data any) {
+	err := binary.Read(r, binary.LittleEndian, data)
+	cat.Catch(err)
+}
@@ -0,0 +1,116 @@
+// SNESMOD
+// (C) 2025 Mukunda Johnson (mukunda.com)
+// Licensed under MIT
+
+// This file describes the diagnostics reported during linting and conversion.
+
+package smconv
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+type Severity int
+
+const (
+	SeverityWarning Severity = iota
+	SeverityError
+)
+
+func (s Severity) String() string {
+	if s == SeverityError {
+		return "error"
+	}
+	return "warning"
+}
+
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Where a diagnostic came from. Pattern, Row, and Channel are -1 when not applicable.
+// Channel is zero-based. MessageLine is the 1-based line in the song message, or 0 when
+// not applicable.
+type Location struct {
+	File        string
+	Pattern     int
+	Row         int
+	Channel     int
+	MessageLine int
+}
+
+// A location that doesn't point anywhere within the module.
+func noLocation() Location {
+	return Location{Pattern: -1, Row: -1, Channel: -1}
+}
+
+func (loc Location) String() string {
+	parts := []string{}
+	if loc.File != "" {
+		parts = append(parts, loc.File)
+	}
+	if loc.Pattern >= 0 {
+		parts = append(parts, fmt.Sprintf("pattern %d, row %d, channel %d", loc.Pattern, loc.Row, loc.Channel+1))
+	}
+	if loc.MessageLine > 0 {
+		parts = append(parts, fmt.Sprintf("message line %d", loc.MessageLine))
+	}
+	return strings.Join(parts, ": ")
+}
+
+type Diagnostic struct {
+	Severity Severity
+	Code     string
+	Message  string
+	Location Location
+}
+
+func (d Diagnostic) String() string {
+	text := fmt.Sprintf("%s [%s] %s", d.Severity, d.Code, d.Message)
+	if loc := d.Location.String(); loc != "" {
+		text = loc + ": " + text
+	}
+	return text
+}
+
+// Fields that don't apply to the location are left out. The channel is one-based, the
+// same as in the text.
+func (d Diagnostic) MarshalJSON() ([]byte, error) {
+	out := struct {
+		Severity    Severity `json:"severity"`
+		Code        string   `json:"code"`
+		Message     string   `json:"message"`
+		File        string   `json:"file,omitempty"`
+		Pattern     *int     `json:"pattern,omitempty"`
+		Row         *int     `json:"row,omitempty"`
+		Channel     *int     `json:"channel,omitempty"`
+		MessageLine int      `json:"messageLine,omitempty"`
+	}{
+		Severity:    d.Severity,
+		Code:        d.Code,
+		Message:     d.Message,
+		File:        d.Location.File,
+		MessageLine: d.Location.MessageLine,
+	}
+
+	if d.Location.Pattern >= 0 {
+		channel := d.Location.Channel + 1
+		out.Pattern = &d.Location.Pattern
+		out.Row = &d.Location.Row
+		out.Channel = &channel
+	}
+
+	return json.Marshal(out)
+}
+
+// Returns true if any of the diagnostics is an error.
+func HasErrors(diags []Diagnostic) bool {
+	for _, d := range diags {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,33 @@
+// SNESMOD
+// (C) 2025 Mukunda Johnson (mukunda.com)
+// Licensed under MIT
+
+package smconv
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiagnosticJson(t *testing.T) {
+	d := Diagnostic{
+		Severity: SeverityWarning,
+		Code:     "channels",
+		Message:  "channel 10 is not supported and will be dropped",
+		Location: Location{File: "song.it", Pattern: 2, Row: 5, Channel: 9},
+	}
+
+	// The channel is one-based in both forms.
+	assert.Equal(t, "song.it: pattern 2, row 5, channel 10: warning [channels] channel 10 is not supported and will be dropped", d.String())
+	data, err := json.Marshal(d)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"severity": "warning", "code": "channels", "message": "channel 10 is not supported and will be dropped",
+		"file": "song.it", "pattern": 2, "row": 5, "channel": 10}`, string(data))
+
+	d.Location = noLocation()
+	data, err = json.Marshal(d)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"severity": "warning", "code": "channels", "message": "channel 10 is not supported and will be dropped"}`, string(data))
+}
@@ -0,0 +1,142 @@
+// SNESMOD
+// (C) 2025 Mukunda Johnson (mukunda.com)
+// Licensed under MIT
+
+// This file decodes the BRR sources in a soundbank back to WAV files, so the compression
+// artifacts and loop seams can be heard before a sample is committed.
+
+package smconv
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+
+	"go.mukunda.com/snesmod/smconv/spc/emu"
+)
+
+const (
+	// Sound effects play at 32000 Hz at the default pitch.
+	kEffectRate = 32000
+
+	// Rate at a PitchBase of 0.
+	kPitchBaseRate = 8363.0
+)
+
+// A source decoded as the DSP plays it.
+type DecodedSource struct {
+	Index int
+
+	// The sound effect ID, or the first module sample that uses the source, e.g.
+	// MOD_TOWN_S3.
+	Name string
+
+	// The decoded sample. The rate plays the source at C-5 for module samples and the
+	// default pitch for sound effects. The loop is the BRR loop, after unrolling or
+	// resampling.
+	Wav *WavSample
+
+	// Playback rate adjustment for a loop that was resampled to fit the BRR block size.
+	// It's already applied to the module samples and the WAV rate.
+	TuningFactor float64
+}
+
+// Decode a source in the soundbank.
+func (bank *SoundBank) DecodeSource(index int) *DecodedSource {
+	source := bank.Sources[index]
+	pcm := emu.DecodeBrr(source.Data)
+
+	decoded := &DecodedSource{
+		Index:        index,
+		Name:         source.Id,
+		TuningFactor: source.TuningFactor,
+	}
+
+	c5Rate := float64(kEffectRate)
+	if name, sample := bank.sourceUser(index); sample != nil {
+		// The pitch base is what the driver uses, so it includes the tuning factor.
+		c5Rate = kPitchBaseRate * math.Pow(2, float64(int16(sample.PitchBase))/768)
+		if decoded.Name == "" {
+			decoded.Name = name
+		}
+	}
+	if decoded.Name == "" {
+		decoded.Name = fmt.Sprintf("SOURCE%d", index)
+	}
+
+	// WAV rates are whole numbers, so the unity note has the rest of the tuning.
+	rate := max(int(math.Round(c5Rate)), 1)
+	wav := &WavSample{
+		Rate:      rate,
+		Data:      pcm,
+		UnityNote: kWavDefaultUnityNote + 12*math.Log2(float64(rate)/c5Rate),
+	}
+	if source.Loops() {
+		wav.Loop = true
+		wav.LoopStart = source.Loop / kBrrBlockSize * 16
+		wav.LoopEnd = len(pcm)
+	}
+	decoded.Wav = wav
+
+	return decoded
+}
+
+// The first module sample that uses a source, and its name, e.g. MOD_TOWN_S3. Returns
+// nil if no module sample uses it.
+func (bank *SoundBank) sourceUser(index int) (string, *SmSample) {
+	for m, mod := range bank.Modules {
+		for i, sample := range mod.Samples {
+			if int(sample.DirectoryIndex) < len(mod.SourceList) && int(mod.SourceList[sample.DirectoryIndex]) == index {
+				id := mod.Id
+				if id == "" {
+					// Modules read from a soundbank don't have IDs.
+					id = fmt.Sprintf("MODULE%d", m)
+				}
+				return fmt.Sprintf("%s_S%d", id, i+1), sample
+			}
+		}
+	}
+	return "", nil
+}
+
+// Write every source in the soundbank to `dir` as <index>-<name>.wav, with the loop and
+// tuning in a smpl chunk. The directory is created if needed.
+func (bank *SoundBank) DumpSources(dir string) ([]*DecodedSource, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	dumped := []*DecodedSource{}
+	for i := range bank.Sources {
+		decoded := bank.DecodeSource(i)
+		file, err := os.Create(filepath.Join(dir, decoded.Filename()))
+		if err != nil {
+			return nil, err
+		}
+		err = decoded.Wav.Write(file)
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+		dumped = append(dumped, decoded)
+	}
+	return dumped, nil
+}
+
+// The file name used by DumpSources.
+func (decoded *DecodedSource) Filename() string {
+	return fmt.Sprintf("%03d-%s.wav", decoded.Index, decoded.Name)
+}
+
+func (decoded *DecodedSource) String() string {
+	wav := decoded.Wav
+	text := fmt.Sprintf("%s: %d samples at %d Hz", decoded.Filename(), len(wav.Data), wav.Rate)
+	if wav.Loop {
+		text += fmt.Sprintf(", loop %d-%d", wav.LoopStart, wav.LoopEnd)
+	}
+	if decoded.TuningFactor != 1 {
+		text += fmt.Sprintf(", loop resampled (tuning %.4f)", decoded.TuningFactor)
+	}
+	return text
+}
@@ -0,0 +1,85 @@
+// SNESMOD
+// (C) 2025 Mukunda Johnson (mukunda.com)
+// Licensed under MIT
+
+package smconv
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeSource(t *testing.T) {
+	pcm := make([]int16, 1000)
+	for i := range pcm {
+		pcm[i] = int16(i * 20)
+	}
+
+	// A 100 sample loop is unrolled, and a 4001 sample loop is resampled.
+	unrolled, err := encodeSource(pcm, 500, 100, false)
+	assert.NoError(t, err)
+	long := make([]int16, 5001)
+	resampled, err := encodeSource(long, 1000, 4001, false)
+	assert.NoError(t, err)
+	assert.NotEqual(t, 1.0, resampled.TuningFactor)
+
+	bank := &SoundBank{
+		Sources: []*Source{unrolled, resampled, {Data: buildBrr(2, 1), Id: "SFX_BEEP", TuningFactor: 1}},
+		Modules: []*SmModule{{
+			Id:         "MOD_TEST",
+			SourceList: []uint16{1, 0},
+			Samples: []*SmSample{
+				{DirectoryIndex: 1, PitchBase: 768},
+				{DirectoryIndex: 0, PitchBase: uint16(0x10000 - 768)},
+			},
+		}},
+	}
+
+	decoded := bank.DecodeSource(0)
+	assert.Equal(t, "MOD_TEST_S1", decoded.Name)
+	assert.Equal(t, "000-MOD_TEST_S1.wav", decoded.Filename())
+	assert.Equal(t, 8363*2, decoded.Wav.Rate)
+	assert.InDelta(t, 60, decoded.Wav.UnityNote, 1e-9)
+	assert.Len(t, decoded.Wav.Data, len(unrolled.Data)/kBrrBlockSize*16)
+	assert.True(t, decoded.Wav.Loop)
+	assert.Equal(t, unrolled.Loop/kBrrBlockSize*16, decoded.Wav.LoopStart)
+	assert.Equal(t, len(decoded.Wav.Data), decoded.Wav.LoopEnd)
+	assert.GreaterOrEqual(t, decoded.Wav.LoopEnd-decoded.Wav.LoopStart, 100)
+
+	// Negative pitch bases, and rates that need the unity note to be exact.
+	decoded = bank.DecodeSource(1)
+	assert.Equal(t, "MOD_TEST_S2", decoded.Name)
+	assert.Equal(t, 4182, decoded.Wav.Rate)
+	assert.InDelta(t, 8363.0/2, decoded.Wav.C5Rate(), 1e-6)
+	assert.Contains(t, decoded.String(), "loop resampled")
+
+	decoded = bank.DecodeSource(2)
+	assert.Equal(t, "SFX_BEEP", decoded.Name)
+	assert.Equal(t, kEffectRate, decoded.Wav.Rate)
+	assert.False(t, decoded.Wav.Loop)
+	assert.Len(t, decoded.Wav.Data, 32)
+}
+
+func TestDumpSources(t *testing.T) {
+	bank := buildRoundTripBank(t)
+	dir := filepath.Join(t.TempDir(), "sources")
+	dumped, err := bank.DumpSources(dir)
+	assert.NoError(t, err)
+	assert.Len(t, dumped, len(bank.Sources))
+
+	files, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, files, len(bank.Sources))
+
+	for _, decoded := range dumped {
+		wav, err := LoadWav(filepath.Join(dir, decoded.Filename()))
+		assert.NoError(t, err)
+		assert.Equal(t, decoded.Wav.Data, wav.Data)
+		assert.Equal(t, decoded.Wav.Loop, wav.Loop)
+		assert.InDelta(t, decoded.Wav.C5Rate(), wav.C5Rate(), 1e-6)
+	}
+	assert.Equal(t, "SFX_TADA", dumped[len(dumped)-1].Name)
+}
@@ -25,6 +25,7 @@ func (bank *SoundBank) Export(filename string, hirom bool) (rerr error) {
 		if err != nil {
 			return err
 		}
+		defer file.Close()
 
 		bwrite(cat, file, uint16(len(bank.Sources)))
 		bwrite(cat, file, uint16(len(bank.Modules)))
@@ -288,6 +289,7 @@ func (bank *SoundBank) ExportAssembly(filename string, binfile string) (rerr err
 
 		file, err := os.Create(filename)
 		cat.Catch(err)
+		defer file.Close()
 
 		bwrite(cat, file, []byte(`; SNESMOD Soundbank Data
 ; Generated by SMCONV
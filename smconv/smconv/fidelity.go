@@ -0,0 +1,167 @@
+// SNESMOD
+// (C) 2025 Mukunda Johnson (mukunda.com)
+// Licensed under MIT
+
+// This file describes the fidelity report, which lists the places where conversion
+// changes the music, to explain why the SPC doesn't sound exactly like the tracker. It's
+// separate from linting: these aren't rule violations, only the driver's limits and the
+// shortcuts that conversion takes to fit them.
+
+package smconv
+
+import (
+	"fmt"
+	"math"
+
+	"go.mukunda.com/modlib/common"
+)
+
+// A change that conversion makes to a module.
+type FidelityChange struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (c FidelityChange) String() string {
+	return fmt.Sprintf("[%s] %s", c.Code, c.Message)
+}
+
+type fidelityReport struct {
+	mod     *common.Module
+	changes []FidelityChange
+}
+
+func (r *fidelityReport) add(code string, format string, a ...any) {
+	r.changes = append(r.changes, FidelityChange{Code: code, Message: fmt.Sprintf(format, a...)})
+}
+
+// Lists everything that conversion changes in a module, in the order of the
+// instruments, samples, and channels. Unsupported effects and other rule violations are
+// reported by LintModule instead.
+func FidelityReport(mod *common.Module) []FidelityChange {
+	r := &fidelityReport{mod: mod}
+
+	for i := range mod.Instruments {
+		r.reportInstrument(i+1, &mod.Instruments[i])
+	}
+
+	for i := range mod.Samples {
+		r.reportSample(i+1, &mod.Samples[i])
+	}
+
+	r.reportChannels()
+
+	return r.changes
+}
+
+func (r *fidelityReport) reportInstrument(num int, ins *common.Instrument) {
+	// Same as convertInstrument.
+	if fadeout := min(ins.Fadeout/4, 255) * 4; fadeout != ins.Fadeout {
+		r.add("fadeout", "instrument %d fadeout %d is rounded to %d", num, ins.Fadeout, fadeout)
+	}
+
+	volumeEnvelope := false
+	for _, env := range ins.Envelopes {
+		switch env.Type {
+		case common.EnvelopeTypePanning:
+			r.add("pan-envelope", "instrument %d panning envelope is dropped", num)
+			continue
+		case common.EnvelopeTypePitch:
+			r.add("pitch-envelope", "instrument %d pitch envelope is dropped", num)
+			continue
+		}
+
+		// Only the first volume envelope is converted.
+		if volumeEnvelope {
+			continue
+		}
+		volumeEnvelope = true
+
+		if env.Sustain && env.SustainEnd != env.SustainStart {
+			r.add("envelope-sustain", "instrument %d sustain loop (nodes %d-%d) is collapsed to a sustain point at node %d",
+				num, env.SustainStart, env.SustainEnd, env.SustainStart)
+		}
+
+		if env.Loop && env.LoopEnd < len(env.Nodes)-1 {
+			r.add("envelope-loop", "instrument %d envelope is cut at the loop end (node %d), dropping %d nodes",
+				num, env.LoopEnd, len(env.Nodes)-1-env.LoopEnd)
+		}
+	}
+}
+
+func (r *fidelityReport) reportSample(num int, sample *common.Sample) {
+	frames := 0
+	if len(sample.Data.Data) > 0 {
+		switch data := sample.Data.Data[0].(type) {
+		case []int16:
+			frames = len(data)
+		case []int8:
+			frames = len(data)
+		}
+	}
+	if frames == 0 {
+		return
+	}
+
+	if sample.Data.Bits == 8 {
+		r.add("sample-bits", "sample %d is 8-bit and is upsampled to 16-bit", num)
+	}
+
+	if len(sample.Data.Data) > 1 {
+		r.add("stereo-sample", "sample %d is stereo, only the left channel is kept", num)
+	}
+
+	if !sample.Loop {
+		return
+	}
+
+	// Same as createSource and encodeSource.
+	loopLength := sample.LoopEnd - sample.LoopStart
+	if loopLength <= 0 {
+		return
+	}
+	if sample.PingPong {
+		loopLength *= 2
+		r.add("loop-pingpong", "sample %d ping-pong loop is unrolled to a forward loop of %d samples", num, loopLength)
+	}
+
+	unrolls, resample := loopAlignment(loopLength)
+	if unrolls > 0 {
+		r.add("loop-unroll", "sample %d loop of %d samples is repeated %d more times to align it to %d samples",
+			num, loopLength, unrolls, loopLength*(1+unrolls))
+	}
+
+	if resample > 0 {
+		newLength := loopLength + resample
+		text := fmt.Sprintf("sample %d loop of %d samples is resampled to %d samples", num, loopLength, newLength)
+
+		// Compare the loop frequency in the tracker with the driver's, which uses the
+		// pitch base made from the tuning factor.
+		if sample.C5 > 0 {
+			tuning := float64(loopLength) / float64(newLength)
+			pitchBase := convertSample(sample, 0, tuning).PitchBase
+			rate := kPitchBaseRate * math.Pow(2, float64(int16(pitchBase))/768)
+			cents := 1200 * math.Log2(rate/float64(newLength)/(float64(sample.C5)/float64(loopLength)))
+			text += fmt.Sprintf(", tuning error %+.1f cents", cents)
+		}
+
+		r.add("loop-resample", "%s", text)
+	}
+}
+
+// Channels past 8 are dropped by convertPattern. Each one is reported once, where it's
+// first used.
+func (r *fidelityReport) reportChannels() {
+	reported := map[int]bool{}
+	for p, pattern := range r.mod.Patterns {
+		for row, entries := range pattern.Rows {
+			for _, e := range entries.Entries {
+				ch := int(e.Channel)
+				if ch >= 8 && !reported[ch] {
+					reported[ch] = true
+					r.add("channels", "channel %d is dropped, first used in pattern %d, row %d", ch+1, p, row)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,91 @@
+// SNESMOD
+// (C) 2025 Mukunda Johnson (mukunda.com)
+// Licensed under MIT
+
+package smconv
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mukunda.com/modlib/common"
+)
+
+func TestFidelityReport(t *testing.T) {
+	envelope := common.Envelope{
+		Type:         common.EnvelopeTypeVolume,
+		Loop:         true,
+		LoopStart:    1,
+		LoopEnd:      2,
+		Sustain:      true,
+		SustainStart: 0,
+		SustainEnd:   1,
+		Nodes:        []common.EnvelopeNode{{X: 0, Y: 64}, {X: 10, Y: 32}, {X: 20, Y: 16}, {X: 30, Y: 0}},
+	}
+
+	mod := &common.Module{
+		Instruments: []common.Instrument{
+			{Fadeout: 256},
+			{
+				Fadeout: 10,
+				Envelopes: []common.Envelope{
+					envelope,
+					{Type: common.EnvelopeTypePanning},
+					{Type: common.EnvelopeTypePitch},
+				},
+			},
+		},
+		Samples: []common.Sample{
+			{C5: 8363, Data: common.SampleData{Bits: 16, Channels: 1, Data: []any{make([]int16, 64)}}},
+			{C5: 8363, Loop: true, LoopStart: 0, LoopEnd: 100,
+				Data: common.SampleData{Bits: 8, Channels: 1, Data: []any{make([]int8, 100)}}},
+			{C5: 8363, Loop: true, PingPong: true, LoopStart: 0, LoopEnd: 1001,
+				Data: common.SampleData{Bits: 16, Channels: 2, Data: []any{make([]int16, 1001), make([]int16, 1001)}}},
+		},
+		Patterns: []common.Pattern{{Rows: []common.Row{
+			{Entries: []common.Entry{{Channel: 0, Note: 61}, {Channel: 9, Note: 61}}},
+			{Entries: []common.Entry{{Channel: 9, Note: 61}, {Channel: 8, Note: 61}}},
+		}}},
+	}
+
+	changes := FidelityReport(mod)
+	if !assert.Len(t, changes, 12) {
+		return
+	}
+
+	assert.Equal(t, []FidelityChange{
+		{"fadeout", "instrument 2 fadeout 10 is rounded to 8"},
+		{"envelope-sustain", "instrument 2 sustain loop (nodes 0-1) is collapsed to a sustain point at node 0"},
+		{"envelope-loop", "instrument 2 envelope is cut at the loop end (node 2), dropping 1 nodes"},
+		{"pan-envelope", "instrument 2 panning envelope is dropped"},
+		{"pitch-envelope", "instrument 2 pitch envelope is dropped"},
+		{"sample-bits", "sample 2 is 8-bit and is upsampled to 16-bit"},
+		{"loop-unroll", "sample 2 loop of 100 samples is repeated 3 more times to align it to 400 samples"},
+		{"stereo-sample", "sample 3 is stereo, only the left channel is kept"},
+		{"loop-pingpong", "sample 3 ping-pong loop is unrolled to a forward loop of 2002 samples"},
+	}, changes[:9])
+
+	// The tuning error depends on the rounding of the pitch base.
+	assert.Equal(t, "loop-resample", changes[9].Code)
+	assert.True(t, strings.HasPrefix(changes[9].Message, "sample 3 loop of 2002 samples is resampled to 2016 samples, tuning error "))
+	assert.True(t, strings.HasSuffix(changes[9].Message, " cents"))
+
+	assert.Equal(t, FidelityChange{"channels", "channel 10 is dropped, first used in pattern 0, row 0"}, changes[10])
+	assert.Equal(t, FidelityChange{"channels", "channel 9 is dropped, first used in pattern 0, row 1"}, changes[11])
+	assert.Equal(t, "[channels] channel 10 is dropped, first used in pattern 0, row 0", changes[10].String())
+}
+
+func TestLoopAlignment(t *testing.T) {
+	for _, test := range []struct{ length, unrolls, resample int }{
+		{0, 0, 0},
+		{32, 0, 0},
+		{100, 3, 0},
+		{1000, 0, 8},
+		{2001, 0, 15},
+	} {
+		unrolls, resample := loopAlignment(test.length)
+		assert.Equal(t, test.unrolls, unrolls, test.length)
+		assert.Equal(t, test.resample, resample, test.length)
+	}
+}
@@ -0,0 +1,153 @@
+// SNESMOD
+// (C) 2025 Mukunda Johnson (mukunda.com)
+// Licensed under MIT
+
+// This file describes how modules and sound effects are named in the definitions file.
+// IDs are made from file and sample names unless they're given explicitly, and every ID
+// must be a valid identifier for the assembler and C.
+
+package smconv
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	kDefaultModulePrefix = "MOD_"
+	kDefaultEffectPrefix = "SFX_"
+)
+
+var ErrInvalidId = errors.New("invalid id")
+var ErrIdCollision = errors.New("id collision")
+var ErrModuleNotFound = errors.New("module not found")
+
+var reValidId = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+var reStripId = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// Returns true if the ID can be used as a symbol in the generated files.
+func IsValidId(id string) bool {
+	return reValidId.MatchString(id)
+}
+
+// Convert a file path into an ID for inserting into definition files.
+// This strips the directory and extension, converts to uppercase, and replaces non
+// alphanumeric with "_". An ID that would start with a digit is prefixed with "_".
+func pathToId(prefix string, path string) string {
+	path = strings.ToUpper(path)
+	path = strings.ReplaceAll(path, "\\", "/")
+
+	slash := strings.LastIndex(path, "/")
+	if slash != -1 {
+		path = path[slash+1:]
+	}
+
+	dot := strings.Index(path, ".")
+	if dot != -1 {
+		path = path[:dot]
+	}
+
+	path = prefix + path
+	path = reStripId.ReplaceAllString(path, "_")
+
+	if path == "" || (path[0] >= '0' && path[0] <= '9') {
+		path = "_" + path
+	}
+
+	return path
+}
+
+func (bank *SoundBank) modulePrefix() string {
+	if bank.ModulePrefix == "" {
+		return kDefaultModulePrefix
+	}
+	return bank.ModulePrefix
+}
+
+func (bank *SoundBank) effectPrefix() string {
+	if bank.EffectPrefix == "" {
+		return kDefaultEffectPrefix
+	}
+	return bank.EffectPrefix
+}
+
+// A named entry in the definitions file.
+type idEntry struct {
+	id       *string
+	explicit bool
+	source   string // What the entry is, for errors.
+}
+
+// Check that every ID in the bank is valid and unique. Modules and sound effects share
+// one namespace in the definitions file. If `rename` is true, IDs that were made from
+// names are disambiguated with a number suffix (_2, _3, ...) instead of failing.
+// Explicit IDs are never renamed, so two equal explicit IDs are always an error.
+func (bank *SoundBank) ResolveIds(rename bool) error {
+	entries := []idEntry{}
+	for _, mod := range bank.Modules {
+		if mod.Id != "" {
+			entries = append(entries, idEntry{&mod.Id, mod.explicitId, "module " + mod.Filename})
+		}
+	}
+	for i, source := range bank.Sources {
+		if source.Id != "" {
+			entries = append(entries, idEntry{&source.Id, source.explicitId, fmt.Sprintf("sound effect %d", i)})
+		}
+	}
+
+	errs := []error{}
+	taken := map[string]string{}
+
+	for _, e := range entries {
+		if !e.explicit {
+			continue
+		}
+		if !IsValidId(*e.id) {
+			errs = append(errs, fmt.Errorf("%w: \"%s\" for %s is not a valid identifier", ErrInvalidId, *e.id, e.source))
+		} else if other, ok := taken[*e.id]; ok {
+			errs = append(errs, fmt.Errorf("%w: %s is used by %s and %s", ErrIdCollision, *e.id, other, e.source))
+		}
+		taken[*e.id] = e.source
+	}
+
+	for _, e := range entries {
+		if e.explicit {
+			continue
+		}
+
+		id := *e.id
+		if other, ok := taken[id]; ok {
+			if !rename {
+				errs = append(errs, fmt.Errorf("%w: %s is used by %s and %s", ErrIdCollision, id, other, e.source))
+				continue
+			}
+			for n := 2; taken[id] != ""; n++ {
+				id = fmt.Sprintf("%s_%d", *e.id, n)
+			}
+			*e.id = id
+		}
+		taken[id] = e.source
+	}
+
+	return errors.Join(errs...)
+}
+
+// Find a module by its ID or index in the bank.
+func (bank *SoundBank) FindModule(ref string) (int, error) {
+	if index, err := strconv.Atoi(ref); err == nil {
+		if index < 0 || index >= len(bank.Modules) {
+			return 0, fmt.Errorf("%w: module index %d is out of range", ErrModuleNotFound, index)
+		}
+		return index, nil
+	}
+
+	for i, mod := range bank.Modules {
+		if mod.Id == ref {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %s", ErrModuleNotFound, ref)
+}
@@ -0,0 +1,77 @@
+// SNESMOD
+// (C) 2025 Mukunda Johnson (mukunda.com)
+// Licensed under MIT
+
+package smconv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mukunda.com/modlib"
+)
+
+func TestPathToId(t *testing.T) {
+	assert.Equal(t, "MOD_THEME", pathToId("MOD_", "music/town/theme.it"))
+	assert.Equal(t, "MOD_THEME", pathToId("MOD_", "music\\battle\\Theme.IT"))
+	assert.Equal(t, "SFX_JUMP_SMALL", pathToId("SFX_", "jump-small.wav"))
+	assert.Equal(t, "_1UP", pathToId("", "1up.wav"))
+	assert.Equal(t, "_", pathToId("", ".hidden"))
+
+	for _, id := range []string{"MOD_THEME", "_1UP", "a"} {
+		assert.True(t, IsValidId(id), id)
+	}
+	for _, id := range []string{"", "1UP", "MOD-THEME", "MOD THEME"} {
+		assert.False(t, IsValidId(id), id)
+	}
+}
+
+func TestResolveIds(t *testing.T) {
+	mod, _ := modlib.LoadModule("test/reflection.it")
+	build := func(prefix string, files ...string) *SoundBank {
+		bank := &SoundBank{ModulePrefix: prefix}
+		for _, file := range files {
+			id := ""
+			if file == "explicit" {
+				file, id = "x/theme.it", "MOD_THEME"
+			}
+			assert.NoError(t, bank.AddModuleWithOptions(mod, file, ModuleOptions{Id: id}))
+		}
+		return bank
+	}
+
+	bank := build("", "town/theme.it", "battle/theme.it")
+	err := bank.ResolveIds(false)
+	assert.ErrorIs(t, err, ErrIdCollision)
+	assert.ErrorContains(t, err, "MOD_THEME is used by module town/theme.it and module battle/theme.it")
+
+	assert.NoError(t, bank.ResolveIds(true))
+	assert.Equal(t, "MOD_THEME", bank.Modules[0].Id)
+	assert.Equal(t, "MOD_THEME_2", bank.Modules[1].Id)
+
+	// An explicit ID keeps its name and the other one is renamed, even when it came first.
+	bank = build("", "town/theme.it", "explicit", "battle/theme.it")
+	assert.NoError(t, bank.ResolveIds(true))
+	assert.Equal(t, "MOD_THEME_2", bank.Modules[0].Id)
+	assert.Equal(t, "MOD_THEME", bank.Modules[1].Id)
+	assert.Equal(t, "MOD_THEME_3", bank.Modules[2].Id)
+
+	bank = build("", "explicit", "explicit")
+	assert.ErrorIs(t, bank.ResolveIds(true), ErrIdCollision)
+
+	bank = build("MUSIC_", "town/theme.it")
+	assert.NoError(t, bank.ResolveIds(false))
+	assert.Equal(t, "MUSIC_THEME", bank.Modules[0].Id)
+
+	// Explicit IDs must be valid identifiers.
+	bank = &SoundBank{}
+	assert.NoError(t, bank.AddModuleWithOptions(mod, "theme.it", ModuleOptions{Id: "2ND-THEME"}))
+	assert.ErrorIs(t, bank.ResolveIds(true), ErrInvalidId)
+
+	// Modules and effects share a namespace.
+	bank = &SoundBank{ModulePrefix: "SND_", EffectPrefix: "SND_"}
+	assert.NoError(t, bank.AddModule(mod, "theme.it"))
+	_, err = bank.AddBrrSoundEffect(&Source{Data: buildBrr(1, kBrrEndFlag)}, pathToId(bank.effectPrefix(), "theme.brr"))
+	assert.NoError(t, err)
+	assert.ErrorIs(t, bank.ResolveIds(false), ErrIdCollision)
+}
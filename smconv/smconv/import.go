@@ -0,0 +1,279 @@
+// SNESMOD
+// (C) 2025 Mukunda Johnson (mukunda.com)
+// Licensed under MIT
+
+// This file describes the functions to read a soundbank file back into the structures
+// that are exported. See doc/soundbank.txt for the format.
+
+package smconv
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"go.mukunda.com/errorcat"
+)
+
+var ErrInvalidSoundBank = errors.New("invalid soundbank")
+
+// Size of the module data before the patterns: the header and the pointer tables.
+var moduleHeaderSize = binary.Size(SmModuleHeader{}) + binary.Size(SmModuleHeaderPointers{})
+
+// A 24-bit pointer in the soundbank header tables.
+type bankPointer struct {
+	Addr uint16
+	Bank uint8
+}
+
+// Convert a pointer from the soundbank tables into a file offset. This is the reverse of
+// the conversion in Export.
+func (ptr bankPointer) offset(hirom bool) (int64, error) {
+	if hirom { // 64k banks
+		return int64(ptr.Bank)<<16 | int64(ptr.Addr), nil
+	}
+
+	// 32k banks
+	if ptr.Addr < 0x8000 {
+		return 0, fmt.Errorf("%w: LoROM address %02X:%04X is below $8000", ErrInvalidSoundBank, ptr.Bank, ptr.Addr)
+	}
+	return int64(ptr.Bank)<<15 | int64(ptr.Addr&0x7FFF), nil
+}
+
+// Read a soundbank file. `hirom` must match the mapping it was exported with. Modules
+// and sources have no IDs or filenames since those aren't stored in the soundbank.
+func ReadSoundBank(r io.ReadSeeker, hirom bool) (*SoundBank, error) {
+	bank := &SoundBank{HiRom: hirom}
+
+	err := errorcat.Guard(func(cat eC) error {
+		var numSources, numModules uint16
+		bread(cat, r, &numSources)
+		bread(cat, r, &numModules)
+
+		if numModules > kMaxBankModules {
+			return fmt.Errorf("%w: %d modules, the maximum is %d", ErrInvalidSoundBank, numModules, kMaxBankModules)
+		}
+
+		modulePointers := make([]bankPointer, kMaxBankModules)
+		sourcePointers := make([]bankPointer, numSources)
+		bread(cat, r, modulePointers)
+		bread(cat, r, sourcePointers)
+
+		for i := 0; i < int(numModules); i++ {
+			offset, err := modulePointers[i].offset(hirom)
+			cat.Catch(err)
+			pseek(cat, r, offset, io.SeekStart)
+
+			mod, err := readModule(r)
+			if err != nil {
+				return fmt.Errorf("module %d: %w", i, err)
+			}
+			bank.Modules = append(bank.Modules, mod)
+		}
+
+		for i := 0; i < int(numSources); i++ {
+			offset, err := sourcePointers[i].offset(hirom)
+			cat.Catch(err)
+			pseek(cat, r, offset, io.SeekStart)
+
+			source, err := readSource(r)
+			if err != nil {
+				return fmt.Errorf("source %d: %w", i, err)
+			}
+			bank.Sources = append(bank.Sources, source)
+		}
+
+		return nil
+	})
+
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSoundBank, err)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return bank, nil
+}
+
+// Read a module with its bank header, as written by SmModule.Export.
+func readModule(r io.Reader) (*SmModule, error) {
+	mod := &SmModule{}
+	err := errorcat.Guard(func(cat eC) error {
+
+		bread(cat, r, &mod.BankHeader)
+		mod.SourceList = make([]uint16, mod.BankHeader.SourceListCount)
+		bread(cat, r, mod.SourceList)
+
+		data := make([]byte, int(mod.BankHeader.ModuleSize)*2)
+		_, err := io.ReadFull(r, data)
+		cat.Catch(err)
+
+		return mod.parse(data)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return mod, nil
+}
+
+// Parse the module data that is loaded into SPC memory at kModuleBase.
+func (mod *SmModule) parse(data []byte) (rerr error) {
+	return errorcat.Guard(func(cat eC) error {
+		if len(data) < moduleHeaderSize {
+			return fmt.Errorf("%w: module size %d is too small", ErrInvalidSoundBank, len(data))
+		}
+
+		rd := bytes.NewReader(data)
+		var pointers SmModuleHeaderPointers
+		bread(cat, rd, &mod.Header)
+		bread(cat, rd, &pointers)
+
+		mod.Patterns = []*SmPattern{}
+		for _, offset := range moduleTableOffsets(cat, data, "pattern", pointers.PatternsL, pointers.PatternsH) {
+			mod.Patterns = append(mod.Patterns, parsePattern(cat, data[offset:]))
+		}
+
+		mod.Instruments = []*SmInstrument{}
+		for _, offset := range moduleTableOffsets(cat, data, "instrument", pointers.InstrumentsL, pointers.InstrumentsH) {
+			mod.Instruments = append(mod.Instruments, parseInstrument(cat, data[offset:]))
+		}
+
+		mod.Samples = []*SmSample{}
+		for _, offset := range moduleTableOffsets(cat, data, "sample", pointers.SamplesL, pointers.SamplesH) {
+			sample := &SmSample{}
+			bread(cat, bytes.NewReader(data[offset:]), sample)
+			mod.Samples = append(mod.Samples, sample)
+		}
+
+		return nil
+	})
+}
+
+// Resolve a module pointer table into offsets in the module data. The table ends at the
+// first unused ($FFFF) entry.
+func moduleTableOffsets(cat eC, data []byte, what string, low [64]byte, high [64]byte) []int {
+	offsets := []int{}
+	for i := 0; i < 64; i++ {
+		ptr := int(low[i]) | int(high[i])<<8
+		if ptr == 0xFFFF {
+			break
+		}
+
+		offset := ptr - kModuleBase
+		if offset < moduleHeaderSize || offset >= len(data) {
+			cat.Catch(fmt.Errorf("%w: %s %d pointer $%04X is outside of the module", ErrInvalidSoundBank, what, i, ptr))
+		}
+		offsets = append(offsets, offset)
+	}
+	return offsets
+}
+
+// Parse a pattern. Its size isn't stored, so the rows are walked to find the end.
+func parsePattern(cat eC, data []byte) *SmPattern {
+	truncated := fmt.Errorf("%w: pattern data is truncated", ErrInvalidSoundBank)
+	if len(data) < 1 {
+		cat.Catch(truncated)
+	}
+
+	rows := int(data[0]) + 1
+	pos := 1
+	for row := 0; row < rows; row++ {
+		if pos+2 > len(data) {
+			cat.Catch(truncated)
+		}
+
+		// Skip the hints byte.
+		channelMask := data[pos+1]
+		pos += 2
+
+		for channel := 0; channel < 8; channel++ {
+			if channelMask&(1<<channel) == 0 {
+				continue
+			}
+
+			if pos >= len(data) {
+				cat.Catch(truncated)
+			}
+
+			updateMask := data[pos]
+			pos++
+
+			// The high bits mark which bytes follow: note, instrument, volume command,
+			// and effect+parameter.
+			for bit := 4; bit < 8; bit++ {
+				if updateMask&(1<<bit) != 0 {
+					pos++
+				}
+			}
+			if updateMask&0x80 != 0 {
+				pos++
+			}
+
+			if pos > len(data) {
+				cat.Catch(truncated)
+			}
+		}
+	}
+
+	return &SmPattern{
+		Rows: data[0],
+		Data: bytes.Clone(data[1:pos]),
+	}
+}
+
+// Parse an instrument. The envelope fields are only present when the envelope length is
+// not zero.
+func parseInstrument(cat eC, data []byte) *SmInstrument {
+	smi := &SmInstrument{}
+	rd := bytes.NewReader(data)
+
+	bread(cat, rd, &smi.Info.Fadeout)
+	bread(cat, rd, &smi.Info.SampleIndex)
+	bread(cat, rd, &smi.Info.GlobalVolume)
+	bread(cat, rd, &smi.Info.SetPanning)
+	bread(cat, rd, &smi.Info.EnvelopeLength)
+
+	if smi.Info.EnvelopeLength > 0 {
+		bread(cat, rd, &smi.Info.EnvelopeSustain)
+		bread(cat, rd, &smi.Info.EnvelopeLoopStart)
+		bread(cat, rd, &smi.Info.EnvelopeLoopEnd)
+
+		smi.Envelope = make([]SmEnvelopeNode, smi.Info.EnvelopeLength/4)
+		bread(cat, rd, smi.Envelope)
+	}
+
+	return smi
+}
+
+// Read a source with its length and loop header, as written by Source.Export.
+func readSource(r io.Reader) (*Source, error) {
+	var source *Source
+	err := errorcat.Guard(func(cat eC) error {
+		var length, loop uint16
+		bread(cat, r, &length)
+		bread(cat, r, &loop)
+
+		if loop > length {
+			return fmt.Errorf("%w: loop offset %d is past the end of the data (%d)", ErrInvalidSoundBank, loop, length)
+		}
+
+		source = &Source{
+			Loop: int(loop),
+			Data: make([]byte, length),
+
+			// The tuning is already applied to the module samples.
+			TuningFactor: 1.0,
+		}
+		_, err := io.ReadFull(r, source.Data)
+		cat.Catch(err)
+
+		source.updateHash()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return source, nil
+}
@@ -0,0 +1,107 @@
+// SNESMOD
+// (C) 2025 Mukunda Johnson (mukunda.com)
+// Licensed under MIT
+
+package smconv
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mukunda.com/modlib"
+)
+
+func buildRoundTripBank(t *testing.T) *SoundBank {
+	bank := &SoundBank{}
+
+	mod, err := modlib.LoadModule("../test/pollen8.it")
+	assert.NoError(t, err)
+	assert.NoError(t, bank.AddModule(mod, "test/pollen8.it"))
+
+	mod, err = modlib.LoadModule("test/reflection.it")
+	assert.NoError(t, err)
+	assert.NoError(t, bank.AddModule(mod, "test/reflection.it"))
+
+	source, err := LoadBrr("../../example/sound/tada.brr")
+	assert.NoError(t, err)
+	_, err = bank.AddBrrSoundEffect(source, "SFX_TADA")
+	assert.NoError(t, err)
+
+	return bank
+}
+
+func TestReadSoundBankRoundTrip(t *testing.T) {
+	for _, hirom := range []bool{false, true} {
+		bank := buildRoundTripBank(t)
+		assert.NoError(t, bank.Export(".testdata-roundtrip.smbank", hirom))
+
+		f, err := os.Open(".testdata-roundtrip.smbank")
+		assert.NoError(t, err)
+		read, err := ReadSoundBank(f, hirom)
+		f.Close()
+		assert.NoError(t, err)
+
+		assert.Equal(t, hirom, read.HiRom)
+		assert.Len(t, read.Modules, len(bank.Modules))
+		assert.Len(t, read.Sources, len(bank.Sources))
+
+		for i, mod := range bank.Modules {
+			readMod := read.Modules[i]
+			assert.Equal(t, mod.SourceList, readMod.SourceList)
+			assert.Equal(t, mod.BankHeader.SourceListCount, readMod.BankHeader.SourceListCount)
+			assert.Equal(t, mod.Header, readMod.Header)
+			assert.Equal(t, mod.Patterns, readMod.Patterns)
+			assert.Equal(t, mod.Instruments, readMod.Instruments)
+			assert.Equal(t, mod.Samples, readMod.Samples)
+
+			// The size covers the module data without the bank header.
+			moduleData := &SeekingByteBuffer{}
+			assert.NoError(t, mod.Export(moduleData, false))
+			assert.Equal(t, len(moduleData.Bytes()), int(readMod.BankHeader.ModuleSize)*2)
+		}
+
+		for i, source := range bank.Sources {
+			assert.Equal(t, source.Data, read.Sources[i].Data)
+			assert.Equal(t, source.Loop, read.Sources[i].Loop)
+			assert.Equal(t, source.Hash, read.Sources[i].Hash)
+		}
+
+		// Exporting what was read gives the same file.
+		original, err := os.ReadFile(".testdata-roundtrip.smbank")
+		assert.NoError(t, err)
+		assert.NoError(t, read.Export(".testdata-roundtrip2.smbank", hirom))
+		exported, err := os.ReadFile(".testdata-roundtrip2.smbank")
+		assert.NoError(t, err)
+		assert.Equal(t, original, exported)
+	}
+}
+
+func TestReadSoundBankErrors(t *testing.T) {
+	bank := buildRoundTripBank(t)
+	assert.NoError(t, bank.Export(".testdata-roundtrip.smbank", false))
+	data, err := os.ReadFile(".testdata-roundtrip.smbank")
+	assert.NoError(t, err)
+
+	_, err = ReadSoundBank(bytes.NewReader(data[:len(data)-2]), false)
+	assert.ErrorIs(t, err, ErrInvalidSoundBank, "truncated")
+
+	_, err = ReadSoundBank(bytes.NewReader(data[:100]), false)
+	assert.ErrorIs(t, err, ErrInvalidSoundBank, "truncated header")
+
+	bad := bytes.Clone(data)
+	bad[5] = 0x00 // First module address below $8000
+	_, err = ReadSoundBank(bytes.NewReader(bad), false)
+	assert.ErrorIs(t, err, ErrInvalidSoundBank, "LoROM address")
+
+	// Point the first pattern into the module header.
+	moduleStart := 4 + 128*3 + len(bank.Sources)*3 + 4 + len(bank.Modules[0].SourceList)*2
+	patternsL := moduleStart + binary.Size(SmModuleHeader{})
+	bad = bytes.Clone(data)
+	bad[patternsL] = 0
+	bad[patternsL+64] = kModuleBase >> 8
+	_, err = ReadSoundBank(bytes.NewReader(bad), false)
+	assert.ErrorIs(t, err, ErrInvalidSoundBank, "pattern pointer")
+}
@@ -0,0 +1,441 @@
+// SNESMOD
+// (C) 2025 Mukunda Johnson (mukunda.com)
+// Licensed under MIT
+
+// This file describes the soundbank inspection, which lists what's in an exported
+// soundbank and checks it for problems. Unlike ReadSoundBank, a broken entry doesn't stop
+// the inspection, so everything else can still be shown.
+
+package smconv
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"go.mukunda.com/errorcat"
+)
+
+// Size of the soundbank header before the source pointer table.
+const kBankHeaderSize = 4 + kMaxBankModules*3
+
+type PointerInspection struct {
+	Bank    int   `json:"bank"`
+	Address int   `json:"address"`
+	Offset  int64 `json:"offset"` // File offset, or -1 if the pointer is invalid
+}
+
+func (p PointerInspection) String() string {
+	return fmt.Sprintf("%02X:%04X", p.Bank, p.Address)
+}
+
+type EchoInspection struct {
+	VolumeL  int   `json:"volumeL"`
+	VolumeR  int   `json:"volumeR"`
+	Delay    int   `json:"delay"`
+	Feedback int   `json:"feedback"`
+	Fir      []int `json:"fir"`
+	Enable   int   `json:"enable"` // Channel bits
+}
+
+type ModuleInspection struct {
+	Index   int               `json:"index"`
+	Pointer PointerInspection `json:"pointer"`
+	Size    int               `json:"size"` // Module data in bytes, without the bank header
+
+	Volume      int            `json:"volume"`
+	Tempo       int            `json:"tempo"`
+	Speed       int            `json:"speed"`
+	Echo        EchoInspection `json:"echo"`
+	Sequence    []int          `json:"sequence"` // Up to the end marker (255)
+	Patterns    int            `json:"patterns"`
+	Instruments int            `json:"instruments"`
+	Samples     int            `json:"samples"`
+	Sources     []int          `json:"sources"`
+
+	// SPC memory used by the module with its sources and echo buffer.
+	SpcBytes int `json:"spcBytes"`
+
+	// Play time from the start of the sequence, in seconds.
+	IntroSeconds float64 `json:"introSeconds"`
+	LoopSeconds  float64 `json:"loopSeconds"`
+	LoopPosition int     `json:"loopPosition"`
+	LoopRow      int     `json:"loopRow"`
+}
+
+type SourceInspection struct {
+	Index   int               `json:"index"`
+	Pointer PointerInspection `json:"pointer"`
+	Length  int               `json:"length"`
+	Loop    int               `json:"loop"`
+	Loops   bool              `json:"loops"`
+	Modules []int             `json:"modules"` // Modules that use the source
+}
+
+type SoundBankInspection struct {
+	HiRom          bool                `json:"hirom"`
+	SoundRegion    int                 `json:"soundRegion"` // Pages that the modules were checked with
+	FileSize       int64               `json:"fileSize"`
+	ModuleCount    int                 `json:"moduleCount"`
+	SourceCount    int                 `json:"sourceCount"`
+	ModulePointers []PointerInspection `json:"modulePointers"` // All 128 entries
+	Modules        []ModuleInspection  `json:"modules"`
+	Sources        []SourceInspection  `json:"sources"`
+
+	// Problems found while reading the soundbank. An empty list means the bank is valid.
+	Problems []string `json:"problems"`
+}
+
+func (info *SoundBankInspection) problem(format string, args ...any) {
+	info.Problems = append(info.Problems, fmt.Sprintf(format, args...))
+}
+
+// Check a pointer from the soundbank tables and resolve its file offset.
+func (info *SoundBankInspection) resolve(what string, ptr bankPointer) PointerInspection {
+	result := PointerInspection{Bank: int(ptr.Bank), Address: int(ptr.Addr), Offset: -1}
+
+	offset, err := ptr.offset(info.HiRom)
+	if err != nil {
+		info.problem("%s: %v", what, err)
+	} else if offset < kBankHeaderSize+int64(info.SourceCount)*3 || offset >= info.FileSize {
+		info.problem("%s: pointer %s is outside of the soundbank data", what, result)
+	} else {
+		result.Offset = offset
+	}
+
+	return result
+}
+
+// Read a soundbank and list its contents. An error is only returned if the header
+// can't be read. Other problems are listed in the result. `soundRegion` is the sound
+// region size in pages that the game allocates, for checking the SPC memory layout.
+func InspectSoundBank(r io.ReadSeeker, hirom bool, soundRegion int) (*SoundBankInspection, error) {
+	info := &SoundBankInspection{
+		HiRom:       hirom,
+		SoundRegion: soundRegion,
+		Modules:     []ModuleInspection{},
+		Sources:     []SourceInspection{},
+		Problems:    []string{},
+	}
+
+	var modulePointers []bankPointer
+	var sourcePointers []bankPointer
+
+	err := errorcat.Guard(func(cat eC) error {
+		pseek(cat, r, 0, io.SeekEnd)
+		info.FileSize = ptell(cat, r)
+		pseek(cat, r, 0, io.SeekStart)
+
+		var numSources, numModules uint16
+		bread(cat, r, &numSources)
+		bread(cat, r, &numModules)
+		info.SourceCount = int(numSources)
+		info.ModuleCount = int(numModules)
+
+		modulePointers = make([]bankPointer, kMaxBankModules)
+		sourcePointers = make([]bankPointer, numSources)
+		bread(cat, r, modulePointers)
+		bread(cat, r, sourcePointers)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: can't read the header: %v", ErrInvalidSoundBank, err)
+	}
+
+	if info.ModuleCount > kMaxBankModules {
+		info.problem("the module count %d is over the maximum of %d", info.ModuleCount, kMaxBankModules)
+	}
+
+	// Read sources first so modules can be measured with them.
+	bank := &SoundBank{HiRom: hirom}
+	for i, ptr := range sourcePointers {
+		what := fmt.Sprintf("source %d", i)
+		si := SourceInspection{Index: i, Pointer: info.resolve(what, ptr), Modules: []int{}}
+		source := placeholderSource()
+
+		if si.Pointer.Offset >= 0 {
+			_, err := r.Seek(si.Pointer.Offset, io.SeekStart)
+			if err == nil {
+				source, err = readSource(r)
+			}
+			if err != nil {
+				info.problem("%s: %v", what, err)
+				source = placeholderSource()
+			} else {
+				info.checkSource(what, source)
+			}
+		}
+
+		si.Length = len(source.Data)
+		si.Loop = source.Loop
+		si.Loops = source.Loops()
+		bank.Sources = append(bank.Sources, source)
+		info.Sources = append(info.Sources, si)
+	}
+
+	for i, ptr := range modulePointers {
+		if i >= info.ModuleCount {
+			info.ModulePointers = append(info.ModulePointers, PointerInspection{
+				Bank: int(ptr.Bank), Address: int(ptr.Addr), Offset: -1,
+			})
+			if ptr != (bankPointer{}) {
+				info.problem("module pointer %d is unused but not zero", i)
+			}
+			continue
+		}
+
+		what := fmt.Sprintf("module %d", i)
+		pointer := info.resolve(what, ptr)
+		info.ModulePointers = append(info.ModulePointers, pointer)
+		if pointer.Offset < 0 {
+			continue
+		}
+
+		_, err := r.Seek(pointer.Offset, io.SeekStart)
+		var mod *SmModule
+		if err == nil {
+			mod, err = readModule(r)
+		}
+		if err != nil {
+			info.problem("%s: %v", what, err)
+			continue
+		}
+
+		info.Modules = append(info.Modules, info.inspectModule(what, i, pointer, mod, bank))
+	}
+
+	return info, nil
+}
+
+// Check the BRR data of a source.
+func (info *SoundBankInspection) checkSource(what string, source *Source) {
+	if len(source.Data) == 0 || len(source.Data)%kBrrBlockSize != 0 {
+		info.problem("%s: length %d is not a multiple of %d", what, len(source.Data), kBrrBlockSize)
+		return
+	}
+	if source.Loop%kBrrBlockSize != 0 || source.Loop >= len(source.Data) {
+		info.problem("%s: loop offset %d is not a block in the data", what, source.Loop)
+	}
+
+	last := len(source.Data) - kBrrBlockSize
+	for i := 0; i < last; i += kBrrBlockSize {
+		if source.Data[i]&kBrrEndFlag != 0 {
+			info.problem("%s: block %d has the end flag before the last block", what, i/kBrrBlockSize)
+			return
+		}
+	}
+	if source.Data[last]&kBrrEndFlag == 0 {
+		info.problem("%s: the last block doesn't have the end flag", what)
+	}
+}
+
+func (info *SoundBankInspection) inspectModule(what string, index int, pointer PointerInspection, mod *SmModule, bank *SoundBank) ModuleInspection {
+	h := mod.Header
+	mi := ModuleInspection{
+		Index:   index,
+		Pointer: pointer,
+		Size:    int(mod.BankHeader.ModuleSize) * 2,
+		Volume:  int(h.InitialVolume),
+		Tempo:   int(h.InitialTempo),
+		Speed:   int(h.InitialSpeed),
+		Echo: EchoInspection{
+			VolumeL:  int(h.EchoVolumeL),
+			VolumeR:  int(h.EchoVolumeR),
+			Delay:    int(h.EchoDelay),
+			Feedback: int(h.EchoFeedback),
+			Fir:      []int{},
+			Enable:   int(h.EchoEnable),
+		},
+		Sequence:    []int{},
+		Patterns:    len(mod.Patterns),
+		Instruments: len(mod.Instruments),
+		Samples:     len(mod.Samples),
+		Sources:     []int{},
+	}
+
+	for _, tap := range h.EchoFir {
+		mi.Echo.Fir = append(mi.Echo.Fir, int(tap))
+	}
+
+	if h.EchoDelay > 15 {
+		info.problem("%s: echo delay %d is out of range", what, h.EchoDelay)
+	}
+
+	for _, entry := range h.Sequence {
+		if entry == 255 {
+			break
+		}
+		mi.Sequence = append(mi.Sequence, int(entry))
+		if entry != 254 && int(entry) >= len(mod.Patterns) {
+			info.problem("%s: sequence refers to missing pattern %d", what, entry)
+		}
+	}
+
+	if length, err := mod.MeasureLength(0); err != nil {
+		info.problem("%s: can't measure the play time: %v", what, err)
+	} else {
+		mi.IntroSeconds = length.Intro.Seconds()
+		mi.LoopSeconds = length.Loop.Seconds()
+		mi.LoopPosition = length.LoopPosition
+		mi.LoopRow = length.LoopRow
+	}
+
+	sourcesOk := true
+	for _, source := range mod.SourceList {
+		mi.Sources = append(mi.Sources, int(source))
+		if int(source) >= len(bank.Sources) {
+			info.problem("%s: source list refers to missing source %d", what, source)
+			sourcesOk = false
+			continue
+		}
+		info.Sources[source].Modules = append(info.Sources[source].Modules, index)
+	}
+
+	for i, ins := range mod.Instruments {
+		if int(ins.Info.SampleIndex) >= len(mod.Samples) {
+			info.problem("%s: instrument %d refers to missing sample %d", what, i+1, ins.Info.SampleIndex)
+		}
+	}
+	for i, sample := range mod.Samples {
+		if int(sample.DirectoryIndex) >= len(mod.SourceList) {
+			info.problem("%s: sample %d refers to missing source %d", what, i+1, sample.DirectoryIndex)
+		}
+	}
+
+	if mi.Size+kModuleBase > kSpcRamSize {
+		info.problem("%s: module data (%d bytes) doesn't fit in SPC memory", what, mi.Size)
+	}
+
+	if sourcesOk {
+		report := bank.Report(mod)
+		mi.SpcBytes = report.Total()
+		if over := report.Overage(); over > 0 {
+			info.problem("%s: too big for SPC memory by %d bytes", what, over)
+		}
+
+		layout, err := bank.SpcLayout(mod, info.SoundRegion)
+		if err == nil {
+			err = layout.Validate()
+		}
+		if err != nil {
+			info.problem("%s: %v", what, err)
+		}
+	}
+
+	return mi
+}
+
+// Format the sequence with +++ for skip entries.
+func formatSequence(sequence []int) string {
+	entries := []string{}
+	for _, entry := range sequence {
+		if entry == 254 {
+			entries = append(entries, "+++")
+		} else {
+			entries = append(entries, fmt.Sprint(entry))
+		}
+	}
+	return strings.Join(entries, " ")
+}
+
+func formatInts(values []int) string {
+	return strings.Trim(fmt.Sprint(values), "[]")
+}
+
+// Text listing of the soundbank.
+func (info *SoundBankInspection) String() string {
+	text := &strings.Builder{}
+	mapping := "LoROM"
+	if info.HiRom {
+		mapping = "HiROM"
+	}
+
+	fmt.Fprintf(text, "Soundbank: %d bytes, %s\n", info.FileSize, mapping)
+	fmt.Fprintf(text, "Modules: %d\n", info.ModuleCount)
+	fmt.Fprintf(text, "Sources: %d\n", info.SourceCount)
+
+	fmt.Fprintf(text, "\nModule pointers:\n")
+	tw := tabwriter.NewWriter(text, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "  #\tAddress\tOffset\n")
+	unused := 0
+	for i, ptr := range info.ModulePointers {
+		if i >= info.ModuleCount {
+			unused++
+			continue
+		}
+		fmt.Fprintf(tw, "  %d\t%s\t%s\n", i, ptr, formatOffset(ptr.Offset))
+	}
+	tw.Flush()
+	if unused > 0 {
+		fmt.Fprintf(text, "  (%d unused)\n", unused)
+	}
+
+	for _, mi := range info.Modules {
+		fmt.Fprintf(text, "\nModule %d at %s, %d bytes\n", mi.Index, mi.Pointer, mi.Size)
+		fmt.Fprintf(text, "  Volume: %d, tempo: %d, speed: %d\n", mi.Volume, mi.Tempo, mi.Speed)
+		fmt.Fprintf(text, "  Echo: volume %d/%d, delay %d, feedback %d, FIR %s, channels %08b\n",
+			mi.Echo.VolumeL, mi.Echo.VolumeR, mi.Echo.Delay, mi.Echo.Feedback,
+			formatInts(mi.Echo.Fir), mi.Echo.Enable)
+		fmt.Fprintf(text, "  Sequence: %s\n", formatSequence(mi.Sequence))
+		if mi.LoopSeconds > 0 {
+			intro := time.Duration(mi.IntroSeconds * float64(time.Second))
+			loop := time.Duration(mi.LoopSeconds * float64(time.Second))
+			fmt.Fprintf(text, "  Play time: %s (intro %s, loop %s from position %d, row %d)\n",
+				formatPlayTime(intro+loop), formatPlayTime(intro), formatPlayTime(loop),
+				mi.LoopPosition, mi.LoopRow)
+		}
+		fmt.Fprintf(text, "  Patterns: %d, instruments: %d, samples: %d\n", mi.Patterns, mi.Instruments, mi.Samples)
+		fmt.Fprintf(text, "  Sources: %s\n", formatInts(mi.Sources))
+		if mi.SpcBytes > 0 {
+			fmt.Fprintf(text, "  SPC memory: %d of %d bytes\n", mi.SpcBytes, kSpcRamSize)
+		}
+	}
+
+	fmt.Fprintf(text, "\nSources:\n")
+	tw = tabwriter.NewWriter(text, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "  #\tAddress\tOffset\tLength\tLoop\tModules\n")
+	for _, si := range info.Sources {
+		loop := "-"
+		if si.Loops {
+			loop = fmt.Sprint(si.Loop)
+		}
+		modules := formatInts(si.Modules)
+		if len(si.Modules) == 0 {
+			modules = "(effect)"
+		}
+		fmt.Fprintf(tw, "  %d\t%s\t%s\t%d\t%s\t%s\n",
+			si.Index, si.Pointer, formatOffset(si.Pointer.Offset), si.Length, loop, modules)
+	}
+	tw.Flush()
+
+	shared := false
+	for _, si := range info.Sources {
+		if len(si.Modules) > 1 {
+			if !shared {
+				fmt.Fprintf(text, "\nShared sources:\n")
+				shared = true
+			}
+			fmt.Fprintf(text, "  Source %d: modules %s\n", si.Index, formatInts(si.Modules))
+		}
+	}
+
+	if len(info.Problems) == 0 {
+		fmt.Fprintf(text, "\nNo problems found.")
+	} else {
+		fmt.Fprintf(text, "\nProblems:")
+		for _, p := range info.Problems {
+			fmt.Fprintf(text, "\n  %s", p)
+		}
+	}
+
+	return text.String()
+}
+
+func formatOffset(offset int64) string {
+	if offset < 0 {
+		return "invalid"
+	}
+	return fmt.Sprintf("0x%06X", offset)
+}
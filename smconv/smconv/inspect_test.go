@@ -0,0 +1,93 @@
+// SNESMOD
+// (C) 2025 Mukunda Johnson (mukunda.com)
+// Licensed under MIT
+
+package smconv
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mukunda.com/modlib"
+)
+
+func TestInspectSoundBank(t *testing.T) {
+	bank := buildRoundTripBank(t)
+
+	// A second copy of reflection shares its source with the first.
+	mod, _ := modlib.LoadModule("test/reflection.it")
+	assert.NoError(t, bank.AddModuleWithOptions(mod, "test/reflection.it", ModuleOptions{Id: "MOD_REFLECTION2"}))
+
+	assert.NoError(t, bank.Export(".testdata-inspect.smbank", true))
+	data, err := os.ReadFile(".testdata-inspect.smbank")
+	assert.NoError(t, err)
+
+	info, err := InspectSoundBank(bytes.NewReader(data), true, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, info.Problems)
+
+	assert.EqualValues(t, len(data), info.FileSize)
+	assert.Equal(t, 3, info.ModuleCount)
+	assert.Equal(t, len(bank.Sources), info.SourceCount)
+	assert.Len(t, info.ModulePointers, 128)
+	assert.Len(t, info.Modules, 3)
+
+	reflection := info.Modules[1]
+	assert.Equal(t, 135, reflection.Tempo)
+	assert.Equal(t, 6, reflection.Speed)
+	assert.Equal(t, []int{0, 254}, reflection.Sequence[:2])
+	assert.Equal(t, len(bank.Modules[1].Patterns), reflection.Patterns)
+	assert.Equal(t, bank.Report(bank.Modules[1]).Total(), reflection.SpcBytes)
+
+	length, err := bank.Modules[1].MeasureLength(0)
+	assert.NoError(t, err)
+	assert.Equal(t, length.Loop.Seconds(), reflection.LoopSeconds)
+
+	shared := info.Sources[bank.Modules[1].SourceList[0]]
+	assert.Equal(t, []int{1, 2}, shared.Modules)
+
+	// The effect isn't used by any module.
+	effect := info.Sources[len(info.Sources)-1]
+	assert.Empty(t, effect.Modules)
+	assert.False(t, effect.Loops)
+
+	text := info.String()
+	assert.Contains(t, text, "Shared sources:")
+	assert.Contains(t, text, "Play time: ")
+	assert.Contains(t, text, "No problems found.")
+}
+
+func TestInspectSoundBankProblems(t *testing.T) {
+	bank := buildRoundTripBank(t)
+	assert.NoError(t, bank.Export(".testdata-inspect.smbank", false))
+	data, err := os.ReadFile(".testdata-inspect.smbank")
+	assert.NoError(t, err)
+
+	_, err = InspectSoundBank(bytes.NewReader(data[:10]), false, 0)
+	assert.ErrorIs(t, err, ErrInvalidSoundBank)
+
+	// Inspecting a LoROM bank as HiROM puts the pointers in the wrong place.
+	info, err := InspectSoundBank(bytes.NewReader(data), true, 0)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, info.Problems)
+
+	// An unused module pointer that isn't cleared.
+	bad := bytes.Clone(data)
+	bad[4+2*3] = 0x12
+	info, err = InspectSoundBank(bytes.NewReader(bad), false, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"module pointer 2 is unused but not zero"}, info.Problems)
+
+	// A source pointer past the end still lists the other entries.
+	bad = bytes.Clone(data)
+	sourceTable := kBankHeaderSize
+	bad[sourceTable+2] = 0x7F
+	info, err = InspectSoundBank(bytes.NewReader(bad), false, 0)
+	assert.NoError(t, err)
+	assert.Len(t, info.Problems, 1)
+	assert.Contains(t, info.Problems[0], "source 0")
+	assert.Len(t, info.Modules, 2)
+	assert.Len(t, info.Sources, len(bank.Sources))
+}
@@ -0,0 +1,166 @@
+// SNESMOD
+// (C) 2025 Mukunda Johnson (mukunda.com)
+// Licensed under MIT
+
+// This file describes the SPC memory layout of the driver with a module loaded. It
+// follows sm_spc.asm: the module and its sources are loaded upward from MODULE, the
+// sound region (spcAllocateSoundRegion) is at the top of memory, and the echo buffer is
+// placed right below the sound region by SetupEcho. If the module data reaches the echo
+// buffer, the samples are overwritten when the song starts.
+
+package smconv
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// SampleDirectory in the driver. The effect directory and other driver tables follow
+	// it up to the program.
+	kSpcSampleDirectory = 0x200
+	kSpcDriverTables    = 0x300
+
+	// Where the driver program is loaded.
+	kSpcDriverBase = 0x400
+
+	// The sound region ends here. The last page holds the BRK vector and the IPL ROM.
+	kSpcSoundRegionEnd = 0xFF00
+
+	kSpcPageSize = 256
+)
+
+var ErrSpcMemoryOverlap = errors.New("SPC memory regions overlap")
+
+// A range of SPC memory, from Start up to but not including End.
+type SpcRegion struct {
+	Name  string
+	Start int
+	End   int
+}
+
+func (r SpcRegion) Size() int {
+	return r.End - r.Start
+}
+
+func (r SpcRegion) String() string {
+	if r.Size() == 0 {
+		return fmt.Sprintf("%-18s (empty)", r.Name)
+	}
+	return fmt.Sprintf("%-18s $%04X-$%04X  %5d bytes", r.Name, r.Start, r.End-1, r.Size())
+}
+
+type SpcLayout struct {
+	DirectPage      SpcRegion
+	SampleDirectory SpcRegion
+	DriverTables    SpcRegion
+	Driver          SpcRegion
+	Module          SpcRegion
+	Sources         SpcRegion
+	Echo            SpcRegion
+	SoundRegion     SpcRegion
+	Reserved        SpcRegion
+}
+
+// All regions in address order, as the driver expects them.
+func (layout *SpcLayout) Regions() []SpcRegion {
+	return []SpcRegion{
+		layout.DirectPage,
+		layout.SampleDirectory,
+		layout.DriverTables,
+		layout.Driver,
+		layout.Module,
+		layout.Sources,
+		layout.Echo,
+		layout.SoundRegion,
+		layout.Reserved,
+	}
+}
+
+// Where SetupEcho places the echo buffer: EDL*2K bytes below the sound region. With an
+// EDL of 0, the driver still clears one page.
+func echoRegion(echoDelay int, soundRegionPages int) SpcRegion {
+	end := 0xFF - soundRegionPages
+	start := end - echoDelay*8
+	if start == end {
+		start--
+	}
+
+	// The driver would wrap around to the top of memory. Either way, everything below
+	// is overwritten.
+	start = max(start, 0)
+	return SpcRegion{"echo buffer", start * kSpcPageSize, end * kSpcPageSize}
+}
+
+// Build the layout for a module in the bank. `soundRegionPages` is the size of the sound
+// region that the game allocates with spcAllocateSoundRegion, in 256-byte pages.
+func (bank *SoundBank) SpcLayout(mod *SmModule, soundRegionPages int) (*SpcLayout, error) {
+	if soundRegionPages < 0 || soundRegionPages > 255 {
+		return nil, fmt.Errorf("sound region size out of range: %d", soundRegionPages)
+	}
+
+	moduleBuffer := &SeekingByteBuffer{}
+	if err := mod.Export(moduleBuffer, false); err != nil {
+		return nil, err
+	}
+
+	sourceBytes := 0
+	for _, index := range mod.SourceList {
+		sourceBytes += len(bank.Sources[index].Data)
+	}
+
+	moduleEnd := kModuleBase + len(moduleBuffer.Bytes())
+	soundRegion := kSpcSoundRegionEnd - soundRegionPages*kSpcPageSize
+
+	return &SpcLayout{
+		DirectPage:      SpcRegion{"direct page/stack", 0, kSpcSampleDirectory},
+		SampleDirectory: SpcRegion{"sample directory", kSpcSampleDirectory, kSpcDriverTables},
+		DriverTables:    SpcRegion{"driver tables", kSpcDriverTables, kSpcDriverBase},
+		Driver:          SpcRegion{"driver", kSpcDriverBase, kSpcDriverBase + len(spcDriverBinary)},
+		Module:          SpcRegion{"module", kModuleBase, moduleEnd},
+		Sources:         SpcRegion{"sources", moduleEnd, moduleEnd + sourceBytes},
+		Echo:            echoRegion(int(mod.Header.EchoDelay), soundRegionPages),
+		SoundRegion:     SpcRegion{"sound region", soundRegion, kSpcSoundRegionEnd},
+		Reserved:        SpcRegion{"IPL ROM/vectors", kSpcSoundRegionEnd, 0x10000},
+	}, nil
+}
+
+// Check that no regions overlap.
+func (layout *SpcLayout) Validate() error {
+	errs := []error{}
+	regions := layout.Regions()
+
+	for i, a := range regions {
+		if a.Size() == 0 {
+			continue
+		}
+
+		for _, b := range regions[i+1:] {
+			if b.Size() == 0 {
+				continue
+			}
+			if a.Start < b.End && b.Start < a.End {
+				errs = append(errs, fmt.Errorf("%w: %s ($%04X-$%04X) and %s ($%04X-$%04X)",
+					ErrSpcMemoryOverlap, a.Name, a.Start, a.End-1, b.Name, b.Start, b.End-1))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Free bytes between the end of the sources and the start of the echo buffer. This is
+// negative if they overlap.
+func (layout *SpcLayout) Free() int {
+	return layout.Echo.Start - layout.Sources.End
+}
+
+func (layout *SpcLayout) String() string {
+	lines := []string{"SPC memory layout:"}
+	for _, r := range layout.Regions() {
+		lines = append(lines, r.String())
+	}
+	lines = append(lines, fmt.Sprintf("Free: %d bytes", layout.Free()))
+	return strings.Join(lines, "\n")
+}
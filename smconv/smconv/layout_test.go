@@ -0,0 +1,61 @@
+// SNESMOD
+// (C) 2025 Mukunda Johnson (mukunda.com)
+// Licensed under MIT
+
+package smconv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mukunda.com/modlib"
+)
+
+func TestEchoRegion(t *testing.T) {
+	// With EDL 0, the driver still uses the page below the sound region.
+	assert.Equal(t, SpcRegion{"echo buffer", 0xFE00, 0xFF00}, echoRegion(0, 0))
+	assert.Equal(t, SpcRegion{"echo buffer", 0xD700, 0xEF00}, echoRegion(3, 16))
+	assert.Equal(t, SpcRegion{"echo buffer", 0x8700, 0xFF00}, echoRegion(15, 0))
+}
+
+func TestSpcLayout(t *testing.T) {
+	bank := &SoundBank{}
+	mod, _ := modlib.LoadModule("test/reflection.it")
+	assert.NoError(t, bank.AddModule(mod, "test/reflection.it"))
+	smm := bank.Modules[0]
+
+	layout, err := bank.SpcLayout(smm, 0)
+	assert.NoError(t, err)
+	assert.NoError(t, layout.Validate())
+
+	moduleData := &SeekingByteBuffer{}
+	assert.NoError(t, smm.Export(moduleData, false))
+	assert.Equal(t, kModuleBase, layout.Module.Start)
+	assert.Equal(t, kModuleBase+len(moduleData.Bytes()), layout.Module.End)
+	assert.Equal(t, layout.Module.End, layout.Sources.Start)
+	assert.Equal(t, len(bank.Sources[0].Data), layout.Sources.Size())
+	assert.Equal(t, 0xFE00-layout.Sources.End, layout.Free())
+
+	// A large sound region and echo buffer leave no room for the module.
+	smm.Header.EchoDelay = 15
+	layout, err = bank.SpcLayout(smm, 120)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, layout.Validate(), ErrSpcMemoryOverlap)
+	assert.Negative(t, layout.Free())
+
+	_, err = bank.SpcLayout(smm, 256)
+	assert.Error(t, err)
+}
+
+func TestSoundRegionLimit(t *testing.T) {
+	mod, _ := modlib.LoadModule("../test/pollen8.it")
+
+	bank := &SoundBank{SoundRegion: 64}
+	assert.NoError(t, bank.AddModule(mod, "test/pollen8.it"))
+
+	// The module fits in the budget, but not below a sound region this large.
+	bank = &SoundBank{SoundRegion: 180}
+	err := bank.AddModule(mod, "test/pollen8.it")
+	assert.ErrorIs(t, err, ErrModuleTooBig)
+	assert.ErrorIs(t, err, ErrSpcMemoryOverlap)
+}
@@ -0,0 +1,251 @@
+// SNESMOD
+// (C) 2025 Mukunda Johnson (mukunda.com)
+// Licensed under MIT
+
+// This file describes the song length calculator. It walks a converted module the same
+// way that the driver plays it (Module_OnTick in sm_spc.asm), so the times match the SPC
+// output. Songs always loop in the driver: the end of the sequence restarts it.
+
+package smconv
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// The driver's tick timer: timer 0 runs at 8 kHz with a divider of $5000/tempo.
+	kTimer0Rate     = 8000
+	kTempoTimerBase = 0x5000
+
+	// Tempo limits applied by Txx.
+	kMinTempo = 80
+	kMaxTempo = 200
+
+	// Safety limit for walking a song that doesn't repeat a state.
+	kMaxWalkRows = 1 << 20
+)
+
+var ErrInvalidPattern = errors.New("invalid pattern data")
+var ErrSongNotLooping = errors.New("song doesn't loop")
+
+// One channel in a decoded pattern row. The flags have bit 0-3 set for note,
+// instrument, volume command, and effect. Values that weren't stored in the row are
+// filled from the last value in the pattern, like the driver does.
+type smRowEntry struct {
+	Flags      uint8
+	Note       uint8
+	Instrument uint8
+	Vcmd       uint8
+	Effect     uint8
+	Param      uint8
+}
+
+type smRow [8]smRowEntry
+
+// Decode the compressed pattern data into rows.
+func (smp *SmPattern) decodeRows() ([]smRow, error) {
+	rows := make([]smRow, int(smp.Rows)+1)
+	last := smRow{}
+	data := smp.Data
+	pos := 0
+
+	read := func() (uint8, error) {
+		if pos >= len(data) {
+			return 0, fmt.Errorf("%w: data ends early", ErrInvalidPattern)
+		}
+		pos++
+		return data[pos-1], nil
+	}
+
+	for r := range rows {
+		// Skip the hints byte.
+		if _, err := read(); err != nil {
+			return nil, err
+		}
+		channelMask, err := read()
+		if err != nil {
+			return nil, err
+		}
+
+		for ch := 0; ch < 8; ch++ {
+			if channelMask&(1<<ch) == 0 {
+				continue
+			}
+
+			mask, err := read()
+			if err != nil {
+				return nil, err
+			}
+
+			entry := &last[ch]
+			fields := []*uint8{&entry.Note, &entry.Instrument, &entry.Vcmd, &entry.Effect}
+			for bit, field := range fields {
+				if mask&(0x10<<bit) == 0 {
+					continue
+				}
+				if *field, err = read(); err != nil {
+					return nil, err
+				}
+				if bit == 3 {
+					if entry.Param, err = read(); err != nil {
+						return nil, err
+					}
+				}
+			}
+
+			rows[r][ch] = *entry
+			rows[r][ch].Flags = mask & 0x0F
+		}
+	}
+
+	return rows, nil
+}
+
+// The length of a song as played by the driver.
+type SongLength struct {
+	// Time from the start position until the loop point.
+	Intro time.Duration
+
+	// Time of one pass through the loop.
+	Loop time.Duration
+
+	// Where the loop starts.
+	LoopPosition int
+	LoopRow      int
+}
+
+// Time to play through the song once, with the intro and one loop.
+func (l SongLength) Total() time.Duration {
+	return l.Intro + l.Loop
+}
+
+// Format a play time as m:ss.s.
+func formatPlayTime(d time.Duration) string {
+	tenths := d.Round(100*time.Millisecond) / (100 * time.Millisecond)
+	return fmt.Sprintf("%d:%02d.%d", tenths/600, tenths/10%60, tenths%10)
+}
+
+// Duration of one tick at the given tempo.
+func tickDuration(tempo int) time.Duration {
+	timer := min(kTempoTimerBase/max(tempo, 1), 256)
+	return time.Duration(timer) * time.Second / kTimer0Rate
+}
+
+// The driver's playback state.
+type songState struct {
+	position int
+	row      int
+	speed    int
+	tempo    int
+}
+
+// Walk the sequence from the start position until the playback state repeats. Axx, Bxx,
+// Cxx and Txx (including tempo slides) are followed like in the driver. The driver
+// doesn't support SEx row delays or SBx loops, and Cxx always breaks to row 0.
+func (smm *SmModule) MeasureLength(start int) (SongLength, error) {
+	patterns := make([][]smRow, len(smm.Patterns))
+
+	// Same as Module_ChangePosition.
+	changePosition := func(position int) (int, error) {
+		for i := 0; i <= len(smm.Header.Sequence); i++ {
+			if position >= len(smm.Header.Sequence) || smm.Header.Sequence[position] == 255 {
+				position = 0
+			} else if smm.Header.Sequence[position] == 254 {
+				position++
+			} else {
+				pattern := int(smm.Header.Sequence[position])
+				if pattern >= len(smm.Patterns) {
+					return 0, fmt.Errorf("%w: sequence position %d refers to missing pattern %d", ErrInvalidPattern, position, pattern)
+				}
+				if patterns[pattern] == nil {
+					rows, err := smm.Patterns[pattern].decodeRows()
+					if err != nil {
+						return 0, fmt.Errorf("pattern %d: %w", pattern, err)
+					}
+					patterns[pattern] = rows
+				}
+				return position, nil
+			}
+		}
+		return 0, fmt.Errorf("%w: the sequence has no patterns", ErrInvalidPattern)
+	}
+
+	position, err := changePosition(start)
+	if err != nil {
+		return SongLength{}, err
+	}
+
+	state := songState{position, 0, int(smm.Header.InitialSpeed), int(smm.Header.InitialTempo)}
+	visited := map[songState]time.Duration{}
+	elapsed := time.Duration(0)
+
+	for i := 0; i < kMaxWalkRows; i++ {
+		if at, ok := visited[state]; ok {
+			return SongLength{
+				Intro:        at,
+				Loop:         elapsed - at,
+				LoopPosition: state.position,
+				LoopRow:      state.row,
+			}, nil
+		}
+		visited[state] = elapsed
+
+		row := patterns[smm.Header.Sequence[state.position]][state.row]
+		jump := -1
+		tempoSlide := 0
+
+		// Tick 0 effects.
+		for _, entry := range row {
+			if entry.Flags&8 == 0 {
+				continue
+			}
+			switch int(entry.Effect) {
+			case EffectA:
+				if entry.Param != 0 {
+					state.speed = int(entry.Param)
+				}
+			case EffectB:
+				jump = int(entry.Param)
+			case EffectC:
+				jump = state.position + 1
+			case EffectT:
+				if entry.Param >= 0x20 {
+					state.tempo = min(max(int(entry.Param), kMinTempo), kMaxTempo)
+				} else if entry.Param >= 0x10 {
+					tempoSlide = int(entry.Param & 0x0F)
+				} else {
+					tempoSlide = -int(entry.Param)
+				}
+			}
+		}
+
+		// The driver applies tempo slides on every tick, and each tick waits with the
+		// tempo that was just set.
+		for tick := 0; tick < max(state.speed, 1); tick++ {
+			if tempoSlide != 0 {
+				state.tempo = min(max(state.tempo+tempoSlide, kMinTempo), kMaxTempo)
+			}
+			elapsed += tickDuration(state.tempo)
+		}
+
+		if jump >= 0 {
+			if state.position, err = changePosition(jump); err != nil {
+				return SongLength{}, err
+			}
+			state.row = 0
+			continue
+		}
+
+		state.row++
+		if state.row >= len(patterns[smm.Header.Sequence[state.position]]) {
+			if state.position, err = changePosition(state.position + 1); err != nil {
+				return SongLength{}, err
+			}
+			state.row = 0
+		}
+	}
+
+	return SongLength{}, ErrSongNotLooping
+}
@@ -0,0 +1,158 @@
+// SNESMOD
+// (C) 2025 Mukunda Johnson (mukunda.com)
+// Licensed under MIT
+
+package smconv
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mukunda.com/modlib"
+)
+
+// An effect on channel 0 of a test pattern.
+type testEffect struct {
+	row    int
+	effect int
+	param  uint8
+}
+
+func makeTestPattern(rows int, effects ...testEffect) *SmPattern {
+	smp := &SmPattern{Rows: uint8(rows - 1)}
+	for r := 0; r < rows; r++ {
+		data := []byte{0, 0}
+		for _, e := range effects {
+			if e.row == r {
+				data = []byte{0, 1, 0x88, uint8(e.effect), e.param}
+			}
+		}
+		smp.Data = append(smp.Data, data...)
+	}
+	return smp
+}
+
+func makeTestModule(sequence []uint8, patterns ...*SmPattern) *SmModule {
+	smm := &SmModule{Patterns: patterns}
+	smm.Header.InitialSpeed = 6
+	smm.Header.InitialTempo = 125
+	for i := range smm.Header.Sequence {
+		smm.Header.Sequence[i] = 255
+	}
+	copy(smm.Header.Sequence[:], sequence)
+	return smm
+}
+
+// One row at speed 6, tempo 125: 6 ticks of 163/8000 seconds.
+const testRowTime = 6 * 163 * time.Second / 8000
+
+func TestDecodeRows(t *testing.T) {
+	smp := &SmPattern{Rows: 2, Data: []byte{
+		0, 1, 0xBB, 60, 1, 2, 0x30, // Note, instrument, effect B30
+		0, 0,
+		0, 1, 0x0B, // Reuse the note, instrument and effect
+	}}
+
+	rows, err := smp.decodeRows()
+	assert.NoError(t, err)
+	assert.Len(t, rows, 3)
+	assert.Equal(t, smRowEntry{Flags: 0x0B, Note: 60, Instrument: 1, Effect: 2, Param: 0x30}, rows[0][0])
+	assert.Equal(t, smRowEntry{}, rows[1][0])
+	assert.Equal(t, smRowEntry{Flags: 0x0B, Note: 60, Instrument: 1, Effect: 2, Param: 0x30}, rows[2][0])
+
+	smp.Data = smp.Data[:len(smp.Data)-1]
+	_, err = smp.decodeRows()
+	assert.ErrorIs(t, err, ErrInvalidPattern)
+}
+
+func TestMeasureLength(t *testing.T) {
+	// The end of the sequence restarts the song.
+	smm := makeTestModule([]uint8{0, 254, 1}, makeTestPattern(4), makeTestPattern(2))
+	length, err := smm.MeasureLength(0)
+	assert.NoError(t, err)
+	assert.Equal(t, SongLength{Loop: 6 * testRowTime}, length)
+
+	// Starting from another position starts the loop there, skipping 254.
+	length, err = smm.MeasureLength(1)
+	assert.NoError(t, err)
+	assert.Equal(t, SongLength{Loop: 6 * testRowTime, LoopPosition: 2}, length)
+
+	// Bxx at the end of the row.
+	smm = makeTestModule([]uint8{0, 1}, makeTestPattern(4), makeTestPattern(8, testEffect{2, EffectB, 1}))
+	length, err = smm.MeasureLength(0)
+	assert.NoError(t, err)
+	assert.Equal(t, SongLength{Intro: 4 * testRowTime, Loop: 3 * testRowTime, LoopPosition: 1}, length)
+
+	// Cxx goes to row 0 of the next position.
+	smm = makeTestModule([]uint8{0, 1}, makeTestPattern(4, testEffect{1, EffectC, 0x10}), makeTestPattern(2))
+	length, err = smm.MeasureLength(0)
+	assert.NoError(t, err)
+	assert.Equal(t, 4*testRowTime, length.Loop)
+
+	// Axx takes effect on its own row, and Txx is limited to 80-200. The loop starts
+	// where the speed and tempo are the same as the last time.
+	smm = makeTestModule([]uint8{0, 1},
+		makeTestPattern(2, testEffect{0, EffectA, 3}),
+		makeTestPattern(2, testEffect{0, EffectT, 0xFF}, testEffect{1, EffectB, 0}))
+	length, err = smm.MeasureLength(0)
+	assert.NoError(t, err)
+	assert.Equal(t, 3*163*time.Second/8000+3*163*time.Second/8000+3*102*time.Second/8000, length.Intro)
+	assert.Equal(t, 4*3*tickDuration(200), length.Loop)
+	assert.Equal(t, 1, length.LoopPosition)
+	assert.Equal(t, 1, length.LoopRow)
+
+	// Tempo slides are applied on every tick, including the first.
+	smm = makeTestModule([]uint8{0}, makeTestPattern(1, testEffect{0, EffectT, 0x12}))
+	smm.Header.InitialTempo = 196
+	length, err = smm.MeasureLength(0)
+	assert.NoError(t, err)
+	assert.Equal(t, 6*tickDuration(200), length.Loop)
+	assert.Equal(t, 5*tickDuration(200)+tickDuration(198), length.Intro)
+
+	// Broken sequences are errors.
+	smm = makeTestModule([]uint8{254})
+	_, err = smm.MeasureLength(0)
+	assert.ErrorIs(t, err, ErrInvalidPattern)
+
+	smm = makeTestModule([]uint8{3}, makeTestPattern(1))
+	_, err = smm.MeasureLength(0)
+	assert.ErrorIs(t, err, ErrInvalidPattern)
+}
+
+func TestMeasureLengthModules(t *testing.T) {
+	for _, filename := range []string{"test/reflection.it", "../test/pollen8.it"} {
+		mod, err := modlib.LoadModule(filename)
+		assert.NoError(t, err)
+
+		bank := SoundBank{}
+		assert.NoError(t, bank.AddModule(mod, filename))
+
+		length, err := bank.Modules[0].MeasureLength(0)
+		assert.NoError(t, err, filename)
+		assert.Positive(t, length.Loop, filename)
+
+		report := bank.Report(bank.Modules[0])
+		assert.Equal(t, &length, report.PlayTime)
+		assert.Contains(t, report.String(), "Play time: ")
+	}
+}
+
+func TestSpcPlayTime(t *testing.T) {
+	duration, fadeOut := spcPlayTime(SongLength{Intro: 10 * time.Second, Loop: 90500 * time.Millisecond})
+	assert.Equal(t, 101, duration)
+	assert.Equal(t, 10000, fadeOut)
+
+	duration, fadeOut = spcPlayTime(SongLength{Loop: 2 * time.Second})
+	assert.Equal(t, 2, duration)
+	assert.Equal(t, 2000, fadeOut)
+
+	duration, _ = spcPlayTime(SongLength{Intro: time.Hour})
+	assert.Equal(t, 999, duration)
+}
+
+func TestFormatPlayTime(t *testing.T) {
+	assert.Equal(t, "0:00.0", formatPlayTime(0))
+	assert.Equal(t, "1:05.3", formatPlayTime(65250*time.Millisecond))
+	assert.Equal(t, "12:00.0", formatPlayTime(12*time.Minute))
+}
@@ -0,0 +1,287 @@
+// SNESMOD
+// (C) 2025 Mukunda Johnson (mukunda.com)
+// Licensed under MIT
+
+// This file checks a module against the composition rules in doc/snesmod_music.txt.
+// Conversion doesn't refuse music that breaks the rules, so the linter is the place to
+// catch problems before they're heard on hardware.
+//
+// Some rules aren't checked yet: envelope carry, random volume/panning variation,
+// pitch/pan separation, the "Gxx shares memory with Exx/Fxx" compatibility flag,
+// sample auto-vibrato, and instrument filters (IFC/IFR).
+
+package smconv
+
+import (
+	"fmt"
+	"math"
+
+	"go.mukunda.com/modlib/common"
+)
+
+const (
+	// Highest playback rate that the DSP pitch registers can express.
+	kMaxPlaybackRate = 128000
+
+	// Limit of the pattern, instrument, and sample pointer tables in the module header.
+	kMaxModuleEntries = 64
+
+	// Longest distance between two envelope nodes that the driver can express.
+	kMaxEnvelopeNodeTicks = 256
+)
+
+type linter struct {
+	mod      *common.Module
+	findings []Diagnostic
+
+	// Channels that have already been reported for being out of range, so each one is
+	// only reported once.
+	reportedChannels map[int]bool
+
+	// Instrument/note pairs that have already been reported for exceeding the pitch limit.
+	reportedNotes map[[2]int]bool
+}
+
+func (l *linter) add(code string, format string, a ...any) {
+	l.addAt(code, -1, -1, -1, format, a...)
+}
+
+func (l *linter) addAt(code string, pattern, row, channel int, format string, a ...any) {
+	loc := noLocation()
+	loc.Pattern, loc.Row, loc.Channel = pattern, row, channel
+
+	l.findings = append(l.findings, Diagnostic{
+		Severity: SeverityWarning,
+		Code:     code,
+		Message:  fmt.Sprintf(format, a...),
+		Location: loc,
+	})
+}
+
+// Checks a module against the SNESMOD composition rules and returns everything that
+// will not play back as it does in the tracker. An empty result means the module is
+// safe to convert. The diagnostics don't have a file in their location.
+func LintModule(mod *common.Module) []Diagnostic {
+	l := &linter{
+		mod:              mod,
+		reportedChannels: map[int]bool{},
+		reportedNotes:    map[[2]int]bool{},
+	}
+
+	l.lintHeader()
+	l.lintInstruments()
+	l.lintSamples()
+
+	for i := range mod.Patterns {
+		l.lintPattern(i, &mod.Patterns[i])
+	}
+
+	return l.findings
+}
+
+func (l *linter) lintHeader() {
+	mod := l.mod
+
+	if mod.Channels > 8 {
+		l.add("channels", "module uses %d channels, only 8 are supported", mod.Channels)
+	}
+
+	if !mod.InstrumentMode || len(mod.Instruments) == 0 {
+		l.add("sample-mode", "module must be in instrument mode and have instruments")
+	}
+
+	if !mod.LinearFrequencySlides {
+		l.add("linear-slides", "only linear frequency mode is supported")
+	}
+
+	if mod.OldEffects {
+		l.add("old-effects", "\"old effects\" must be off")
+	}
+
+	if len(mod.Patterns) > kMaxModuleEntries {
+		l.add("patterns", "module has %d patterns, the maximum is %d", len(mod.Patterns), kMaxModuleEntries)
+	}
+
+	if len(mod.Instruments) > kMaxModuleEntries {
+		l.add("instruments", "module has %d instruments, the maximum is %d", len(mod.Instruments), kMaxModuleEntries)
+	}
+
+	if len(mod.Samples) > kMaxModuleEntries {
+		l.add("samples", "module has %d samples, the maximum is %d", len(mod.Samples), kMaxModuleEntries)
+	}
+}
+
+func (l *linter) lintInstruments() {
+	for i, ins := range l.mod.Instruments {
+		num := i + 1
+
+		if ins.NewNoteAction != 0 {
+			l.add("nna", "instrument %d uses a new note action", num)
+		}
+
+		sample := int(ins.Notemap[60].Sample)
+		if sample == 0 || sample > len(l.mod.Samples) {
+			l.add("undefined-sample", "instrument %d does not map a valid sample at C-5", num)
+		}
+
+		l.lintNotemap(num, &ins)
+
+		for _, env := range ins.Envelopes {
+			switch env.Type {
+			case common.EnvelopeTypePanning:
+				l.add("pan-envelope", "instrument %d uses a panning envelope", num)
+				continue
+			case common.EnvelopeTypePitch:
+				l.add("pitch-envelope", "instrument %d uses a pitch envelope", num)
+				continue
+			}
+
+			if env.Sustain && env.SustainEnd != env.SustainStart {
+				l.add("envelope-sustain", "instrument %d sustain loop must be a single node", num)
+			}
+
+			for n := 1; n < len(env.Nodes); n++ {
+				if int(env.Nodes[n].X)-int(env.Nodes[n-1].X) > kMaxEnvelopeNodeTicks {
+					l.add("envelope-nodes", "instrument %d envelope nodes %d and %d are more than %d ticks apart",
+						num, n-1, n, kMaxEnvelopeNodeTicks)
+					break
+				}
+			}
+		}
+	}
+}
+
+// The driver only uses the middle entry of the note map, so every other entry must play
+// the same sample without transposing the note. Each instrument is reported at most once
+// per problem.
+func (l *linter) lintNotemap(num int, ins *common.Instrument) {
+	middle := ins.Notemap[60]
+	reportedSample, reportedNote := false, false
+
+	for n, entry := range ins.Notemap {
+		if !reportedSample && entry.Sample != middle.Sample {
+			reportedSample = true
+			l.add("sample-map", "instrument %d maps sample %d to note %d, only sample %d at C-5 is used",
+				num, entry.Sample, n+1, middle.Sample)
+		}
+
+		if !reportedNote && int(entry.Note)-n != int(middle.Note)-60 {
+			reportedNote = true
+			l.add("sample-map", "instrument %d transposes note %d, the note map is ignored", num, n+1)
+		}
+	}
+}
+
+func (l *linter) lintSamples() {
+	for i, sample := range l.mod.Samples {
+		// Sample data holds one slice per channel.
+		if len(sample.Data.Data) > 1 {
+			l.add("stereo-sample", "sample %d is stereo", i+1)
+		}
+	}
+}
+
+// Returns the playback rate of a note for an instrument, or 0 if it can't be determined.
+// The driver only uses the middle entry of the note map, so that's the sample used.
+func (l *linter) noteRate(note uint8, instrument int) float64 {
+	if instrument < 1 || instrument > len(l.mod.Instruments) {
+		return 0
+	}
+
+	sample := int(l.mod.Instruments[instrument-1].Notemap[60].Sample)
+	if sample < 1 || sample > len(l.mod.Samples) {
+		return 0
+	}
+
+	// Notes are 1-120, C-5 is 61.
+	return float64(l.mod.Samples[sample-1].C5) * math.Pow(2, float64(int(note)-61)/12.0)
+}
+
+func (l *linter) lintPattern(index int, pattern *common.Pattern) {
+	lastInstrument := [8]int{}
+
+	for r, row := range pattern.Rows {
+		for _, e := range row.Entries {
+			ch := int(e.Channel)
+
+			if ch >= 8 {
+				if !l.reportedChannels[ch] {
+					l.reportedChannels[ch] = true
+					l.addAt("channels", index, r, ch, "channel %d is not supported and will be dropped", ch+1)
+				}
+				continue
+			}
+
+			if e.Instrument != 0 {
+				lastInstrument[ch] = int(e.Instrument)
+				if int(e.Instrument) > len(l.mod.Instruments) {
+					l.addAt("undefined-instrument", index, r, ch, "instrument %d is not defined", e.Instrument)
+				}
+			}
+
+			if e.Note >= 1 && e.Note <= 120 {
+				key := [2]int{lastInstrument[ch], int(e.Note)}
+				rate := l.noteRate(e.Note, lastInstrument[ch])
+				if rate > kMaxPlaybackRate && !l.reportedNotes[key] {
+					l.reportedNotes[key] = true
+					l.addAt("pitch-limit", index, r, ch, "note plays at %.0f Hz, above the %d Hz limit",
+						rate, kMaxPlaybackRate)
+				}
+			}
+
+			switch e.VolumeCommand {
+			case VcmdPitchSlideDown, VcmdPitchSlideUp, VcmdPortaToNote, VcmdVibratoDepth:
+				l.addAt("volume-command", index, r, ch, "volume column E, F, G and H commands are not supported")
+			case VcmdFineVolUp, VcmdFineVolDown, VcmdVolSlideUp, VcmdVolSlideDown:
+				// There's no memory for volume commands, so a zero parameter does nothing.
+				if e.VolumeParam == 0 {
+					l.addAt("volume-command", index, r, ch, "volume column %c0 does nothing, there is no memory",
+						'A'+e.VolumeCommand-VcmdFineVolUp)
+				}
+			}
+
+			l.lintEffect(index, r, ch, int(e.Effect), int(e.EffectParam))
+		}
+	}
+}
+
+// Names of effects that the driver doesn't support, for messages.
+var unsupportedEffectNames = map[int]string{
+	EffectI: "tremor",
+	EffectR: "tremolo",
+	EffectY: "panbrello",
+}
+
+// Returns true if the driver plays an effect. Unsupported effects are ignored.
+func driverSupportsEffect(effect int, param int) bool {
+	switch effect {
+	case EffectI, EffectR, EffectY, EffectZ:
+		return false
+	case EffectS:
+		switch param >> 4 {
+		case 0x1, 0x2, 0x3, 0x4, 0x5, 0x7, 0x9, 0xA, 0xB:
+			return false
+		}
+	}
+	return true
+}
+
+// Returns true if the driver plays a volume column command, in the IT encoding. The
+// pitch commands (E, F, G, and H) are ignored.
+func driverSupportsVcmd(vcmd uint8) bool {
+	return vcmd <= 104 || (vcmd >= 128 && vcmd <= 192)
+}
+
+func (l *linter) lintEffect(pattern, row, channel int, effect int, param int) {
+	if effect == EffectC && param != 0 {
+		l.addAt("effect", pattern, row, channel, "C%02X must break to row 0", param)
+	}
+
+	if !driverSupportsEffect(effect, param) {
+		text := fmt.Sprintf("%c%02X", 'A'+effect-1, param)
+		if name, ok := unsupportedEffectNames[effect]; ok {
+			text = fmt.Sprintf("%s (%s)", name, text)
+		}
+		l.addAt("effect", pattern, row, channel, "%s is not supported", text)
+	}
+}
@@ -0,0 +1,101 @@
+// SNESMOD
+// (C) 2025 Mukunda Johnson (mukunda.com)
+// Licensed under MIT
+
+package smconv
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mukunda.com/modlib"
+)
+
+func TestLintModule(t *testing.T) {
+	mod, err := modlib.LoadModule("../test/pollen8.it")
+	assert.NoError(t, err)
+	assert.Empty(t, LintModule(mod))
+
+	// Break some rules. Take a copy of an existing entry and place it on a few rows.
+	pattern := &mod.Patterns[0]
+	entry := pattern.Rows[0].Entries[0]
+	channel := int(entry.Channel)
+
+	mod.Channels = 9
+
+	entry.Effect, entry.EffectParam = EffectC, 0x10
+	pattern.Rows[1].Entries = append(pattern.Rows[1].Entries[:0:0], entry)
+	entry.Effect, entry.EffectParam = EffectS, 0xB1
+	pattern.Rows[2].Entries = append(pattern.Rows[2].Entries[:0:0], entry)
+	entry.Effect, entry.EffectParam = 0, 0
+	entry.VolumeCommand = VcmdPortaToNote
+	pattern.Rows[3].Entries = append(pattern.Rows[3].Entries[:0:0], entry)
+
+	findings := LintModule(mod)
+	codes := []string{}
+	for _, f := range findings {
+		codes = append(codes, f.Code)
+	}
+
+	assert.Contains(t, codes, "channels")
+	assert.Contains(t, findings, Diagnostic{
+		Severity: SeverityWarning,
+		Code:     "effect",
+		Message:  "C10 must break to row 0",
+		Location: Location{Pattern: 0, Row: 1, Channel: channel},
+	})
+	assert.Contains(t, findings, Diagnostic{
+		Severity: SeverityWarning,
+		Code:     "effect",
+		Message:  "SB1 is not supported",
+		Location: Location{Pattern: 0, Row: 2, Channel: channel},
+	})
+	assert.Contains(t, codes, "volume-command")
+}
+
+func TestLintNotemap(t *testing.T) {
+	mod, err := modlib.LoadModule("../test/pollen8.it")
+	assert.NoError(t, err)
+
+	ins := &mod.Instruments[0]
+	ins.Notemap[10].Sample = ins.Notemap[60].Sample + 1
+	ins.Notemap[70].Note++
+
+	assert.Equal(t, []Diagnostic{
+		{
+			Severity: SeverityWarning,
+			Code:     "sample-map",
+			Message:  fmt.Sprintf("instrument 1 maps sample %d to note 11, only sample %d at C-5 is used", ins.Notemap[10].Sample, ins.Notemap[60].Sample),
+			Location: noLocation(),
+		},
+		{
+			Severity: SeverityWarning,
+			Code:     "sample-map",
+			Message:  "instrument 1 transposes note 71, the note map is ignored",
+			Location: noLocation(),
+		},
+	}, LintModule(mod))
+}
+
+func TestLintVolumeMemory(t *testing.T) {
+	mod, err := modlib.LoadModule("../test/pollen8.it")
+	assert.NoError(t, err)
+
+	pattern := &mod.Patterns[0]
+	entry := pattern.Rows[0].Entries[0]
+	channel := int(entry.Channel)
+
+	entry.Effect, entry.EffectParam = 0, 0
+	entry.VolumeCommand, entry.VolumeParam = VcmdVolSlideUp, 0
+	pattern.Rows[1].Entries = append(pattern.Rows[1].Entries[:0:0], entry)
+	entry.VolumeCommand, entry.VolumeParam = VcmdFineVolDown, 2
+	pattern.Rows[2].Entries = append(pattern.Rows[2].Entries[:0:0], entry)
+
+	assert.Equal(t, []Diagnostic{{
+		Severity: SeverityWarning,
+		Code:     "volume-command",
+		Message:  "volume column C0 does nothing, there is no memory",
+		Location: Location{Pattern: 0, Row: 1, Channel: channel},
+	}}, LintModule(mod))
+}
@@ -0,0 +1,210 @@
+// SNESMOD
+// (C) 2025 Mukunda Johnson (mukunda.com)
+// Licensed under MIT
+
+// This file describes the ID lockfile, which pins the indices of modules and sound
+// effects in the definitions file. Without it, indices depend on the input order, so
+// adding one song can renumber everything that comes after it.
+
+package smconv
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+)
+
+// Maximum number of modules in the soundbank's module table.
+const kMaxBankModules = 128
+
+var ErrInvalidLock = errors.New("invalid lockfile")
+
+// Maps symbols to their indices in the soundbank.
+type IdLock struct {
+	Modules map[string]int `json:"modules"`
+	Effects map[string]int `json:"effects"`
+}
+
+func NewIdLock() *IdLock {
+	return &IdLock{
+		Modules: map[string]int{},
+		Effects: map[string]int{},
+	}
+}
+
+// Load a lockfile. If the file doesn't exist, an empty lock is returned so that the
+// first build can create it.
+func LoadIdLock(filename string) (*IdLock, error) {
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		return NewIdLock(), nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	lock := NewIdLock()
+	if err := json.Unmarshal(data, lock); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidLock, err)
+	}
+	if lock.Modules == nil {
+		lock.Modules = map[string]int{}
+	}
+	if lock.Effects == nil {
+		lock.Effects = map[string]int{}
+	}
+
+	if err := lock.validate(); err != nil {
+		return nil, err
+	}
+
+	return lock, nil
+}
+
+func (lock *IdLock) validate() error {
+	check := func(ids map[string]int, limit int) error {
+		used := map[int]string{}
+		for id, index := range ids {
+			if index < 0 || index >= limit {
+				return fmt.Errorf("%w: index %d of %s is out of range", ErrInvalidLock, index, id)
+			}
+			if other, ok := used[index]; ok {
+				return fmt.Errorf("%w: %s and %s have the same index", ErrInvalidLock, other, id)
+			}
+			used[index] = id
+		}
+		return nil
+	}
+
+	if err := check(lock.Modules, kMaxBankModules); err != nil {
+		return err
+	}
+	return check(lock.Effects, 65536)
+}
+
+// Write the lockfile. Keys are sorted, so the file only changes when the IDs do.
+func (lock *IdLock) Save(filename string) error {
+	data, err := json.MarshalIndent(lock, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, append(data, '\n'), 0644)
+}
+
+// A module that holds the index of a removed module. It ends immediately if played.
+func placeholderModule() *SmModule {
+	smm := &SmModule{}
+	for i := range smm.Header.Sequence {
+		smm.Header.Sequence[i] = 255
+	}
+	return smm
+}
+
+// A source that holds the index of a removed effect. It's one silent BRR block.
+func placeholderSource() *Source {
+	source := &Source{
+		Data:         make([]byte, kBrrBlockSize),
+		TuningFactor: 1.0,
+	}
+	source.Data[0] = kBrrEndFlag
+	source.updateHash()
+	return source
+}
+
+// Arrange a list so that locked IDs keep their indices. Entries that aren't in the lock
+// fill the unreserved slots and are then appended, and new IDs are added to the lock.
+// Locked IDs that aren't in the list get a placeholder and are returned as removed.
+func arrangeLocked[T any](items []T, idOf func(T) string, locked map[string]int, placeholder func() T) (arranged []T, order []int, removed []string) {
+	size := 0
+	for _, index := range locked {
+		size = max(size, index+1)
+	}
+
+	arranged = make([]T, size)
+	filled := make([]bool, size)
+	order = make([]int, len(items))
+	present := map[string]bool{}
+	unlocked := []int{}
+
+	for i, item := range items {
+		id := idOf(item)
+		index, ok := locked[id]
+		if id == "" || !ok || present[id] {
+			unlocked = append(unlocked, i)
+			continue
+		}
+		present[id] = true
+		arranged[index] = item
+		filled[index] = true
+		order[i] = index
+	}
+
+	for id, index := range locked {
+		if !present[id] {
+			removed = append(removed, id)
+			arranged[index] = placeholder()
+			filled[index] = true
+		}
+	}
+	sort.Strings(removed)
+
+	free := 0
+	for _, i := range unlocked {
+		for free < size && filled[free] {
+			free++
+		}
+
+		if free < size {
+			order[i] = free
+			arranged[free] = items[i]
+			filled[free] = true
+		} else {
+			order[i] = len(arranged)
+			arranged = append(arranged, items[i])
+		}
+
+		if id := idOf(items[i]); id != "" && !present[id] {
+			present[id] = true
+			locked[id] = order[i]
+		}
+	}
+
+	// Unreserved slots that weren't filled still need an entry.
+	for index := range arranged[:size] {
+		if !filled[index] {
+			arranged[index] = placeholder()
+		}
+	}
+
+	return arranged, order, removed
+}
+
+// Reorder the bank so that IDs in the lock keep their indices, and add new IDs to the
+// lock. Returns the IDs that are in the lock but no longer in the bank. Their indices
+// are kept reserved with placeholders so they aren't reused, until they're removed from
+// the lockfile.
+func (bank *SoundBank) ApplyLock(lock *IdLock) (removed []string, err error) {
+	modules, _, removedModules := arrangeLocked(bank.Modules,
+		func(m *SmModule) string { return m.Id }, lock.Modules, placeholderModule)
+
+	if len(modules) > kMaxBankModules {
+		return nil, fmt.Errorf("%w: the bank has %d modules, the maximum is %d",
+			ErrInvalidLock, len(modules), kMaxBankModules)
+	}
+
+	sources, order, removedEffects := arrangeLocked(bank.Sources,
+		func(s *Source) string { return s.Id }, lock.Effects, placeholderSource)
+
+	// Modules refer to sources by index.
+	for _, mod := range modules {
+		for i, index := range mod.SourceList {
+			mod.SourceList[i] = SourceIndex(order[index])
+		}
+	}
+
+	bank.Modules = modules
+	bank.Sources = sources
+	return append(removedModules, removedEffects...), nil
+}
@@ -0,0 +1,85 @@
+// SNESMOD
+// (C) 2025 Mukunda Johnson (mukunda.com)
+// Licensed under MIT
+
+package smconv
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mukunda.com/modlib"
+)
+
+func TestApplyLock(t *testing.T) {
+	mod, _ := modlib.LoadModule("test/reflection.it")
+	build := func(modules []string, effects []string) *SoundBank {
+		bank := &SoundBank{}
+		for _, name := range modules {
+			assert.NoError(t, bank.AddModuleWithOptions(mod, "test/reflection.it", ModuleOptions{Id: name}))
+		}
+		for i, name := range effects {
+			_, err := bank.AddBrrSoundEffect(&Source{Data: buildBrr(i+1, kBrrEndFlag)}, name)
+			assert.NoError(t, err)
+			bank.Sources[len(bank.Sources)-1].updateHash()
+		}
+		return bank
+	}
+
+	lock := NewIdLock()
+	bank := build([]string{"MOD_A", "MOD_B"}, []string{"SFX_A", "SFX_B"})
+	removed, err := bank.ApplyLock(lock)
+	assert.NoError(t, err)
+	assert.Empty(t, removed)
+	assert.Equal(t, map[string]int{"MOD_A": 0, "MOD_B": 1}, lock.Modules)
+	assert.Equal(t, map[string]int{"SFX_A": 1, "SFX_B": 2}, lock.Effects)
+
+	assert.NoError(t, lock.Save(".testdata-lock.json"))
+	lock, err = LoadIdLock(".testdata-lock.json")
+	assert.NoError(t, err)
+
+	// A new module before the others and a removed effect don't move the locked IDs.
+	bank = build([]string{"MOD_NEW", "MOD_B", "MOD_A"}, []string{"SFX_B", "SFX_C"})
+	removed, err = bank.ApplyLock(lock)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"SFX_A"}, removed)
+
+	assert.Equal(t, "MOD_A", bank.Modules[0].Id)
+	assert.Equal(t, "MOD_B", bank.Modules[1].Id)
+	assert.Equal(t, "MOD_NEW", bank.Modules[2].Id)
+	assert.Equal(t, 2, lock.Modules["MOD_NEW"])
+
+	// The module's source fills the free slot, and the removed effect's slot holds a
+	// placeholder.
+	assert.Equal(t, "", bank.Sources[0].Id)
+	assert.Greater(t, len(bank.Sources[0].Data), kBrrBlockSize)
+	assert.Equal(t, "", bank.Sources[1].Id)
+	assert.Len(t, bank.Sources[1].Data, kBrrBlockSize)
+	assert.Equal(t, "SFX_B", bank.Sources[2].Id)
+	assert.Equal(t, "SFX_C", bank.Sources[3].Id)
+	assert.Equal(t, 3, lock.Effects["SFX_C"])
+	for _, m := range bank.Modules {
+		assert.Equal(t, []uint16{0}, m.SourceList)
+	}
+
+	// The removed ID stays in the lock so its index isn't reused.
+	assert.Equal(t, 1, lock.Effects["SFX_A"])
+
+	// A bank with placeholders can be exported.
+	assert.NoError(t, bank.Export(".testdata-lock.smbank", false))
+}
+
+func TestLoadIdLock(t *testing.T) {
+	lock, err := LoadIdLock(".testdata-missing.json")
+	assert.NoError(t, err)
+	assert.Empty(t, lock.Modules)
+
+	assert.NoError(t, os.WriteFile(".testdata-lock-bad.json", []byte(`{"modules": {"MOD_A": 1, "MOD_B": 1}}`), 0644))
+	_, err = LoadIdLock(".testdata-lock-bad.json")
+	assert.ErrorIs(t, err, ErrInvalidLock)
+
+	assert.NoError(t, os.WriteFile(".testdata-lock-bad.json", []byte(`{"modules": {"MOD_A": 128}}`), 0644))
+	_, err = LoadIdLock(".testdata-lock-bad.json")
+	assert.ErrorIs(t, err, ErrInvalidLock)
+}
@@ -0,0 +1,219 @@
+// SNESMOD
+// (C) 2025 Mukunda Johnson (mukunda.com)
+// Licensed under MIT
+
+// This file describes the project manifest, which lists everything that goes into a
+// soundbank so that the build can be kept in version control. Manifests can be YAML,
+// JSON, or TOML, chosen by the file extension.
+
+package smconv
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"go.mukunda.com/modlib"
+	"gopkg.in/yaml.v3"
+)
+
+var ErrInvalidManifest = errors.New("invalid manifest")
+
+type Manifest struct {
+	// Base filename for the soundbank outputs (.smbank, .asm, and .inc).
+	Output string `yaml:"output" json:"output" toml:"output"`
+
+	// Use HIROM mapping for the soundbank.
+	HiRom bool `yaml:"hirom" json:"hirom" toml:"hirom"`
+
+	// Optional ID lockfile that pins the indices of modules and sound effects.
+	Lock string `yaml:"lock" json:"lock" toml:"lock"`
+
+	// Prefixes for IDs made from file and sample names. Empty uses MOD_ and SFX_.
+	ModulePrefix string `yaml:"modulePrefix" json:"modulePrefix" toml:"modulePrefix"`
+	EffectPrefix string `yaml:"effectPrefix" json:"effectPrefix" toml:"effectPrefix"`
+
+	// "error" (default) or "rename". See SoundBank.ResolveIds.
+	IdCollisions string `yaml:"idCollisions" json:"idCollisions" toml:"idCollisions"`
+
+	// Size of the sound region that the game allocates, in 256-byte pages.
+	SoundRegion int `yaml:"soundRegion" json:"soundRegion" toml:"soundRegion"`
+
+	// SPC tags for every module, e.g. the game title. Module tags override them.
+	Tags ModuleTags `yaml:"tags" json:"tags" toml:"tags"`
+
+	Modules []ManifestModule `yaml:"modules" json:"modules" toml:"modules"`
+	Effects []ManifestEffect `yaml:"effects" json:"effects" toml:"effects"`
+}
+
+type ManifestModule struct {
+	File          string `yaml:"file" json:"file" toml:"file"`
+	ModuleOptions `yaml:",inline"`
+}
+
+type ManifestEffect struct {
+	File          string `yaml:"file" json:"file" toml:"file"`
+	EffectOptions `yaml:",inline"`
+}
+
+// Load a manifest file. Paths in the manifest are relative to the manifest's directory.
+func LoadManifest(filename string) (*Manifest, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := ParseManifest(data, filepath.Ext(filename))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+
+	m.resolvePaths(filepath.Dir(filename))
+	return m, nil
+}
+
+// Parse a manifest. `format` is the file extension: .yaml, .yml, .json, or .toml.
+// Unknown keys are an error, so typos in option names don't go unnoticed.
+func ParseManifest(data []byte, format string) (*Manifest, error) {
+	m := &Manifest{}
+
+	switch strings.ToLower(format) {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(m); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidManifest, err)
+		}
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(m); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidManifest, err)
+		}
+	case ".toml":
+		meta, err := toml.Decode(string(data), m)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidManifest, err)
+		}
+		if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+			return nil, fmt.Errorf("%w: unknown key %s", ErrInvalidManifest, undecoded[0])
+		}
+	default:
+		return nil, fmt.Errorf("%w: unknown format \"%s\"", ErrInvalidManifest, format)
+	}
+
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// Check the manifest for missing fields and invalid options.
+func (m *Manifest) Validate() error {
+	errs := []error{}
+
+	if m.Output == "" {
+		errs = append(errs, fmt.Errorf("%w: output is required", ErrInvalidManifest))
+	}
+
+	for _, prefix := range []string{m.ModulePrefix, m.EffectPrefix} {
+		if prefix != "" && !IsValidId(prefix) {
+			errs = append(errs, fmt.Errorf("%w: invalid id prefix \"%s\"", ErrInvalidManifest, prefix))
+		}
+	}
+
+	if m.IdCollisions != "" && m.IdCollisions != "error" && m.IdCollisions != "rename" {
+		errs = append(errs, fmt.Errorf("%w: idCollisions must be \"error\" or \"rename\"", ErrInvalidManifest))
+	}
+
+	if m.SoundRegion < 0 || m.SoundRegion > 255 {
+		errs = append(errs, fmt.Errorf("%w: soundRegion out of range: %d", ErrInvalidManifest, m.SoundRegion))
+	}
+
+	if err := m.Tags.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if len(m.Modules) == 0 && len(m.Effects) == 0 {
+		errs = append(errs, fmt.Errorf("%w: no modules or effects", ErrInvalidManifest))
+	}
+
+	for i, mod := range m.Modules {
+		if mod.File == "" {
+			errs = append(errs, fmt.Errorf("%w: module %d has no file", ErrInvalidManifest, i+1))
+		}
+		if err := mod.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("module %s: %w", mod.File, err))
+		}
+	}
+
+	for i, sfx := range m.Effects {
+		if sfx.File == "" {
+			errs = append(errs, fmt.Errorf("%w: effect %d has no file", ErrInvalidManifest, i+1))
+		}
+		if sfx.Rate < 0 {
+			errs = append(errs, fmt.Errorf("effect %s: %w: invalid rate %d", sfx.File, ErrInvalidEffectOptions, sfx.Rate))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (m *Manifest) resolvePaths(dir string) {
+	resolve := func(path string) string {
+		if path == "" || filepath.IsAbs(path) {
+			return path
+		}
+		return filepath.Join(dir, path)
+	}
+
+	m.Output = resolve(m.Output)
+	m.Lock = resolve(m.Lock)
+	for i := range m.Modules {
+		m.Modules[i].File = resolve(m.Modules[i].File)
+	}
+	for i := range m.Effects {
+		m.Effects[i].File = resolve(m.Effects[i].File)
+	}
+}
+
+// Load and convert everything in the manifest into a new soundbank.
+func (m *Manifest) Build() (*SoundBank, error) {
+	bank := &SoundBank{
+		HiRom:        m.HiRom,
+		ModulePrefix: m.ModulePrefix,
+		EffectPrefix: m.EffectPrefix,
+		SoundRegion:  m.SoundRegion,
+	}
+
+	for _, entry := range m.Modules {
+		mod, err := modlib.LoadModule(entry.File)
+		if err != nil {
+			return nil, fmt.Errorf("loading module %s: %w", entry.File, err)
+		}
+
+		opts := entry.ModuleOptions
+		opts.Tags = m.Tags.Merge(opts.Tags)
+		if err := bank.AddModuleWithOptions(mod, entry.File, opts); err != nil {
+			return nil, fmt.Errorf("converting module %s: %w", entry.File, err)
+		}
+	}
+
+	for _, entry := range m.Effects {
+		if err := bank.AddSoundEffectFile(entry.File, entry.EffectOptions); err != nil {
+			return nil, fmt.Errorf("converting sound effects %s: %w", entry.File, err)
+		}
+	}
+
+	if err := bank.ResolveIds(m.IdCollisions == "rename"); err != nil {
+		return nil, err
+	}
+
+	return bank, nil
+}
@@ -0,0 +1,181 @@
+// SNESMOD
+// (C) 2025 Mukunda Johnson (mukunda.com)
+// Licensed under MIT
+
+package smconv
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseManifest(t *testing.T) {
+	yamlText := `
+output: build/soundbank
+hirom: true
+tags:
+  game: Quest
+  length: 120
+modules:
+  - file: music/town.it
+    id: MOD_TOWN_THEME
+    echo:
+      delay: 4
+      volume: [20, 30]
+      channels: [1, 2]
+    tags:
+      title: Town
+      author: Someone
+effects:
+  - file: sfx/jump.wav
+    rate: 16000
+  - file: sfx/sfx.it
+    sample: 3
+    id: SFX_COIN
+`
+	jsonText := `{
+	"output": "build/soundbank",
+	"hirom": true,
+	"tags": {"game": "Quest", "length": 120},
+	"modules": [{
+		"file": "music/town.it",
+		"id": "MOD_TOWN_THEME",
+		"echo": {"delay": 4, "volume": [20, 30], "channels": [1, 2]},
+		"tags": {"title": "Town", "author": "Someone"}
+	}],
+	"effects": [
+		{"file": "sfx/jump.wav", "rate": 16000},
+		{"file": "sfx/sfx.it", "sample": 3, "id": "SFX_COIN"}
+	]
+}`
+	tomlText := `
+output = "build/soundbank"
+hirom = true
+tags = { game = "Quest", length = 120 }
+
+[[modules]]
+file = "music/town.it"
+id = "MOD_TOWN_THEME"
+echo = { delay = 4, volume = [20, 30], channels = [1, 2] }
+tags = { title = "Town", author = "Someone" }
+
+[[effects]]
+file = "sfx/jump.wav"
+rate = 16000
+
+[[effects]]
+file = "sfx/sfx.it"
+sample = 3
+id = "SFX_COIN"
+`
+
+	for format, text := range map[string]string{".yaml": yamlText, ".json": jsonText, ".toml": tomlText} {
+		m, err := ParseManifest([]byte(text), format)
+		if !assert.NoError(t, err, format) {
+			continue
+		}
+
+		assert.Equal(t, "build/soundbank", m.Output, format)
+		assert.True(t, m.HiRom, format)
+		assert.Len(t, m.Modules, 1, format)
+		assert.Equal(t, "music/town.it", m.Modules[0].File, format)
+		assert.Equal(t, "MOD_TOWN_THEME", m.Modules[0].Id, format)
+		assert.Equal(t, 4, *m.Modules[0].Echo.Delay, format)
+		assert.Nil(t, m.Modules[0].Echo.Feedback, format)
+		assert.Equal(t, []int{20, 30}, m.Modules[0].Echo.Volume, format)
+		assert.Equal(t, []int{1, 2}, m.Modules[0].Echo.Channels, format)
+		assert.Equal(t, ModuleTags{Title: "Town", Author: "Someone"}, m.Modules[0].Tags, format)
+		assert.Equal(t, "Quest", m.Tags.Game, format)
+		assert.Equal(t, 120, *m.Tags.Length, format)
+		assert.Equal(t, []ManifestEffect{
+			{File: "sfx/jump.wav", EffectOptions: EffectOptions{Rate: 16000}},
+			{File: "sfx/sfx.it", EffectOptions: EffectOptions{Sample: 3, Id: "SFX_COIN"}},
+		}, m.Effects, format)
+	}
+}
+
+func TestParseManifestErrors(t *testing.T) {
+	_, err := ParseManifest([]byte("output: x\nmodules:\n  - file: a.it\n    eccho: {}\n"), ".yaml")
+	assert.ErrorIs(t, err, ErrInvalidManifest, "unknown key")
+
+	_, err = ParseManifest([]byte(`output = "x"
+[[modules]]
+file = "a.it"
+typo = 1
+`), ".toml")
+	assert.ErrorIs(t, err, ErrInvalidManifest, "unknown key")
+
+	_, err = ParseManifest([]byte(`{"modules": [{"file": "a.it"}]}`), ".json")
+	assert.ErrorIs(t, err, ErrInvalidManifest, "no output")
+
+	_, err = ParseManifest([]byte("output: x\n"), ".yaml")
+	assert.ErrorIs(t, err, ErrInvalidManifest, "nothing to build")
+
+	_, err = ParseManifest([]byte("output: x\nmodules:\n  - file: a.it\n    echo: {delay: 16, fir: [1]}\n"), ".yaml")
+	assert.ErrorIs(t, err, ErrInvalidModuleOptions)
+	assert.ErrorContains(t, err, "echo delay value out of range: 16")
+	assert.ErrorContains(t, err, "echo fir needs 8 values")
+
+	_, err = ParseManifest([]byte("output: x\ntags: {date: 2025/01/01}\nmodules:\n  - file: a.it\n"), ".yaml")
+	assert.ErrorIs(t, err, ErrInvalidModuleOptions, "bad date")
+
+	_, err = ParseManifest([]byte("output: x\n"), ".ini")
+	assert.ErrorIs(t, err, ErrInvalidManifest)
+}
+
+func TestBuildManifest(t *testing.T) {
+	text := `output: .testdata-manifest
+tags:
+  game: Quest
+  author: Nobody
+modules:
+  - file: test/reflection.it
+    id: MOD_REFLECT
+    echo:
+      delay: 1
+      feedback: -20
+      volume: [40]
+      fir: [64, 32, 0, 0, 0, 0, 0, 0]
+      channels: [8]
+    tags:
+      title: Reflection
+effects:
+  - file: ../../example/sound/tada.brr
+    id: SFX_FANFARE
+  - file: test/reflection.it
+`
+	assert.NoError(t, os.WriteFile(".testdata-manifest.yaml", []byte(text), 0644))
+	m, err := LoadManifest(".testdata-manifest.yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join("..", "..", "example", "sound", "tada.brr"), m.Effects[0].File)
+
+	bank, err := m.Build()
+	assert.NoError(t, err)
+	assert.Len(t, bank.Modules, 1)
+
+	mod := bank.Modules[0]
+	assert.Equal(t, "MOD_REFLECT", mod.Id)
+	assert.Equal(t, "Reflection", mod.Title)
+	assert.Equal(t, "Quest", mod.SpcTags(ModuleTags{}).Game)
+	assert.Equal(t, "Nobody", mod.SpcTags(ModuleTags{}).Author)
+	assert.EqualValues(t, 1, mod.Header.EchoDelay)
+	assert.EqualValues(t, -20, mod.Header.EchoFeedback)
+	assert.EqualValues(t, 40, mod.Header.EchoVolumeL)
+	assert.EqualValues(t, 40, mod.Header.EchoVolumeR)
+	assert.Equal(t, [8]int8{64, 32}, mod.Header.EchoFir)
+	assert.EqualValues(t, 0x80, mod.Header.EchoEnable)
+
+	ids := []string{}
+	for _, s := range bank.Sources {
+		ids = append(ids, s.Id)
+	}
+	assert.Contains(t, ids, "SFX_FANFARE")
+
+	// An id for a module with more than one effect is ambiguous.
+	m.Effects = []ManifestEffect{{File: "test/reflection.it", EffectOptions: EffectOptions{Id: "SFX_X"}}}
+	_, err = m.Build()
+	assert.ErrorIs(t, err, ErrInvalidEffectOptions)
+}
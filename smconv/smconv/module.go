@@ -20,12 +20,15 @@ import (
 )
 
 var ErrInvalidSmOptions = errors.New("invalid snesmod options")
+var ErrInvalidModuleOptions = errors.New("invalid module options")
 
 // See soundbank.txt for binary format documentation.
 
 // Break up the structure to work with encoding/binary.
 type SmModule struct {
 	Id          string
+	explicitId  bool
+	Filename    string
 	BankHeader  SmModuleBankHeader
 	SourceList  []uint16
 	Header      SmModuleHeader
@@ -34,16 +37,33 @@ type SmModule struct {
 	Samples     []*SmSample
 
 	// Warnings gathered during conversion.
-	Warnings []string
+	Warnings []Diagnostic
 
 	// Metadata (used for SPC)
 	Title       string
 	Author      string
+	Game        string
 	SongMessage string
+
+	// SPC tags from the module options, which override the metadata.
+	Tags ModuleTags
+}
+
+func (smm *SmModule) warn(code string, loc Location, msg string) {
+	loc.File = smm.Filename
+	smm.Warnings = append(smm.Warnings, Diagnostic{
+		Severity: SeverityWarning,
+		Code:     code,
+		Message:  msg,
+		Location: loc,
+	})
 }
 
-func (smm *SmModule) warn(msg string) {
-	smm.Warnings = append(smm.Warnings, msg)
+// Warn about a line in the song message.
+func (smm *SmModule) warnMessage(code string, line int, msg string) {
+	loc := noLocation()
+	loc.MessageLine = line
+	smm.warn(code, loc, msg)
 }
 
 // SmModule is a SNESMOD module stored in a cartridge ROM area.
@@ -118,32 +138,33 @@ type SmSample struct {
 	SetPanning     uint8  // 0-64, &128 = disabled
 }
 
-func (smm *SmModule) readSmoIntArgs(tokens []string, minargs int, maxargs int, minval int, maxval int) []int {
+// `line` is the song message line that the tokens came from, for warnings.
+func (smm *SmModule) readSmoIntArgs(line int, tokens []string, minargs int, maxargs int, minval int, maxval int) []int {
 	cmd := tokens[0]
 	args := []int{}
 
 	if len(tokens) < 1+minargs {
-		smm.warn("Not enough params for " + cmd + " command.")
+		smm.warnMessage("smo-args", line, "Not enough params for "+cmd+" command.")
 		return nil
 	}
 
 	if len(tokens) > 1+maxargs {
-		smm.warn("Too many params for " + cmd + " command.")
+		smm.warnMessage("smo-args", line, "Too many params for "+cmd+" command.")
 		return nil
 	}
 
 	for i := 1; i < len(tokens); i++ {
 		arg, err := strconv.Atoi(tokens[i])
 		if err != nil {
-			smm.warn("Error parsing " + cmd + " command value.")
+			smm.warnMessage("smo-value", line, "Error parsing "+cmd+" command value.")
 			return nil
 		}
 		if arg < minval {
-			smm.warn(fmt.Sprintf("%s value out of range: %d", cmd, arg))
+			smm.warnMessage("smo-range", line, fmt.Sprintf("%s value out of range: %d", cmd, arg))
 			arg = minval
 		}
 		if arg > maxval {
-			smm.warn(fmt.Sprintf("%s value out of range: %d", cmd, arg))
+			smm.warnMessage("smo-range", line, fmt.Sprintf("%s value out of range: %d", cmd, arg))
 			arg = int(maxval)
 		}
 
@@ -154,25 +175,28 @@ func (smm *SmModule) readSmoIntArgs(tokens []string, minargs int, maxargs int, m
 }
 
 // Returns text that is found between [[section]] and [[/section]]. If the closing tag
-// is not present, then the remainder of the text is selected.
+// is not present, then the remainder of the text is selected. The tags are matched
+// without case on the original bytes, since lowercasing can change the length of text
+// that isn't ASCII and shift the offsets.
 func getTextSection(text, section string) string {
-
-	start := strings.ToLower("[[" + section + "]]")
-	end := strings.ToLower("[[/" + section + "]]")
-
-	startIdx := strings.Index(strings.ToLower(text), start)
-	if startIdx == -1 {
+	start := sectionTag("", section).FindStringIndex(text)
+	if start == nil {
 		return ""
 	}
 
-	text = text[startIdx+len(start):]
+	text = text[start[1]:]
 
-	endIdx := strings.Index(strings.ToLower(text), end)
-	if endIdx == -1 {
+	end := sectionTag("/", section).FindStringIndex(text)
+	if end == nil {
 		return text
 	}
 
-	return text[:endIdx]
+	return text[:end[0]]
+}
+
+// Returns a regexp that matches [[section]] or [[/section]] in any case.
+func sectionTag(prefix, section string) *regexp.Regexp {
+	return regexp.MustCompile(`(?i)\[\[` + prefix + regexp.QuoteMeta(section) + `\]\]`)
 }
 
 var reStripSnesmod = regexp.MustCompile(`(?i)\[\[snesmod\]\][\S\s]*?(\[\[/snesmod\]\]|$)`)
@@ -186,12 +210,23 @@ func stripSnesmodTag(text string) string {
 func (smm *SmModule) parseSmOptions(mod *modlib.Module) {
 	smm.SongMessage = stripSnesmodTag(mod.Message)
 
-	text := getTextSection(mod.Message, "snesmod")
+	message := strings.ReplaceAll(mod.Message, "\r\n", "\n")
+	message = strings.ReplaceAll(message, "\r", "\n")
+
+	text := getTextSection(message, "snesmod")
+
+	// The section starts on the same line as the [[SNESMOD]] tag. Count the lines before
+	// it so warnings can point at the right line of the message.
+	firstLine := 1
+	if text != "" {
+		tagStart := sectionTag("", "snesmod").FindStringIndex(message)[0]
+		firstLine += strings.Count(message[:tagStart], "\n")
+	}
 
-	text = strings.ReplaceAll(text, "\r", "\n")
 	lines := strings.Split(text, "\n")
 
-	for _, line := range lines {
+	for i, line := range lines {
+		lineNumber := firstLine + i
 		line = strings.TrimSpace(line)
 		if line == "" {
 			continue
@@ -201,15 +236,15 @@ func (smm *SmModule) parseSmOptions(mod *modlib.Module) {
 
 		switch strings.ToLower(tokens[0]) {
 		case "edl":
-			if args := smm.readSmoIntArgs(tokens, 1, 1, 0, 15); args != nil {
+			if args := smm.readSmoIntArgs(lineNumber, tokens, 1, 1, 0, 15); args != nil {
 				smm.Header.EchoDelay = uint8(args[0])
 			}
 		case "efb":
-			if args := smm.readSmoIntArgs(tokens, 1, 1, -128, 127); args != nil {
+			if args := smm.readSmoIntArgs(lineNumber, tokens, 1, 1, -128, 127); args != nil {
 				smm.Header.EchoFeedback = int8(args[0])
 			}
 		case "evol":
-			if args := smm.readSmoIntArgs(tokens, 1, 2, -128, 127); args != nil {
+			if args := smm.readSmoIntArgs(lineNumber, tokens, 1, 2, -128, 127); args != nil {
 				smm.Header.EchoVolumeL = int8(args[0])
 				if len(args) == 2 {
 					smm.Header.EchoVolumeR = int8(args[1])
@@ -218,31 +253,131 @@ func (smm *SmModule) parseSmOptions(mod *modlib.Module) {
 				}
 			}
 		case "efir":
-			if args := smm.readSmoIntArgs(tokens, 1, 8, -128, 127); args != nil {
+			if args := smm.readSmoIntArgs(lineNumber, tokens, 1, 8, -128, 127); args != nil {
 				for i := 0; i < 8; i++ {
 					smm.Header.EchoFir[i] = int8(args[i])
 				}
 			}
 		case "eon":
-			if args := smm.readSmoIntArgs(tokens, 1, 8, 1, 8); args != nil {
+			if args := smm.readSmoIntArgs(lineNumber, tokens, 1, 8, 1, 8); args != nil {
 				enabled := 0
 				for i := 0; i < len(args); i++ {
 					enabled |= (1 << (args[i] - 1))
 				}
 				smm.Header.EchoEnable = uint8(enabled)
 			}
+		case "author":
+			smm.Author = strings.TrimSpace(line[len(tokens[0]):])
+		case "game":
+			smm.Game = strings.TrimSpace(line[len(tokens[0]):])
+		default:
+			smm.warnMessage("smo-unknown", lineNumber, "Unknown command: "+tokens[0])
+		}
+	}
+}
+
+// Options for a module in a soundbank. They override the settings that the module gives
+// in its song message.
+type ModuleOptions struct {
+	// Symbol for the module in the definitions file. Defaults to MOD_ and the file name.
+	Id string `yaml:"id" json:"id" toml:"id"`
+
+	Echo EchoOptions `yaml:"echo" json:"echo" toml:"echo"`
+	Tags ModuleTags  `yaml:"tags" json:"tags" toml:"tags"`
+}
+
+// Echo settings, with the same meaning and ranges as the [[SNESMOD]] commands in the song
+// message. Unset fields keep the module's own settings.
+type EchoOptions struct {
+	Delay    *int  `yaml:"delay" json:"delay" toml:"delay"`          // edl
+	Feedback *int  `yaml:"feedback" json:"feedback" toml:"feedback"` // efb
+	Volume   []int `yaml:"volume" json:"volume" toml:"volume"`       // evol, one value or left and right
+	Fir      []int `yaml:"fir" json:"fir" toml:"fir"`                // efir, 8 values
+	Channels []int `yaml:"channels" json:"channels" toml:"channels"` // eon, channels 1-8
+}
+
+func checkOptionRange(name string, value int, minval int, maxval int) error {
+	if value < minval || value > maxval {
+		return fmt.Errorf("%w: %s value out of range: %d", ErrInvalidModuleOptions, name, value)
+	}
+	return nil
+}
+
+func (opts *ModuleOptions) Validate() error {
+	echo := &opts.Echo
+	errs := []error{}
+
+	if echo.Delay != nil {
+		errs = append(errs, checkOptionRange("echo delay", *echo.Delay, 0, 15))
+	}
+	if echo.Feedback != nil {
+		errs = append(errs, checkOptionRange("echo feedback", *echo.Feedback, -128, 127))
+	}
+	if echo.Volume != nil && len(echo.Volume) != 1 && len(echo.Volume) != 2 {
+		errs = append(errs, fmt.Errorf("%w: echo volume needs 1 or 2 values", ErrInvalidModuleOptions))
+	}
+	for _, v := range echo.Volume {
+		errs = append(errs, checkOptionRange("echo volume", v, -128, 127))
+	}
+	if echo.Fir != nil && len(echo.Fir) != 8 {
+		errs = append(errs, fmt.Errorf("%w: echo fir needs 8 values", ErrInvalidModuleOptions))
+	}
+	for _, v := range echo.Fir {
+		errs = append(errs, checkOptionRange("echo fir", v, -128, 127))
+	}
+	for _, v := range echo.Channels {
+		errs = append(errs, checkOptionRange("echo channel", v, 1, 8))
+	}
+
+	errs = append(errs, opts.Tags.Validate())
+
+	return errors.Join(errs...)
+}
+
+// Apply validated options on top of the converted module.
+func (smm *SmModule) applyOptions(opts ModuleOptions) {
+	if opts.Id != "" {
+		smm.Id = opts.Id
+		smm.explicitId = true
+	}
+	if opts.Tags.Title != "" {
+		smm.Title = opts.Tags.Title
+	}
+	if opts.Tags.Author != "" {
+		smm.Author = opts.Tags.Author
+	}
+	smm.Tags = smm.Tags.Merge(opts.Tags)
+
+	echo := opts.Echo
+	if echo.Delay != nil {
+		smm.Header.EchoDelay = uint8(*echo.Delay)
+	}
+	if echo.Feedback != nil {
+		smm.Header.EchoFeedback = int8(*echo.Feedback)
+	}
+	if len(echo.Volume) > 0 {
+		smm.Header.EchoVolumeL = int8(echo.Volume[0])
+		smm.Header.EchoVolumeR = int8(echo.Volume[len(echo.Volume)-1])
+	}
+	for i, v := range echo.Fir {
+		smm.Header.EchoFir[i] = int8(v)
+	}
+	if echo.Channels != nil {
+		enabled := 0
+		for _, ch := range echo.Channels {
+			enabled |= 1 << (ch - 1)
 		}
+		smm.Header.EchoEnable = uint8(enabled)
 	}
 }
 
 func convertModule(mod *modlib.Module, filename string, sourceList []SourceIndex, sampleDirectory []uint8, sources []*Source) *SmModule {
 	var smm = new(SmModule)
 
-	// Metadata for SPC
-	//smm.Author= mod.Author
+	// Metadata for SPC. The author can be given in the song message.
 	smm.Title = mod.Title
 
-	smm.Id = pathToId("MOD_", filename)
+	smm.Filename = filename
 	smm.Header.InitialVolume = uint8(mod.GlobalVolume)
 	smm.Header.InitialTempo = uint8(mod.InitialTempo)
 	smm.Header.InitialSpeed = uint8(mod.InitialSpeed)
@@ -251,7 +386,7 @@ func convertModule(mod *modlib.Module, filename string, sourceList []SourceIndex
 	smm.BankHeader.SourceListCount = uint16(len(sourceList))
 
 	if mod.Channels > 8 {
-		smm.warn("module has too many channels")
+		smm.warn("channels", noLocation(), "module has too many channels")
 	}
 
 	for i := 0; i < 8; i++ {
@@ -306,6 +441,36 @@ const (
 	VcmdVibratoDepth   = 10
 )
 
+// Effect numbers as stored in pattern entries. These are the same as IT, where 1 = Axx.
+const (
+	EffectA = 1 + iota // Set speed
+	EffectB            // Position jump
+	EffectC            // Pattern break
+	EffectD            // Volume slide
+	EffectE            // Pitch slide down
+	EffectF            // Pitch slide up
+	EffectG            // Portamento to note
+	EffectH            // Vibrato
+	EffectI            // Tremor
+	EffectJ            // Arpeggio
+	EffectK            // Vibrato + volume slide
+	EffectL            // Portamento + volume slide
+	EffectM            // Set channel volume
+	EffectN            // Channel volume slide
+	EffectO            // Sample offset
+	EffectP            // Panning slide
+	EffectQ            // Retrigger
+	EffectR            // Tremolo
+	EffectS            // Special
+	EffectT            // Set tempo
+	EffectU            // Fine vibrato
+	EffectV            // Set global volume
+	EffectW            // Global volume slide
+	EffectX            // Set panning
+	EffectY            // Panbrello
+	EffectZ            // MIDI macro
+)
+
 func vCmdToSmByte(command uint8, param uint8) uint8 {
 	// Assuming all ranges are valid.
 
@@ -379,6 +544,11 @@ func convertPattern(pattern *common.Pattern) *SmPattern {
 		data = append(data, uint8(noteHints), uint8(updateBits))
 
 		for _, entry := range row.Entries {
+			if entry.Channel >= 8 {
+				// The driver only has 8 channels.
+				continue
+			}
+
 			mask := uint8(0)
 			channelData := []byte{}
 
@@ -463,7 +633,7 @@ func convertInstrument(instr *common.Instrument) *SmInstrument {
 	if env != nil {
 
 		// Initialize to 0x80 (disabled)
-		smi.Info.EnvelopeSustain = 0xff
+		smi.Info.EnvelopeSustain = 0x80
 		smi.Info.EnvelopeLoopStart = 0xff
 		smi.Info.EnvelopeLoopEnd = 0xff
 
@@ -522,49 +692,3 @@ func convertSample(sample *common.Sample, directoryIndex uint8, tuning float64)
 
 	return sms
 }
-
-/*
-
-	if( VERBOSE ) {
-		int pattsize = 0;
-		for i := 0; i < mod.PatternCount; i++ {
-			pattsize += Patterns[i]->GetExportSize();
-		}
-		int sampsize = 0;
-		for( u32 i = 0; i < source_list.size(); i++ {
-			sampsize += sources[source_list[i]]->GetDataLength();
-		}
-		int othersize = 0;
-		for (u32 i = 0; i < Instruments.size(); i++ {
-			othersize += Instruments[i]->GetExportSize();
-		}
-		othersize += GetExportSize_Header();
-
-		int echosize = EchoDelay * 2048;
-		int totalsize = pattsize + sampsize + othersize + echosize;
-		printf(
-			"Conversion report:\n"
-			"Length: %i\n"
-			"Patterns: %i\n"
-			"Instruments: %i\n"
-			"Samples: %i\n"
-			"Pattern data size: %i bytes\n"
-			"Sample data size: %i bytes\n"
-			"Instruments/Other data size: %i bytes\n"
-			"Echo region size: %i bytes\n"
-			"Total size: %i bytes\n"
-			,mod.Length,
-			mod.PatternCount,
-			mod.InstrumentCount,
-			mod.SampleCount,
-			pattsize,
-			sampsize,
-			othersize,
-			echosize,
-			totalsize
-			);
-		if( totalsize > kSpcRamSize ) {
-			printf( "MODULE IS TOO BIG. maximum is %i bytes\n", kSpcRamSize );
-		}
-	}
-}*/
@@ -2,6 +2,7 @@ package smconv
 
 import (
 	"encoding/binary"
+	"encoding/json"
 	"io"
 	"math"
 	"os"
@@ -9,6 +10,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"go.mukunda.com/modlib"
+	"go.mukunda.com/modlib/common"
 )
 
 func read16(file *os.File) uint16 {
@@ -31,7 +33,7 @@ func TestSoundbankExport(t *testing.T) {
 	bank.AddModule(mod, "test/pollen8.it")
 	assert.Equal(t, "MOD_POLLEN8", bank.Modules[0].Id)
 
-	bank.Export(".testdata-pollen", false)
+	bank.Export(".testdata-pollen.smbank", false)
 
 	file, err := os.Open(".testdata-pollen.smbank")
 	assert.NoError(t, err)
@@ -51,7 +53,7 @@ func TestSoundbankExport2(t *testing.T) {
 
 	mod, _ := modlib.LoadModule("test/reflection.it")
 	bank.AddModule(mod, "test/reflection.it")
-	assert.NoError(t, bank.Export(".testdata-reflection", false))
+	assert.NoError(t, bank.Export(".testdata-reflection.smbank", false))
 
 	f, _ := os.Open(".testdata-reflection.smbank")
 
@@ -315,3 +317,82 @@ func TestSoundbankExport2(t *testing.T) {
 
 	}
 }
+
+func TestSmOptionsDiagnostics(t *testing.T) {
+	smm := &SmModule{Filename: "song.it"}
+	smm.parseSmOptions(&modlib.Module{
+		Message: "My song\r\n[[SNESMOD]]\r\nedl 20\r\nefb 1 2\r\nfoo\r\n[[/SNESMOD]]",
+	})
+
+	assert.EqualValues(t, 15, smm.Header.EchoDelay)
+	assert.Equal(t, []Diagnostic{
+		{
+			Severity: SeverityWarning,
+			Code:     "smo-range",
+			Message:  "edl value out of range: 20",
+			Location: Location{File: "song.it", Pattern: -1, Row: -1, Channel: -1, MessageLine: 3},
+		},
+		{
+			Severity: SeverityWarning,
+			Code:     "smo-args",
+			Message:  "Too many params for efb command.",
+			Location: Location{File: "song.it", Pattern: -1, Row: -1, Channel: -1, MessageLine: 4},
+		},
+		{
+			Severity: SeverityWarning,
+			Code:     "smo-unknown",
+			Message:  "Unknown command: foo",
+			Location: Location{File: "song.it", Pattern: -1, Row: -1, Channel: -1, MessageLine: 5},
+		},
+	}, smm.Warnings)
+
+	assert.Equal(t, "song.it: message line 3: warning [smo-range] edl value out of range: 20",
+		smm.Warnings[0].String())
+
+	data, err := json.Marshal(smm.Warnings[0])
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"severity":"warning","code":"smo-range","message":"edl value out of range: 20","file":"song.it","messageLine":3}`, string(data))
+}
+
+func TestSmOptionsNonAscii(t *testing.T) {
+	// Lowercasing turns each invalid byte into a three-byte replacement character, so
+	// offsets into the lowercased message don't line up with the original.
+	smm := &SmModule{Filename: "song.it"}
+	smm.parseSmOptions(&modlib.Module{
+		Message: "\xb0\xb0\xb0hi\r[[SNESMOD]]\redl 9\r[[/snesmod]]\xb0\xb0",
+	})
+
+	assert.EqualValues(t, 9, smm.Header.EchoDelay)
+	assert.Empty(t, smm.Warnings)
+	assert.Equal(t, "\xb0\xb0\xb0hi\r\xb0\xb0", smm.SongMessage)
+
+	assert.Equal(t, "\nedl 9\n", getTextSection("\xb0\xb0\xb0hi\n[[SnesMod]]\nedl 9\n[[/SNESMOD]]", "snesmod"))
+	assert.Equal(t, "", getTextSection("\xb0\xb0\xb0hi", "snesmod"))
+}
+
+func TestConvertExtraChannels(t *testing.T) {
+	mod, err := modlib.LoadModule("../test/pollen8.it")
+	assert.NoError(t, err)
+	bank := SoundBank{}
+	assert.NoError(t, bank.AddModule(mod, "test/pollen8.it"))
+	expected := bank.Modules[0].Patterns
+
+	// Play notes on channels 9 and 12, which the driver doesn't have.
+	mod.Channels = 12
+	rows := mod.Patterns[0].Rows
+	for r := range rows {
+		entries := append([]common.Entry{}, rows[r].Entries...)
+		rows[r].Entries = append(entries, common.Entry{Channel: 8, Note: 61, Instrument: 1},
+			common.Entry{Channel: 11, Note: 50, Instrument: 1, Effect: EffectD, EffectParam: 0x0F})
+	}
+
+	// The extra channels are dropped, so the patterns are the same.
+	bank = SoundBank{}
+	assert.NoError(t, bank.AddModule(mod, "test/pollen8.it"))
+	assert.Equal(t, expected, bank.Modules[0].Patterns)
+	assert.Equal(t, "channels", bank.Diagnostics()[0].Code)
+
+	report := FidelityReport(mod)
+	assert.Contains(t, report, FidelityChange{"channels", "channel 9 is dropped, first used in pattern 0, row 0"})
+	assert.Contains(t, report, FidelityChange{"channels", "channel 12 is dropped, first used in pattern 0, row 0"})
+}
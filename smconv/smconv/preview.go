@@ -0,0 +1,403 @@
+// SNESMOD
+// (C) 2025 Mukunda Johnson (mukunda.com)
+// Licensed under MIT
+
+// This file writes a converted module back to an Impulse Tracker file, so the conversion
+// losses can be heard in a tracker. The samples are the decoded BRR sources, and the
+// instruments, envelopes, and patterns only have what the driver plays.
+
+package smconv
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+
+	"go.mukunda.com/errorcat"
+)
+
+const (
+	kItVersion = 0x0214
+
+	kItFlagStereo         = 0x01
+	kItFlagUseInstruments = 0x04
+	kItFlagLinearSlides   = 0x08
+	kItSpecialMessage     = 0x01
+
+	kItEnvelopeEnabled = 0x01
+	kItEnvelopeLoop    = 0x02
+	kItEnvelopeSustain = 0x04
+
+	kItSampleData     = 0x01
+	kItSample16Bit    = 0x02
+	kItSampleLoop     = 0x10
+	kItSampleSigned   = 0x01
+	kItChannelDisable = 0x80
+
+	kItMaxEnvelopeNodes = 25
+	kItMixVolume        = 48
+	kItSeparation       = 128
+)
+
+type itHeader struct {
+	Magic      [4]byte
+	SongName   [26]byte
+	Highlight  uint16
+	OrdNum     uint16
+	InsNum     uint16
+	SmpNum     uint16
+	PatNum     uint16
+	Cwt        uint16
+	Cmwt       uint16
+	Flags      uint16
+	Special    uint16
+	GlobalVol  uint8
+	MixVol     uint8
+	Speed      uint8
+	Tempo      uint8
+	Separation uint8
+	PitchWheel uint8
+	MsgLength  uint16
+	MsgOffset  uint32
+	Reserved   uint32
+	ChannelPan [64]uint8
+	ChannelVol [64]uint8
+}
+
+type itEnvelopeNode struct {
+	Y uint8
+	X uint16
+}
+
+type itEnvelope struct {
+	Flags        uint8
+	Num          uint8
+	LoopStart    uint8
+	LoopEnd      uint8
+	SustainStart uint8
+	SustainEnd   uint8
+	Nodes        [kItMaxEnvelopeNodes]itEnvelopeNode
+	Reserved     uint8
+}
+
+type itInstrument struct {
+	Magic       [4]byte
+	Filename    [12]byte
+	Zero        uint8
+	Nna         uint8
+	Dct         uint8
+	Dca         uint8
+	Fadeout     uint16
+	Pps         int8
+	Ppc         uint8
+	GlobalVol   uint8
+	DefaultPan  uint8
+	RandomVol   uint8
+	RandomPan   uint8
+	TrkVers     uint16
+	NumSamples  uint8
+	X           uint8
+	Name        [26]byte
+	Ifc         uint8
+	Ifr         uint8
+	MidiChannel uint8
+	MidiProgram uint8
+	MidiBank    uint16
+	Keyboard    [120][2]uint8
+	VolumeEnv   itEnvelope
+	PanEnv      itEnvelope
+	PitchEnv    itEnvelope
+	Dummy       [4]byte
+}
+
+type itSampleHeader struct {
+	Magic         [4]byte
+	Filename      [12]byte
+	Zero          uint8
+	GlobalVol     uint8
+	Flags         uint8
+	Volume        uint8
+	Name          [26]byte
+	Convert       uint8
+	DefaultPan    uint8
+	Length        uint32
+	LoopBegin     uint32
+	LoopEnd       uint32
+	C5Speed       uint32
+	SusLoopBegin  uint32
+	SusLoopEnd    uint32
+	SamplePointer uint32
+	VibratoSpeed  uint8
+	VibratoDepth  uint8
+	VibratoRate   uint8
+	VibratoType   uint8
+}
+
+type itPatternHeader struct {
+	Length   uint16
+	Rows     uint16
+	Reserved [4]byte
+}
+
+// Write the module at `index` in the soundbank to an IT file as the SNES plays it:
+//   - Samples are the decoded BRR sources, with loops unrolled or resampled, at the
+//     driver's pitch.
+//   - Instruments map every note to the middle note map sample, and have the truncated
+//     volume envelope and the fadeout at the driver's resolution.
+//   - Patterns have the first 8 channels, without the effects and volume commands that
+//     the driver ignores.
+func (bank *SoundBank) ExportPreviewModule(w io.Writer, index int) error {
+	if index < 0 || index >= len(bank.Modules) {
+		return fmt.Errorf("%w: module index %d is out of range", ErrModuleNotFound, index)
+	}
+	smm := bank.Modules[index]
+
+	// Every pattern has to be readable before anything is written.
+	patterns := make([][]smRow, len(smm.Patterns))
+	for i, smp := range smm.Patterns {
+		rows, err := smp.decodeRows()
+		if err != nil {
+			return fmt.Errorf("pattern %d: %w", i, err)
+		}
+		patterns[i] = rows
+	}
+
+	return errorcat.Guard(func(cat eC) error {
+		out := &SeekingByteBuffer{}
+
+		// Orders after an end marker can still be reached with Bxx.
+		orders := smm.Header.Sequence[:]
+		for len(orders) > 0 && orders[len(orders)-1] == 255 {
+			orders = orders[:len(orders)-1]
+		}
+		orders = append(append([]uint8{}, orders...), 255)
+
+		header := itHeader{
+			Highlight:  0x1004,
+			OrdNum:     uint16(len(orders)),
+			InsNum:     uint16(len(smm.Instruments)),
+			SmpNum:     uint16(len(smm.Samples)),
+			PatNum:     uint16(len(smm.Patterns)),
+			Cwt:        kItVersion,
+			Cmwt:       kItVersion,
+			Flags:      kItFlagStereo | kItFlagLinearSlides,
+			GlobalVol:  smm.Header.InitialVolume,
+			MixVol:     kItMixVolume,
+			Speed:      smm.Header.InitialSpeed,
+			Tempo:      smm.Header.InitialTempo,
+			Separation: kItSeparation,
+		}
+		copy(header.Magic[:], "IMPM")
+		copy(header.SongName[:], smm.Title)
+		if len(smm.Instruments) > 0 {
+			header.Flags |= kItFlagUseInstruments
+		}
+		for ch := range 64 {
+			if ch < 8 {
+				header.ChannelPan[ch] = smm.Header.InitialChannelPanning[ch]
+				header.ChannelVol[ch] = smm.Header.InitialChannelVolume[ch]
+			} else {
+				header.ChannelPan[ch] = 32 | kItChannelDisable
+				header.ChannelVol[ch] = 64
+			}
+		}
+
+		// The song message keeps the SNESMOD options, so the preview converts the same.
+		message := []byte{}
+		if smm.SongMessage != "" {
+			message = append([]byte(strings.ReplaceAll(smm.SongMessage, "\n", "\r")), 0)
+			header.Special |= kItSpecialMessage
+			header.MsgLength = uint16(len(message))
+		}
+
+		// The offset tables are filled in at the end.
+		bwrite(cat, out, header)
+		bwrite(cat, out, orders)
+		tablePos := out.Tell()
+		instrumentOffsets := make([]uint32, len(smm.Instruments))
+		sampleOffsets := make([]uint32, len(smm.Samples))
+		patternOffsets := make([]uint32, len(smm.Patterns))
+		bwrite(cat, out, instrumentOffsets)
+		bwrite(cat, out, sampleOffsets)
+		bwrite(cat, out, patternOffsets)
+
+		if len(message) > 0 {
+			header.MsgOffset = uint32(out.Tell())
+			bwrite(cat, out, message)
+		}
+
+		for i, smi := range smm.Instruments {
+			instrumentOffsets[i] = uint32(out.Tell())
+			bwrite(cat, out, previewInstrument(smi))
+		}
+
+		sampleHeaders := make([]itSampleHeader, len(smm.Samples))
+		sampleData := make([][]int16, len(smm.Samples))
+		for i, sms := range smm.Samples {
+			sampleHeaders[i], sampleData[i] = bank.previewSample(smm, sms)
+			sampleOffsets[i] = uint32(out.Tell())
+			bwrite(cat, out, sampleHeaders[i])
+		}
+
+		for i, rows := range patterns {
+			patternOffsets[i] = uint32(out.Tell())
+			data := previewPatternData(rows)
+			bwrite(cat, out, itPatternHeader{Length: uint16(len(data)), Rows: uint16(len(rows))})
+			bwrite(cat, out, data)
+		}
+
+		for i, pcm := range sampleData {
+			sampleHeaders[i].SamplePointer = uint32(out.Tell())
+			bwrite(cat, out, pcm)
+		}
+
+		// Go back for the pointers.
+		pseek(cat, out, 0, io.SeekStart)
+		bwrite(cat, out, header)
+		pseek(cat, out, int64(tablePos), io.SeekStart)
+		bwrite(cat, out, instrumentOffsets)
+		bwrite(cat, out, sampleOffsets)
+		bwrite(cat, out, patternOffsets)
+		for i := range sampleHeaders {
+			pseek(cat, out, int64(sampleOffsets[i]), io.SeekStart)
+			bwrite(cat, out, sampleHeaders[i])
+		}
+
+		_, err := w.Write(out.Bytes())
+		cat.Catch(err)
+		return nil
+	})
+}
+
+// Write the module at `index` to an IT file. See ExportPreviewModule.
+func (bank *SoundBank) WritePreviewModule(filename string, index int) error {
+	return errorcat.Guard(func(cat eC) error {
+		file, err := os.Create(filename)
+		cat.Catch(err)
+		defer file.Close()
+
+		cat.Catch(bank.ExportPreviewModule(file, index))
+		return nil
+	})
+}
+
+// An IT instrument with only what the driver uses.
+func previewInstrument(smi *SmInstrument) itInstrument {
+	ins := itInstrument{
+		Fadeout:     uint16(smi.Info.Fadeout) * 4,
+		GlobalVol:   smi.Info.GlobalVolume,
+		DefaultPan:  smi.Info.SetPanning,
+		TrkVers:     kItVersion,
+		MidiProgram: 0xFF,
+		MidiBank:    0xFFFF,
+	}
+	copy(ins.Magic[:], "IMPI")
+
+	// Every note plays the middle note map sample. An index of 255 wraps to 0, which is
+	// no sample.
+	sample := smi.Info.SampleIndex + 1
+	for note := range ins.Keyboard {
+		ins.Keyboard[note] = [2]uint8{uint8(note), sample}
+	}
+	if sample != 0 {
+		ins.NumSamples = 1
+	}
+
+	nodes := min(int(smi.Info.EnvelopeLength/4), len(smi.Envelope), kItMaxEnvelopeNodes)
+	if nodes > 0 {
+		env := &ins.VolumeEnv
+		env.Flags = kItEnvelopeEnabled
+		env.Num = uint8(nodes)
+
+		x := 0
+		for i := range nodes {
+			env.Nodes[i] = itEnvelopeNode{Y: smi.Envelope[i].Level, X: uint16(x)}
+			x += int(smi.Envelope[i].Duration)
+		}
+
+		if smi.Info.EnvelopeSustain != 0x80 {
+			env.Flags |= kItEnvelopeSustain
+			env.SustainStart = smi.Info.EnvelopeSustain / 4
+			env.SustainEnd = env.SustainStart
+		}
+		if smi.Info.EnvelopeLoopStart != 0xFF {
+			env.Flags |= kItEnvelopeLoop
+			env.LoopStart = smi.Info.EnvelopeLoopStart / 4
+			env.LoopEnd = smi.Info.EnvelopeLoopEnd / 4
+		}
+	}
+
+	return ins
+}
+
+// An IT sample header and the sample data for a module sample, from its decoded source.
+// The sample pointer is filled in later.
+func (bank *SoundBank) previewSample(smm *SmModule, sms *SmSample) (itSampleHeader, []int16) {
+	header := itSampleHeader{
+		GlobalVol: sms.GlobalVolume,
+		Volume:    sms.DefaultVolume,
+		Convert:   kItSampleSigned,
+		// The pan flag is flipped in the conversion, so the high bit means disabled.
+		DefaultPan: sms.SetPanning ^ 0x80,
+	}
+	copy(header.Magic[:], "IMPS")
+
+	if int(sms.DirectoryIndex) >= len(smm.SourceList) {
+		return header, nil
+	}
+	sourceIndex := int(smm.SourceList[sms.DirectoryIndex])
+	wav := bank.DecodeSource(sourceIndex).Wav
+	copy(header.Name[:], fmt.Sprintf("BRR source %d", sourceIndex))
+
+	// The pitch base includes the tuning for resampled loops.
+	header.C5Speed = uint32(math.Round(kPitchBaseRate * math.Pow(2, float64(int16(sms.PitchBase))/768)))
+	header.Length = uint32(len(wav.Data))
+	if len(wav.Data) > 0 {
+		header.Flags = kItSampleData | kItSample16Bit
+	}
+	if wav.Loop {
+		header.Flags |= kItSampleLoop
+		header.LoopBegin = uint32(wav.LoopStart)
+		header.LoopEnd = uint32(wav.LoopEnd)
+	}
+
+	return header, wav.Data
+}
+
+// Pack decoded rows into IT pattern data, dropping what the driver ignores.
+func previewPatternData(rows []smRow) []byte {
+	data := []byte{}
+	for _, row := range rows {
+		for ch, entry := range row {
+			mask := uint8(0)
+			values := []byte{}
+			if entry.Flags&1 != 0 {
+				mask |= 1
+				values = append(values, entry.Note)
+			}
+			if entry.Flags&2 != 0 {
+				mask |= 2
+				values = append(values, entry.Instrument)
+			}
+			if entry.Flags&4 != 0 && driverSupportsVcmd(entry.Vcmd) {
+				mask |= 4
+				values = append(values, entry.Vcmd)
+			}
+			if entry.Flags&8 != 0 && driverSupportsEffect(int(entry.Effect), int(entry.Param)) {
+				mask |= 8
+				values = append(values, entry.Effect, entry.Param)
+			}
+			if mask == 0 {
+				continue
+			}
+
+			// Channel numbers are 1-based, and bit 7 means a mask follows.
+			data = append(data, uint8(ch+1)|0x80, mask)
+			data = append(data, values...)
+		}
+		data = append(data, 0)
+	}
+	return data
+}
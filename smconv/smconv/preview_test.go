@@ -0,0 +1,95 @@
+// SNESMOD
+// (C) 2025 Mukunda Johnson (mukunda.com)
+// Licensed under MIT
+
+package smconv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mukunda.com/modlib"
+)
+
+func TestPreviewModule(t *testing.T) {
+	bank := buildRoundTripBank(t)
+
+	for index, mod := range bank.Modules {
+		assert.NoError(t, bank.WritePreviewModule(".testdata-preview.it", index))
+
+		// Converting the preview gives the same module, since only what the driver uses
+		// is in it.
+		loaded, err := modlib.LoadModule(".testdata-preview.it")
+		if !assert.NoError(t, err) {
+			continue
+		}
+		preview := &SoundBank{}
+		assert.NoError(t, preview.AddModule(loaded, mod.Filename))
+		converted := preview.Modules[0]
+
+		assert.Equal(t, mod.Title, converted.Title)
+		assert.Equal(t, mod.Header, converted.Header)
+		assert.Equal(t, mod.SongMessage, converted.SongMessage)
+		assert.Equal(t, mod.Patterns, converted.Patterns)
+		assert.Equal(t, mod.Instruments, converted.Instruments)
+
+		if assert.Len(t, converted.Samples, len(mod.Samples)) {
+			for i, sms := range mod.Samples {
+				assert.InDelta(t, int16(sms.PitchBase), int16(converted.Samples[i].PitchBase), 1)
+				assert.Equal(t, sms.DefaultVolume, converted.Samples[i].DefaultVolume)
+				assert.Equal(t, sms.GlobalVolume, converted.Samples[i].GlobalVolume)
+				assert.Equal(t, sms.SetPanning, converted.Samples[i].SetPanning)
+
+				// The samples are the decoded BRR, which encodes to about the same size.
+				source := bank.Sources[mod.SourceList[sms.DirectoryIndex]]
+				previewSource := preview.Sources[converted.SourceList[converted.Samples[i].DirectoryIndex]]
+				assert.InDelta(t, len(source.Data), len(previewSource.Data), kBrrBlockSize)
+			}
+		}
+	}
+
+	assert.ErrorIs(t, bank.WritePreviewModule(".testdata-preview.it", 2), ErrModuleNotFound)
+}
+
+func TestPreviewPatternData(t *testing.T) {
+	rows := []smRow{{
+		0: {Flags: 0x0F, Note: 60, Instrument: 1, Vcmd: 64, Effect: EffectD, Param: 0x0F},
+		2: {Flags: 0x0C, Vcmd: 110, Effect: EffectR, Param: 0x44},
+		3: {Flags: 0x0C, Vcmd: 150, Effect: EffectS, Param: 0x80},
+		4: {Flags: 0x08, Effect: EffectS, Param: 0x10},
+	}, {}}
+
+	assert.Equal(t, []byte{
+		0x81, 0x0F, 60, 1, 64, EffectD, 0x0F,
+		0x84, 0x0C, 150, EffectS, 0x80,
+		0,
+		0,
+	}, previewPatternData(rows))
+}
+
+func TestPreviewInstrument(t *testing.T) {
+	smi := &SmInstrument{
+		Info: SmInstrumentInfo{
+			Fadeout: 2, SampleIndex: 3, GlobalVolume: 100, SetPanning: 0x80 | 32,
+			EnvelopeLength: 12, EnvelopeSustain: 4, EnvelopeLoopStart: 0xFF, EnvelopeLoopEnd: 0xFF,
+		},
+		Envelope: []SmEnvelopeNode{{64, 10, -1}, {32, 5, 0}, {32, 0, 0}},
+	}
+
+	ins := previewInstrument(smi)
+	assert.EqualValues(t, 8, ins.Fadeout)
+	assert.Equal(t, [2]uint8{0, 4}, ins.Keyboard[0])
+	assert.Equal(t, [2]uint8{119, 4}, ins.Keyboard[119])
+	assert.EqualValues(t, 0x80|32, ins.DefaultPan)
+	assert.EqualValues(t, kItEnvelopeEnabled|kItEnvelopeSustain, ins.VolumeEnv.Flags)
+	assert.EqualValues(t, 3, ins.VolumeEnv.Num)
+	assert.EqualValues(t, 1, ins.VolumeEnv.SustainStart)
+	assert.Equal(t, itEnvelopeNode{32, 15}, ins.VolumeEnv.Nodes[2])
+
+	// No sample in the middle of the note map.
+	smi.Info.SampleIndex = 255
+	smi.Info.EnvelopeLength = 0
+	ins = previewInstrument(smi)
+	assert.Equal(t, [2]uint8{60, 0}, ins.Keyboard[60])
+	assert.Zero(t, ins.VolumeEnv.Flags)
+}
@@ -0,0 +1,94 @@
+// SNESMOD
+// (C) 2025 Mukunda Johnson (mukunda.com)
+// Licensed under MIT
+
+// This file renders SPC files to audio by running them in the emulator. For modules, the
+// SPC has the real driver, so the output is what the SNES plays.
+
+package smconv
+
+import (
+	"errors"
+	"io"
+	"time"
+
+	"go.mukunda.com/errorcat"
+	"go.mukunda.com/snesmod/smconv/spc"
+	"go.mukunda.com/snesmod/smconv/spc/emu"
+)
+
+var ErrNoPlayTime = errors.New("SPC file has no play time")
+
+// Samples rendered at a time.
+const kRenderChunkFrames = 4096
+
+// The play time of an SPC file before it fades, and the fade length. The xid6 lengths
+// are used when the file has them, since they aren't rounded to seconds.
+func SpcPlayTime(spcf *spc.SpcFile) (time.Duration, time.Duration) {
+	id666 := spcf.Id666()
+	length := time.Duration(id666.Duration) * time.Second
+	fade := time.Duration(id666.FadeOut) * time.Millisecond
+
+	if x := spcf.Xid6; x != nil {
+		ticks := x.IntroLength + x.LoopLength*uint32(x.LoopCount) + x.EndLength
+		if ticks > 0 {
+			length = spc.Xid6Duration(ticks)
+		}
+		if x.FadeLength > 0 {
+			fade = spc.Xid6Duration(x.FadeLength)
+		}
+	}
+
+	return length, fade
+}
+
+// Render an SPC file to a stereo 16-bit WAV file at 32000 Hz. It plays for `length`,
+// then fades out linearly over `fade`.
+func RenderSpc(w io.Writer, spcf *spc.SpcFile, length time.Duration, fade time.Duration) error {
+	if length <= 0 {
+		return ErrNoPlayTime
+	}
+
+	return errorcat.Guard(func(cat eC) error {
+		toFrames := func(d time.Duration) int {
+			return int(d * emu.SampleRate / time.Second)
+		}
+		fadeStart := toFrames(length)
+		fadeFrames := toFrames(fade)
+		total := fadeStart + fadeFrames
+
+		writeWavHeader(cat, w, 2, emu.SampleRate, total, nil)
+
+		e := emu.New(spcf)
+		buffer := make([]int16, kRenderChunkFrames*2)
+		for frame := 0; frame < total; {
+			frames := min(kRenderChunkFrames, total-frame)
+			chunk := buffer[:frames*2]
+			e.Render(chunk)
+
+			for i := range frames {
+				if pos := frame + i - fadeStart; pos >= 0 {
+					gain := fadeFrames - pos
+					chunk[i*2] = int16(int(chunk[i*2]) * gain / fadeFrames)
+					chunk[i*2+1] = int16(int(chunk[i*2+1]) * gain / fadeFrames)
+				}
+			}
+
+			bwrite(cat, w, chunk)
+			frame += frames
+		}
+
+		return nil
+	})
+}
+
+// Render a module in the soundbank to a WAV file, through an SPC file made with `opts`.
+// The play time comes from the SPC's tags: the song's intro and one loop by default.
+func (bank *SoundBank) RenderModule(w io.Writer, opts SpcOptions) error {
+	spcf, err := bank.BuildSpcFile(opts)
+	if err != nil {
+		return err
+	}
+	length, fade := SpcPlayTime(spcf)
+	return RenderSpc(w, spcf, length, fade)
+}
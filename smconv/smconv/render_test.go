@@ -0,0 +1,82 @@
+// SNESMOD
+// (C) 2025 Mukunda Johnson (mukunda.com)
+// Licensed under MIT
+
+package smconv
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mukunda.com/snesmod/smconv/spc"
+)
+
+func TestWriteWav(t *testing.T) {
+	out := &bytes.Buffer{}
+	assert.NoError(t, WriteWav(out, []int16{100, -100, 2000, -2000}, 2, 32000))
+	assert.Equal(t, kWavHeaderSize+8, out.Len())
+
+	wav, err := ReadWav(bytes.NewReader(out.Bytes()))
+	assert.NoError(t, err)
+	assert.Equal(t, 32000, wav.Rate)
+	assert.Equal(t, []int16{0, 0}, wav.Data)
+}
+
+func TestRenderPlayTime(t *testing.T) {
+	spcf := spc.NewSpcFile()
+	spcf.SetId666(spc.Id666{Duration: 90, FadeOut: 5000})
+	length, fade := SpcPlayTime(spcf)
+	assert.Equal(t, 90*time.Second, length)
+	assert.Equal(t, 5*time.Second, fade)
+
+	spcf.Xid6 = &spc.Xid6Tags{IntroLength: 64000 * 3 / 2, LoopLength: 64000, LoopCount: 2}
+	length, fade = SpcPlayTime(spcf)
+	assert.Equal(t, 3500*time.Millisecond, length)
+	assert.Equal(t, 5*time.Second, fade)
+}
+
+func TestRenderModule(t *testing.T) {
+	bank := buildRoundTripBank(t)
+	tags := ModuleTags{Length: new(int), Fade: new(int)}
+	*tags.Length = 2
+	*tags.Fade = 500
+
+	out := &bytes.Buffer{}
+	assert.NoError(t, bank.RenderModule(out, SpcOptions{Tags: tags}))
+
+	var header struct {
+		Riff     [4]byte
+		Size     uint32
+		Wave     [8]byte
+		FmtSize  uint32
+		Format   uint16
+		Channels uint16
+		Rate     uint32
+	}
+	assert.NoError(t, binary.Read(bytes.NewReader(out.Bytes()), binary.LittleEndian, &header))
+	assert.Equal(t, "RIFF", string(header.Riff[:]))
+	assert.EqualValues(t, 2, header.Channels)
+	assert.EqualValues(t, 32000, header.Rate)
+
+	frames := (out.Len() - kWavHeaderSize) / 4
+	assert.Equal(t, 2500*32, frames)
+
+	// The driver starts the song, and the fade ends in silence.
+	pcm := make([]int16, frames*2)
+	assert.NoError(t, binary.Read(bytes.NewReader(out.Bytes()[kWavHeaderSize:]), binary.LittleEndian, pcm))
+	peak := 0
+	for _, s := range pcm[:32000*2] {
+		peak = max(peak, int(s), -int(s))
+	}
+	assert.Greater(t, peak, 0)
+	assert.Zero(t, pcm[len(pcm)-2])
+}
+
+func TestRenderSpcNoPlayTime(t *testing.T) {
+	spcf := spc.NewSpcFile()
+	err := RenderSpc(&bytes.Buffer{}, spcf, 0, time.Second)
+	assert.ErrorIs(t, err, ErrNoPlayTime)
+}
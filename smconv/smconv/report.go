@@ -0,0 +1,126 @@
+// SNESMOD
+// (C) 2025 Mukunda Johnson (mukunda.com)
+// Licensed under MIT
+
+// This file describes the conversion report, which measures how much SPC memory a
+// module uses once it's loaded with its sources.
+
+package smconv
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Size of the echo buffer for each unit of echo delay (EDL).
+const kEchoBytesPerDelay = 2048
+
+type ModuleReport struct {
+	Length      int // Number of entries in the sequence
+	Patterns    int
+	Instruments int
+	Samples     int
+
+	PatternBytes int
+	SampleBytes  int // BRR data for all sources loaded with the module
+	OtherBytes   int // Header, instruments, and sample info
+	EchoBytes    int
+
+	// Play time from the start of the sequence, or nil if it couldn't be measured.
+	PlayTime *SongLength
+}
+
+// Total SPC memory used by the module.
+func (r ModuleReport) Total() int {
+	return r.PatternBytes + r.SampleBytes + r.OtherBytes + r.EchoBytes
+}
+
+// How many bytes the module is over the SPC memory limit, or 0 if it fits.
+func (r ModuleReport) Overage() int {
+	return max(r.Total()-kSpcRamSize, 0)
+}
+
+func (r ModuleReport) String() string {
+	text := fmt.Sprintf(`Conversion report:
+Length: %d
+Patterns: %d
+Instruments: %d
+Samples: %d
+Pattern data size: %d bytes
+Sample data size: %d bytes
+Instruments/Other data size: %d bytes
+Echo region size: %d bytes
+Total size: %d bytes`,
+		r.Length,
+		r.Patterns,
+		r.Instruments,
+		r.Samples,
+		r.PatternBytes,
+		r.SampleBytes,
+		r.OtherBytes,
+		r.EchoBytes,
+		r.Total())
+
+	if r.PlayTime != nil {
+		text += fmt.Sprintf("\nPlay time: %s (intro %s, loop %s from position %d, row %d)",
+			formatPlayTime(r.PlayTime.Total()), formatPlayTime(r.PlayTime.Intro),
+			formatPlayTime(r.PlayTime.Loop), r.PlayTime.LoopPosition, r.PlayTime.LoopRow)
+	}
+	if over := r.Overage(); over > 0 {
+		text += fmt.Sprintf("\nMODULE IS TOO BIG by %d bytes. Maximum is %d bytes.", over, kSpcRamSize)
+	}
+
+	return text
+}
+
+// Measures a module in the bank. The module's sources are looked up in the bank.
+func (bank *SoundBank) Report(mod *SmModule) ModuleReport {
+	r := ModuleReport{
+		Patterns:    len(mod.Patterns),
+		Instruments: len(mod.Instruments),
+		Samples:     len(mod.Samples),
+		EchoBytes:   int(mod.Header.EchoDelay) * kEchoBytesPerDelay,
+	}
+
+	for _, entry := range mod.Header.Sequence {
+		if entry == 255 {
+			break
+		}
+		r.Length++
+	}
+
+	for _, p := range mod.Patterns {
+		r.PatternBytes += p.ExportSize()
+	}
+
+	for _, index := range mod.SourceList {
+		r.SampleBytes += len(bank.Sources[index].Data)
+	}
+
+	r.OtherBytes = binary.Size(mod.Header) + binary.Size(SmModuleHeaderPointers{})
+	for _, i := range mod.Instruments {
+		r.OtherBytes += i.ExportSize()
+	}
+	r.OtherBytes += len(mod.Samples) * binary.Size(SmSample{})
+
+	if length, err := mod.MeasureLength(0); err == nil {
+		r.PlayTime = &length
+	}
+
+	return r
+}
+
+// Size of the pattern when exported.
+func (smp *SmPattern) ExportSize() int {
+	return 1 + len(smp.Data)
+}
+
+// Size of the instrument when exported. The envelope parameters are only present when
+// there is an envelope.
+func (smi *SmInstrument) ExportSize() int {
+	size := 5
+	if smi.Info.EnvelopeLength > 0 {
+		size += 3 + len(smi.Envelope)*binary.Size(SmEnvelopeNode{})
+	}
+	return size
+}
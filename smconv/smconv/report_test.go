@@ -0,0 +1,49 @@
+// SNESMOD
+// (C) 2025 Mukunda Johnson (mukunda.com)
+// Licensed under MIT
+
+package smconv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mukunda.com/modlib"
+)
+
+func TestModuleReport(t *testing.T) {
+	bank := SoundBank{}
+
+	mod, _ := modlib.LoadModule("test/reflection.it")
+	assert.NoError(t, bank.AddModule(mod, "test/reflection.it"))
+
+	report := bank.Report(bank.Modules[0])
+	assert.Equal(t, 1, report.Patterns)
+	assert.Equal(t, len(mod.Instruments), report.Instruments)
+	assert.Equal(t, 1, report.Samples)
+
+	// One source with 64 samples, compressed to 9 bytes per 16 samples.
+	assert.Equal(t, 64/16*9, report.SampleBytes)
+	assert.Equal(t, 0, report.EchoBytes)
+	assert.Zero(t, report.Overage())
+
+	// The report should line up with the exported module data.
+	buf := &SeekingByteBuffer{}
+	assert.NoError(t, bank.Modules[0].Export(buf, false))
+	assert.Equal(t, (len(buf.Bytes())+1)&^1, (report.PatternBytes+report.OtherBytes+1)&^1)
+
+	// Echo is 2K per unit of delay.
+	bank.Modules[0].Header.EchoDelay = 5
+	assert.Equal(t, 5*2048, bank.Report(bank.Modules[0]).EchoBytes)
+}
+
+func TestModuleReportOverage(t *testing.T) {
+	bank := SoundBank{
+		Sources: []*Source{{Data: make([]byte, kSpcRamSize)}},
+	}
+	mod := &SmModule{SourceList: []uint16{0}}
+
+	report := bank.Report(mod)
+	assert.Equal(t, report.Total()-kSpcRamSize, report.Overage())
+	assert.Contains(t, report.String(), "MODULE IS TOO BIG")
+}
@@ -0,0 +1,89 @@
+// SNESMOD
+// (C) 2025 Mukunda Johnson (mukunda.com)
+// Licensed under MIT
+
+package smconv
+
+import (
+	"crypto/sha256"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var updateGolden = flag.Bool("update", false, "Update the golden output hashes")
+
+const kGoldenHashFile = "test/golden-hashes.txt"
+
+// Build the test soundbank and SPC in a new directory, and return the SHA-256 of each
+// output.
+func buildReproducibleOutputs(t *testing.T) map[string]string {
+	base := filepath.Join(t.TempDir(), "soundbank")
+	bank := buildRoundTripBank(t)
+	assert.NoError(t, bank.Export(base+".smbank", false))
+	assert.NoError(t, bank.ExportAssembly(base+".asm", base+".smbank"))
+	assert.NoError(t, bank.ExportAssemblyInclude(base+".inc"))
+	assert.NoError(t, bank.WriteSpcFileWithOptions(base+".spc", SpcOptions{Module: 1}))
+
+	hashes := map[string]string{}
+	for _, ext := range []string{".smbank", ".asm", ".inc", ".spc"} {
+		data, err := os.ReadFile(base + ext)
+		assert.NoError(t, err)
+		hashes[ext] = fmt.Sprintf("%x", sha256.Sum256(data))
+	}
+	return hashes
+}
+
+func TestReproducibleBuild(t *testing.T) {
+	t.Setenv("SOURCE_DATE_EPOCH", "1741910400") // 2025-03-14
+
+	first := buildReproducibleOutputs(t)
+	time.Sleep(10 * time.Millisecond)
+	second := buildReproducibleOutputs(t)
+	assert.Equal(t, first, second)
+
+	// The golden hashes are made with `go test -run TestReproducibleBuild -update`, and
+	// catch changes in the output across versions and machines.
+	if *updateGolden {
+		text := ""
+		for _, ext := range []string{".smbank", ".asm", ".inc", ".spc"} {
+			text += fmt.Sprintf("%s  %s\n", first[ext], ext)
+		}
+		assert.NoError(t, os.WriteFile(kGoldenHashFile, []byte(text), 0644))
+	}
+
+	golden, err := os.ReadFile(kGoldenHashFile)
+	if os.IsNotExist(err) {
+		t.Fatalf("%s is missing; run with -update to create it", kGoldenHashFile)
+	}
+	assert.NoError(t, err)
+
+	for _, line := range strings.Split(strings.TrimSpace(string(golden)), "\n") {
+		fields := strings.Fields(line)
+		if assert.Len(t, fields, 2) {
+			assert.Equal(t, fields[0], first[fields[1]], fields[1])
+		}
+	}
+}
+
+func TestBuildDate(t *testing.T) {
+	t.Setenv("SOURCE_DATE_EPOCH", "1741910400")
+	date, err := BuildDate()
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 3, 14, 0, 0, 0, 0, time.UTC), date)
+
+	t.Setenv("SOURCE_DATE_EPOCH", "yesterday")
+	_, err = BuildDate()
+	assert.ErrorIs(t, err, ErrInvalidSourceDateEpoch)
+
+	t.Setenv("SOURCE_DATE_EPOCH", "")
+	date, err = BuildDate()
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), date, time.Minute)
+}
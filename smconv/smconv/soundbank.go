@@ -7,9 +7,13 @@
 package smconv
 
 import (
-	"regexp"
+	"errors"
+	"fmt"
+	"math"
+	"path/filepath"
 	"strings"
 
+	"go.mukunda.com/modlib"
 	"go.mukunda.com/modlib/common"
 )
 
@@ -23,13 +27,49 @@ const (
 
 type SourceIndex = uint16
 
+var ErrEmptySoundEffect = errors.New("sound effect has no sample data")
+var ErrInvalidEffectOptions = errors.New("invalid sound effect options")
+
+// Options for adding a sound effect file to the bank.
+type EffectOptions struct {
+	// Symbol for the effect in the definitions file. Defaults to SFX_ and the file name,
+	// or the sample name for modules.
+	Id string `yaml:"id" json:"id" toml:"id"`
+
+	// Rate to resample WAV files to, or 0 to keep their own rate.
+	Rate int `yaml:"rate" json:"rate" toml:"rate"`
+
+	// Only add this sample (1-based) from a module. 0 adds every sample.
+	Sample int `yaml:"sample" json:"sample" toml:"sample"`
+}
+
 type SoundBank struct {
 	HiRom   bool
 	Sources []*Source
 	Modules []*SmModule
+
+	// Prefixes for IDs that are made from file and sample names. Empty uses the default
+	// MOD_ and SFX_.
+	ModulePrefix string
+	EffectPrefix string
+
+	// Size of the sound region that the game allocates with spcAllocateSoundRegion, in
+	// 256-byte pages. Modules are checked against the memory that's left.
+	SoundRegion int
+
+	// Warnings from adding sound effects.
+	warnings []Diagnostic
 }
 
 func (bank *SoundBank) AddModule(mod *common.Module, filename string) error {
+	return bank.AddModuleWithOptions(mod, filename, ModuleOptions{})
+}
+
+// Same as AddModule, with options that override the module's own settings.
+func (bank *SoundBank) AddModuleWithOptions(mod *common.Module, filename string, opts ModuleOptions) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
 
 	// usedSources indexes into the bank.Sources.
 	// The sampleSourceMap indexes into usedSources, mapping samples -> used sources.
@@ -63,44 +103,236 @@ func (bank *SoundBank) AddModule(mod *common.Module, filename string) error {
 	}
 
 	smMod := convertModule(mod, filename, usedSources, sampleSourceMap, bank.Sources)
+	smMod.Id = pathToId(bank.modulePrefix(), filename)
+	smMod.applyOptions(opts)
+
+	report := bank.Report(smMod)
+	if over := report.Overage(); over > 0 {
+		return fmt.Errorf("%w: needs %d bytes, %d bytes over the %d byte limit",
+			ErrModuleTooBig, report.Total(), over, kSpcRamSize)
+	}
+
+	layout, err := bank.SpcLayout(smMod, bank.SoundRegion)
+	if err != nil {
+		return err
+	}
+	if err := layout.Validate(); err != nil {
+		return fmt.Errorf("%w: %w", ErrModuleTooBig, err)
+	}
+
 	bank.Modules = append(bank.Modules, smMod)
 	return nil
 }
 
-// Adds a source and returns the index of it. If a duplicate source exists, then the
-// existing index is returned instead and nothing is added.
-func (bank *SoundBank) AddSource(s *Source) SourceIndex {
+// Adds a sound effect and returns its source index. Sound effects are always one-shot in
+// the driver, so any loop in the sample is dropped. `id` is the symbol used for the
+// effect in the definitions file.
+func (bank *SoundBank) AddSoundEffect(sample common.Sample, id string) (SourceIndex, error) {
+	sample.Loop = false
+	sample.PingPong = false
+
+	s, err := createSource(sample)
+	if err != nil {
+		return 0, err
+	}
+	return bank.addSoundEffectSource(s, id)
+}
+
+// Adds a WAV file as a sound effect and returns its source index. If `rate` isn't 0, the
+// sample is resampled to that rate first, which is a simple way to save SPC memory. The
+// unity note from the smpl chunk is tuned to C-5 at the rate, and the loop is aligned to
+// the BRR block size like module samples. Effects have no pitch base to correct a
+// resampled loop, so a warning gives the tuning error instead.
+func (bank *SoundBank) AddWavSoundEffect(wav *WavSample, id string, rate int) (SourceIndex, error) {
+	if rate == 0 {
+		rate = wav.Rate
+	}
+	wav = wav.Retune(rate)
+
+	loopLength := 0
+	if wav.Loop {
+		loopLength = wav.LoopEnd - wav.LoopStart
+	}
+
+	s, err := encodeSource(wav.Data, wav.LoopStart, loopLength, wav.PingPong)
+	if err != nil {
+		return 0, err
+	}
+
+	if s.TuningFactor != 1 {
+		bank.warn("effect-tuning", noLocation(), fmt.Sprintf(
+			"%s: the loop is resampled to fit the BRR block size, which detunes the effect by %+.1f cents; use a loop length that's a multiple of 16",
+			id, 1200*math.Log2(s.TuningFactor)))
+	}
+
+	return bank.addSoundEffectSource(s, id)
+}
+
+// Adds a pre-encoded BRR source as a sound effect and returns its source index. The data
+// is used as is, except that the loop flag is cleared to make the effect one-shot.
+func (bank *SoundBank) AddBrrSoundEffect(source *Source, id string) (SourceIndex, error) {
+	if source.Loops() {
+		s := *source
+		s.Data = append([]byte(nil), source.Data...)
+		s.Data[len(s.Data)-kBrrBlockSize] &^= kBrrLoopFlag
+		s.Loop = 0
+		s.updateHash()
+		source = &s
+	}
+
+	return bank.addSoundEffectSource(source, id)
+}
+
+func (bank *SoundBank) addSoundEffectSource(s *Source, id string) (SourceIndex, error) {
+	if len(s.Data) == 0 {
+		return 0, ErrEmptySoundEffect
+	}
+	s.Id = id
+
+	// Identical data can be shared with a module sample, but every effect keeps its own
+	// index so its symbol stays unique.
 	for i, es := range bank.Sources {
-		if es.Hash == s.Hash {
-			return SourceIndex(i)
+		if es.Hash == s.Hash && (es.Id == "" || es.Id == id) {
+			es.Id = id
+			return SourceIndex(i), nil
 		}
 	}
 
 	bank.Sources = append(bank.Sources, s)
-	return SourceIndex(len(bank.Sources) - 1)
+	return SourceIndex(len(bank.Sources) - 1), nil
 }
 
-// Convert a file path into an ID for inserting into definition files.
-// This strips the directory and extension, converts to uppercase, and replaces non
-// alphanumeric with "_".
-func pathToId(prefix string, path string) string {
-	path = strings.ToUpper(path)
-	path = strings.ReplaceAll(path, "\\", "/")
+// Adds every sample in a module as a sound effect. Effects are named after the sample,
+// or after the file and sample number when the sample has no name. Empty sample slots
+// are skipped.
+func (bank *SoundBank) AddSoundEffects(mod *common.Module, filename string) error {
+	for i, sample := range mod.Samples {
+		_, err := bank.AddSoundEffect(sample, bank.sampleEffectId(sample, i, filename))
+		if errors.Is(err, ErrEmptySoundEffect) {
+			continue
+		} else if err != nil {
+			return fmt.Errorf("sample %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
 
-	slash := strings.LastIndex(path, "/")
-	if slash != -1 {
-		path = path[slash+1:]
+func (bank *SoundBank) sampleEffectId(sample common.Sample, index int, filename string) string {
+	name := strings.TrimSpace(sample.Name)
+	if name == "" {
+		name = fmt.Sprintf("%s_%d", pathToId("", filename), index+1)
 	}
+	return pathToId(bank.effectPrefix(), name)
+}
 
-	dot := strings.Index(path, ".")
-	if dot != -1 {
-		path = path[:dot]
+// Adds a sound effect file to the bank. A WAV or BRR file is a single effect, and
+// anything else is loaded as a module with one effect per sample, unless a single sample
+// is chosen in the options.
+func (bank *SoundBank) AddSoundEffectFile(filename string, opts EffectOptions) error {
+	if opts.Rate < 0 {
+		return fmt.Errorf("%w: invalid rate %d", ErrInvalidEffectOptions, opts.Rate)
 	}
 
-	path = prefix + path
+	id := opts.Id
+	if id == "" {
+		id = pathToId(bank.effectPrefix(), filename)
+	}
+
+	var index SourceIndex
+
+	// Warnings from the effect point to the file.
+	firstWarning := len(bank.warnings)
+	defer func() {
+		for i := firstWarning; i < len(bank.warnings); i++ {
+			bank.warnings[i].Location.File = filename
+		}
+	}()
+
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".wav":
+		wav, err := LoadWav(filename)
+		if err != nil {
+			return err
+		}
+		if index, err = bank.AddWavSoundEffect(wav, id, opts.Rate); err != nil {
+			return err
+		}
+	case ".brr":
+		source, err := LoadBrr(filename)
+		if err != nil {
+			return err
+		}
+		if index, err = bank.AddBrrSoundEffect(source, id); err != nil {
+			return err
+		}
+	default:
+		var err error
+		if index, err = bank.addModuleSoundEffects(filename, opts); err != nil || opts.Sample == 0 {
+			return err
+		}
+	}
 
-	stripper := regexp.MustCompile(`[^a-zA-Z0-9_]`)
-	path = stripper.ReplaceAllString(path, "_")
+	if opts.Id != "" {
+		bank.Sources[index].explicitId = true
+	}
+	return nil
+}
 
-	return path
+// Adds the effects from a module file. The index is only returned when a single sample
+// is chosen.
+func (bank *SoundBank) addModuleSoundEffects(filename string, opts EffectOptions) (SourceIndex, error) {
+	mod, err := modlib.LoadModule(filename)
+	if err != nil {
+		return 0, err
+	}
+
+	if opts.Sample == 0 {
+		if opts.Id != "" {
+			return 0, fmt.Errorf("%w: an id needs a sample to be chosen from a module", ErrInvalidEffectOptions)
+		}
+		return 0, bank.AddSoundEffects(mod, filename)
+	}
+
+	if opts.Sample < 0 || opts.Sample > len(mod.Samples) {
+		return 0, fmt.Errorf("%w: sample %d does not exist", ErrInvalidEffectOptions, opts.Sample)
+	}
+
+	sample := mod.Samples[opts.Sample-1]
+	id := opts.Id
+	if id == "" {
+		id = bank.sampleEffectId(sample, opts.Sample-1, filename)
+	}
+	return bank.AddSoundEffect(sample, id)
+}
+
+func (bank *SoundBank) warn(code string, loc Location, msg string) {
+	bank.warnings = append(bank.warnings, Diagnostic{
+		Severity: SeverityWarning,
+		Code:     code,
+		Message:  msg,
+		Location: loc,
+	})
+}
+
+// Returns the diagnostics gathered while converting the sound effects and modules in the
+// bank.
+func (bank *SoundBank) Diagnostics() []Diagnostic {
+	diags := append([]Diagnostic{}, bank.warnings...)
+	for _, mod := range bank.Modules {
+		diags = append(diags, mod.Warnings...)
+	}
+	return diags
+}
+
+// Adds a source and returns the index of it. If a duplicate source exists, then the
+// existing index is returned instead and nothing is added.
+func (bank *SoundBank) AddSource(s *Source) SourceIndex {
+	for i, es := range bank.Sources {
+		if es.Hash == s.Hash {
+			return SourceIndex(i)
+		}
+	}
+
+	bank.Sources = append(bank.Sources, s)
+	return SourceIndex(len(bank.Sources) - 1)
 }
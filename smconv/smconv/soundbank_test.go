@@ -0,0 +1,59 @@
+// SNESMOD
+// (C) 2025 Mukunda Johnson (mukunda.com)
+// Licensed under MIT
+
+package smconv
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mukunda.com/modlib"
+)
+
+func TestAddSoundEffects(t *testing.T) {
+	bank := SoundBank{}
+
+	mod, _ := modlib.LoadModule("test/reflection.it")
+	assert.NoError(t, bank.AddModule(mod, "test/reflection.it"))
+	assert.Len(t, bank.Sources, 1)
+	assert.Empty(t, bank.Sources[0].Id)
+
+	// Effects are one-shot, so the looping sample is encoded again without the loop and
+	// doesn't share the module's source.
+	index, err := bank.AddSoundEffect(mod.Samples[0], "SFX_TEST")
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, index)
+	assert.Equal(t, "SFX_TEST", bank.Sources[index].Id)
+
+	// The same effect under another name gets its own index.
+	index2, err := bank.AddSoundEffect(mod.Samples[0], "SFX_TEST2")
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, index2)
+
+	// Adding it again with the same name shares the index.
+	index3, err := bank.AddSoundEffect(mod.Samples[0], "SFX_TEST")
+	assert.NoError(t, err)
+	assert.Equal(t, index, index3)
+
+	assert.NoError(t, bank.ExportAssemblyInclude(".testdata-sfx.inc"))
+	inc, err := os.ReadFile(".testdata-sfx.inc")
+	assert.NoError(t, err)
+	assert.Contains(t, string(inc), "SFX_TEST                         = 1\n")
+	assert.Contains(t, string(inc), "SFX_TEST2                        = 2\n")
+}
+
+func TestAddSoundEffectsFromModule(t *testing.T) {
+	bank := SoundBank{}
+
+	mod, _ := modlib.LoadModule("../test/pollen8.it")
+	assert.NoError(t, bank.AddSoundEffects(mod, "sfx/pollen8.it"))
+	assert.Empty(t, bank.Modules)
+	assert.NotEmpty(t, bank.Sources)
+
+	for _, source := range bank.Sources {
+		assert.True(t, strings.HasPrefix(source.Id, "SFX_"))
+	}
+}
@@ -32,15 +32,14 @@ type Source struct {
 
 	TuningFactor float64
 	Id           string
+
+	// The ID was given by the user rather than made from a name.
+	explicitId bool
 }
 
 var ErrUnsupportedSampleProperties = errors.New("unsupported sample properties")
 
 func createSource(modsamp common.Sample) (*Source, error) {
-	source := &Source{
-		TuningFactor: 1.0,
-	}
-
 	var sampleData []int16
 
 	if modsamp.Data.Bits == 16 {
@@ -53,7 +52,22 @@ func createSource(modsamp common.Sample) (*Source, error) {
 			sampleData[i] = int16((int(i8data[i]) * 32767) / 128)
 		}
 	} else {
-		return source, ErrUnsupportedSampleProperties
+		return &Source{TuningFactor: 1.0}, ErrUnsupportedSampleProperties
+	}
+
+	loopLength := int(modsamp.LoopEnd - modsamp.LoopStart)
+	if !modsamp.Loop {
+		loopLength = 0
+	}
+
+	return encodeSource(sampleData, int(modsamp.LoopStart), loopLength, modsamp.PingPong)
+}
+
+// Encode 16-bit PCM into a BRR source. A loopLength of 0 means that the sample doesn't
+// loop. The loop is aligned to the BRR block size by unrolling or resampling.
+func encodeSource(sampleData []int16, loopStart int, loopLength int, pingPong bool) (*Source, error) {
+	source := &Source{
+		TuningFactor: 1.0,
 	}
 
 	length := len(sampleData)
@@ -62,43 +76,27 @@ func createSource(modsamp common.Sample) (*Source, error) {
 		return source, nil
 	}
 
-	loopStart := modsamp.LoopStart
-	loopLength := modsamp.LoopEnd - modsamp.LoopStart
-	if !modsamp.Loop {
-		loopLength = 0
-	}
-
 	if loopLength > 0 {
-		// Discard data after loop end.
+		// Discard data after loop end. The capacity is cut too so that unrolling below
+		// doesn't write into the caller's data.
 		length = min(length, loopStart+loopLength)
+		sampleData = sampleData[:length:length]
 	}
 
-	if modsamp.PingPong {
+	if pingPong && loopLength > 0 {
 		// Unroll BIDI loop.
 		for i := loopStart + loopLength - 1; i >= loopStart; i-- {
 			sampleData = append(sampleData, sampleData[i])
 		}
 		loopLength *= 2
+		length = len(sampleData)
 	}
 
 	tuningFactor := 1.0
 
-	if loopLength != 0 {
-		if loopLength&0xF != 0 {
-
-			unrollsNeeded := 0
-			for ll := loopLength; ll&15 != 0; ll += loopLength {
-				unrollsNeeded++
-			}
-
-			if loopLength*(1+unrollsNeeded) < kMaxUnrollThreshold {
-				// Unroll the loop to align.
-				// BrrCodec will handle this.
-			} else {
-				tuningFactor, sampleData, _, loopStart = resampleLoop(sampleData, loopStart, length, 16-(loopLength&15))
-			}
-
-		}
+	// Unrolling the loop to align it is handled by BrrCodec.
+	if _, resample := loopAlignment(loopLength); resample > 0 {
+		tuningFactor, sampleData, _, loopStart = resampleLoop(sampleData, loopStart, length, resample)
 	}
 
 	// Padding is handled by BRR Codec
@@ -113,11 +111,34 @@ func createSource(modsamp common.Sample) (*Source, error) {
 	source.Loop = loopStart / 16 * 9
 	source.Data = codec.BrrData
 	source.TuningFactor = tuningFactor
+	source.updateHash()
+
+	return source, nil
+}
+
+// How a loop of `loopLength` samples is aligned to the BRR block size. The loop is
+// either repeated `unrolls` more times, or resampled to add `resample` samples when
+// unrolling would make it longer than kMaxUnrollThreshold. Both are 0 for a loop that's
+// already aligned or a sample that doesn't loop.
+func loopAlignment(loopLength int) (unrolls int, resample int) {
+	if loopLength == 0 || loopLength&0xF == 0 {
+		return 0, 0
+	}
 
+	for ll := loopLength; ll&15 != 0; ll += loopLength {
+		unrolls++
+	}
+
+	if loopLength*(1+unrolls) < kMaxUnrollThreshold {
+		return unrolls, 0
+	}
+	return 0, 16 - (loopLength & 15)
+}
+
+// Hash the BRR data. Sources with the same hash are shared in the soundbank.
+func (source *Source) updateHash() {
 	hash := sha256.Sum256(source.Data)
 	source.Hash = fmt.Sprintf("%x", hash[:])
-
-	return source, nil
 }
 
 // Add `amount` samples to the loop region and return the new size and loop start.
@@ -153,7 +174,7 @@ func resampleLoop(data []int16, loopStart int, length int, amount int) (tuning f
 		s2 := data[index2]
 		delta := s2 - s1
 
-		resamp := float64(s1) + float64(delta)*(index-float64(index1))
+		resamp := addProduct(float64(s1), float64(delta), index-float64(index1))
 		if resamp < -32768 {
 			resamp = -32768
 		} else if resamp > 32767 {
@@ -165,3 +186,10 @@ func resampleLoop(data []int16, loopStart int, length int, amount int) (tuning f
 
 	return iResampleFactor, resampledData, newLength, newLoopStart
 }
+
+// Returns a + b*c. The explicit conversion keeps the compiler from fusing the multiply
+// and add, which rounds differently on some architectures, so that resampling gives the
+// same output on every machine.
+func addProduct(a, b, c float64) float64 {
+	return a + float64(b*c)
+}
@@ -0,0 +1,44 @@
+// SNESMOD
+// (C) 2025 Mukunda Johnson (mukunda.com)
+// Licensed under MIT
+
+package smconv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mukunda.com/modlib/common"
+)
+
+func TestCreateSourceDataAfterLoop(t *testing.T) {
+	// A 32-sample loop followed by 1000 samples that are never heard.
+	data := make([]int16, 1032)
+	for i := range data {
+		if i < 32 {
+			data[i] = int16(i * 500)
+		} else {
+			data[i] = 30000
+		}
+	}
+
+	for _, pingPong := range []bool{false, true} {
+		source, err := createSource(common.Sample{
+			Loop:      true,
+			PingPong:  pingPong,
+			LoopStart: 0,
+			LoopEnd:   32,
+			Data:      common.SampleData{Bits: 16, Channels: 1, Data: []any{data}},
+		})
+		assert.NoError(t, err)
+
+		// Only the loop is encoded in 9-byte BRR blocks: 32 samples, or 64 with the
+		// ping-pong loop unrolled, and a little padding. The ping-pong loop is unrolled
+		// from the loop end, not from the end of the data.
+		assert.LessOrEqual(t, len(source.Data), 9*(64/16+2), "ping-pong %v", pingPong)
+	}
+
+	// The sample data is unchanged.
+	assert.Len(t, data, 1032)
+	assert.EqualValues(t, 30000, data[32])
+}
@@ -6,6 +6,7 @@ package smconv
 
 import (
 	"errors"
+	"fmt"
 	"os"
 	"time"
 
@@ -21,6 +22,22 @@ var spcDriverBinary []byte
 const kSpcPatchStart = 0x3C
 
 var ErrModuleTooBig = errors.New("total module data is too big to fit in SPC memory")
+var ErrInvalidSpcOptions = errors.New("invalid SPC options")
+
+// Options for exporting a module from the bank to an SPC file.
+type SpcOptions struct {
+	// Index of the module in the bank.
+	Module int
+
+	// Sequence position to start playing from.
+	Position int
+
+	// Date for the ID666 tags when there is no date tag. The zero value uses BuildDate.
+	Date time.Time
+
+	// Tags that override the module's tags.
+	Tags ModuleTags
+}
 
 // Returns true if the SPC driver patch signature could be verified.
 func verifySpcPatchSignature() bool {
@@ -57,42 +74,139 @@ func verifySpcPatchSignature() bool {
 	return true
 }
 
-// Export the soundbank to an SPC file. The soundbank must have only one module in it.
+// Offset of the start position operand in the SPC patch region (mov a, #0).
+const kSpcPatchPosition = kSpcPatchStart + 6
+
+// Limit of the text ID666 duration field: 3 digits of seconds.
+const kSpcMaxDuration = 999
+
+// Longest fade that is measured from a song. Longer loops fade over their first 10
+// seconds.
+const kSpcMaxMeasuredFade = 10000
+
+// ID666 play time for a song: the seconds to play before fading (the intro and one pass
+// through the loop), and the fade length in milliseconds, which fades over the start of
+// the next loop.
+func spcPlayTime(length SongLength) (int, int) {
+	duration := int((length.Total() + time.Second - 1) / time.Second)
+	fadeOut := int(length.Loop / time.Millisecond)
+	return min(max(duration, 1), kSpcMaxDuration), min(fadeOut, kSpcMaxMeasuredFade)
+}
+
+// Export the first module in the soundbank to an SPC file.
 func (bank *SoundBank) WriteSpcFile(filename string) error {
+	return bank.WriteSpcFileWithOptions(filename, SpcOptions{})
+}
+
+// Export a module in the soundbank to an SPC file, starting at the given position.
+func (bank *SoundBank) WriteSpcFileWithOptions(filename string, opts SpcOptions) error {
 	return errorcat.Guard(func(cat eC) error {
+		spcf, err := bank.BuildSpcFile(opts)
+		cat.Catch(err)
+
+		file, err := os.Create(filename)
+		cat.Catch(err)
+		defer file.Close()
+
+		cat.Catch(spcf.Write(file))
+		return nil
+	})
+}
+
+// Make an SPC file that plays a module in the soundbank.
+func (bank *SoundBank) BuildSpcFile(opts SpcOptions) (*spc.SpcFile, error) {
+	var spcf *spc.SpcFile
+	err := errorcat.Guard(func(cat eC) error {
 		if !verifySpcPatchSignature() {
 			cat.Catch(errors.New("SPC driver signature mismatch. Please update to use the SPC export function"))
 		}
 
-		file, err := os.Create(filename)
+		if opts.Module < 0 || opts.Module >= len(bank.Modules) {
+			return fmt.Errorf("%w: module index %d is out of range", ErrInvalidSpcOptions, opts.Module)
+		}
+		mod := bank.Modules[opts.Module]
+
+		if opts.Position < 0 || opts.Position >= len(mod.Header.Sequence) || mod.Header.Sequence[opts.Position] == 255 {
+			return fmt.Errorf("%w: position %d is past the end of the sequence", ErrInvalidSpcOptions, opts.Position)
+		}
+
+		if err := opts.Tags.Validate(); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidSpcOptions, err)
+		}
+
+		// An SPC file starts without a sound region.
+		layout, err := bank.SpcLayout(mod, 0)
 		cat.Catch(err)
+		if err := layout.Validate(); err != nil {
+			return fmt.Errorf("%w: %w", ErrModuleTooBig, err)
+		}
 
-		mod := bank.Modules[0]
-		spcf := spc.NewSpcFile()
+		length, err := mod.MeasureLength(opts.Position)
+		cat.Catch(err)
+
+		spcf = spc.NewSpcFile()
 		spcf.Header.PC = 0x400
 		spcf.Header.SP = 0xEF
-		copy(spcf.Header.Tags.SongTitle[:], mod.Title)
-		copy(spcf.Header.Tags.GameTitle[:], "SNESMOD")
-		copy(spcf.Header.Tags.DumpedBy[:], "SNESMOD")
+		tags := mod.SpcTags(opts.Tags)
 
-		copy(spcf.Header.Tags.Comments[:], mod.SongMessage)
+		date, err := tags.ParseDate()
+		cat.Catch(err)
+		if date.IsZero() {
+			date = opts.Date
+		}
+		if date.IsZero() {
+			date, err = BuildDate()
+			cat.Catch(err)
+		}
 
-		datestring := time.Now().Format("01/02/2006")
+		duration, fadeOut := spcPlayTime(length)
+		if tags.Length != nil {
+			duration = *tags.Length
+		}
+		if tags.Fade != nil {
+			fadeOut = *tags.Fade
+		}
 
-		copy(spcf.Header.Tags.DateDumped[:], datestring)
-		copy(spcf.Header.Tags.SongDuration[:], "180")
-		copy(spcf.Header.Tags.SongFadeOut[:], "5000")
-		copy(spcf.Header.Tags.Composer[:], "") /// Todo: load artist information from mod
-		spcf.Header.Tags.FromEmulator = spc.FromEmulatorSnesmod
+		spcf.SetId666(spc.Id666{
+			SongTitle:  tags.Title,
+			GameTitle:  tags.Game,
+			DumpedBy:   tags.DumpedBy,
+			Comments:   tags.Comments,
+			DateDumped: date,
+			Duration:   duration,
+			FadeOut:    fadeOut,
+			Artist:     tags.Author,
+			Emulator:   spc.FromEmulatorSnesmod,
+		})
+
+		// The extended tags aren't limited like the ID666 fields, and they have the loop
+		// points so that players can loop the song seamlessly.
+		spcf.Xid6 = &spc.Xid6Tags{
+			SongTitle:  tags.Title,
+			GameTitle:  tags.Game,
+			Artist:     tags.Author,
+			Dumper:     tags.DumpedBy,
+			Comments:   tags.Comments,
+			DateDumped: date,
+			Emulator:   spc.FromEmulatorSnesmod,
+			FadeLength: uint32(fadeOut) * spc.Xid6TicksPerSecond / 1000,
+		}
+		if tags.Length != nil {
+			spcf.Xid6.IntroLength = uint32(duration) * spc.Xid6TicksPerSecond
+		} else {
+			spcf.Xid6.IntroLength = spc.Xid6Ticks(length.Intro)
+			spcf.Xid6.LoopLength = spc.Xid6Ticks(length.Loop)
+			spcf.Xid6.LoopCount = 1
+		}
 
 		// Memory offsets for SPC driver
-		const memSpcProgram = 0x400
-		const memSampleTable = 0x200
-		const memModuleStart = 0x1a00
+		const memSpcProgram = kSpcDriverBase
+		const memSampleTable = kSpcSampleDirectory
+		const memModuleStart = kModuleBase
 
-		if len(spcDriverBinary) > memModuleStart-memSpcProgram {
-			return errors.New("spc driver is too large")
-		}
+		// The driver sets up the DSP when it starts, but players read the sample directory
+		// to list the samples before running it.
+		spcf.DspRegisters.SetSampleDirectory(memSampleTable)
 
 		copy(spcf.Memory[memSpcProgram:], spcDriverBinary)
 
@@ -100,12 +214,13 @@ func (bank *SoundBank) WriteSpcFile(filename string) error {
 		// immediately. This address is verified in spc_test.go.
 		spcf.Memory[memSpcProgram+kSpcPatchStart] = 0
 		spcf.Memory[memSpcProgram+kSpcPatchStart+1] = 0
+		spcf.Memory[memSpcProgram+kSpcPatchPosition] = byte(opts.Position)
 
 		moduleBuffer := &SeekingByteBuffer{}
-		cat.Catch(bank.Modules[0].Export(moduleBuffer, false))
+		cat.Catch(mod.Export(moduleBuffer, false))
 
-		for i := 0; i < len(bank.Modules[0].SourceList); i++ {
-			source := bank.Sources[bank.Modules[0].SourceList[i]]
+		for i := 0; i < len(mod.SourceList); i++ {
+			source := bank.Sources[mod.SourceList[i]]
 
 			// Copy the sample START and LOOP points to memory.
 			sampleStart := uint16(moduleBuffer.Tell()) + memModuleStart
@@ -120,14 +235,9 @@ func (bank *SoundBank) WriteSpcFile(filename string) error {
 			moduleBuffer.Write(source.Data)
 		}
 
-		if memModuleStart+len(moduleBuffer.Bytes()) > 0xFF80 {
-			return ErrModuleTooBig
-		}
-
 		copy(spcf.Memory[memModuleStart:], moduleBuffer.Bytes())
 
-		cat.Catch(spcf.Write(file))
-
 		return nil
 	})
+	return spcf, err
 }
@@ -5,9 +5,14 @@
 package smconv
 
 import (
+	"fmt"
+	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"go.mukunda.com/snesmod/smconv/spc"
 )
 
 // A test to help ensure that the SPC patch location is correct. If this test fails then
@@ -18,3 +23,74 @@ func TestConfirmPatchSignature(t *testing.T) {
 	// patched. The program will panic if there's a signature mismatch.
 	assert.True(t, verifySpcPatchSignature())
 }
+
+func TestWriteSpcFileWithOptions(t *testing.T) {
+	bank := buildRoundTripBank(t)
+	date := time.Date(2025, 3, 14, 0, 0, 0, 0, time.UTC)
+
+	err := bank.WriteSpcFileWithOptions(".testdata-options.spc", SpcOptions{Module: 1, Position: 1, Date: date})
+	assert.NoError(t, err)
+
+	f, err := os.Open(".testdata-options.spc")
+	assert.NoError(t, err)
+	defer f.Close()
+	spcf := spc.NewSpcFile()
+	assert.NoError(t, spcf.Read(f))
+
+	// The start position is patched into the boot code.
+	assert.EqualValues(t, 0xE8, spcf.Memory[0x400+kSpcPatchPosition-1])
+	assert.EqualValues(t, 1, spcf.Memory[0x400+kSpcPatchPosition])
+
+	// The second module is loaded.
+	moduleData := &SeekingByteBuffer{}
+	assert.NoError(t, bank.Modules[1].Export(moduleData, false))
+	assert.Equal(t, moduleData.Bytes(), spcf.Memory[kModuleBase:kModuleBase+len(moduleData.Bytes())])
+
+	// It boots like a real SPC700, with the IPL ROM and the DSP muted.
+	assert.Equal(t, spc.IplRomData, spcf.IplRom)
+	assert.Equal(t, spc.IplRomData[:], spcf.Memory[0xFFC0:])
+	assert.EqualValues(t, spc.DspFlgReset|spc.DspFlgMute|spc.DspFlgEchoDisable, spcf.DspRegisters.Flags())
+	assert.EqualValues(t, kSpcSampleDirectory, spcf.DspRegisters.SampleDirectory())
+
+	assert.Equal(t, "03/14/2025", string(spcf.Header.Tags.DateDumped[:10]))
+
+	// The play time is measured from the start position.
+	length, err := bank.Modules[1].MeasureLength(1)
+	assert.NoError(t, err)
+	duration, fadeOut := spcPlayTime(length)
+	assert.Equal(t, fmt.Sprint(duration), strings.TrimRight(string(spcf.Header.Tags.SongDuration[:]), "\x00"))
+	assert.Equal(t, fmt.Sprint(fadeOut), strings.TrimRight(string(spcf.Header.Tags.SongFadeOut[:]), "\x00"))
+
+	// The extended tags have the exact loop.
+	if assert.NotNil(t, spcf.Xid6) {
+		assert.Equal(t, spc.Xid6Ticks(length.Intro), spcf.Xid6.IntroLength)
+		assert.Equal(t, spc.Xid6Ticks(length.Loop), spcf.Xid6.LoopLength)
+		assert.EqualValues(t, fadeOut*64, spcf.Xid6.FadeLength)
+		assert.Equal(t, bank.Modules[1].Title, spcf.Xid6.SongTitle)
+		assert.Equal(t, date, spcf.Xid6.DateDumped)
+	}
+
+	err = bank.WriteSpcFileWithOptions(".testdata-options.spc", SpcOptions{Module: 3})
+	assert.ErrorIs(t, err, ErrInvalidSpcOptions)
+
+	// Reflection's sequence ends at position 2.
+	err = bank.WriteSpcFileWithOptions(".testdata-options.spc", SpcOptions{Module: 1, Position: 2})
+	assert.ErrorIs(t, err, ErrInvalidSpcOptions)
+}
+
+func TestFindModule(t *testing.T) {
+	bank := buildRoundTripBank(t)
+
+	index, err := bank.FindModule("MOD_REFLECTION")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, index)
+
+	index, err = bank.FindModule("0")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, index)
+
+	_, err = bank.FindModule("2")
+	assert.ErrorIs(t, err, ErrModuleNotFound)
+	_, err = bank.FindModule("MOD_MISSING")
+	assert.ErrorIs(t, err, ErrModuleNotFound)
+}
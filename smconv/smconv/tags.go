@@ -0,0 +1,208 @@
+// SNESMOD
+// (C) 2025 Mukunda Johnson (mukunda.com)
+// Licensed under MIT
+
+// This file describes the ID666 tags written to SPC files. Tags come from several places,
+// and the first one that sets a field wins: the SPC options (command line), the module
+// options (manifest), the module's own metadata (title and song message), and last the
+// defaults.
+
+package smconv
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mukunda.com/snesmod/smconv/spc"
+)
+
+// Format of dates in tags. The ID666 field uses MM/DD/YYYY.
+const kTagDateFormat = "2006-01-02"
+
+// Limit for the fade length: 5 digits of milliseconds.
+const kSpcMaxFade = 99999
+
+var ErrInvalidSourceDateEpoch = errors.New("invalid SOURCE_DATE_EPOCH")
+
+// The date for outputs that don't have one. This is SOURCE_DATE_EPOCH when it's set, so
+// that builds are reproducible (https://reproducible-builds.org/specs/source-date-epoch/),
+// or the current date.
+func BuildDate() (time.Time, error) {
+	epoch, ok := os.LookupEnv("SOURCE_DATE_EPOCH")
+	if !ok || epoch == "" {
+		return time.Now(), nil
+	}
+
+	seconds, err := strconv.ParseInt(epoch, 10, 64)
+	if err != nil || seconds < 0 {
+		return time.Time{}, fmt.Errorf("%w: %q", ErrInvalidSourceDateEpoch, epoch)
+	}
+	return time.Unix(seconds, 0).UTC(), nil
+}
+
+// Metadata used when exporting to SPC. Empty fields keep the module's own metadata.
+type ModuleTags struct {
+	Title    string `yaml:"title" json:"title" toml:"title"`
+	Game     string `yaml:"game" json:"game" toml:"game"`
+	Author   string `yaml:"author" json:"author" toml:"author"` // Artist/composer
+	DumpedBy string `yaml:"dumpedBy" json:"dumpedBy" toml:"dumpedBy"`
+	Comments string `yaml:"comments" json:"comments" toml:"comments"`
+	Date     string `yaml:"date" json:"date" toml:"date"` // YYYY-MM-DD
+
+	// Seconds to play before fading out, and the fade length in milliseconds. Unset values
+	// are measured from the song.
+	Length *int `yaml:"length" json:"length" toml:"length"`
+	Fade   *int `yaml:"fade" json:"fade" toml:"fade"`
+}
+
+// Check that the tags fit in the ID666 fields.
+func (tags *ModuleTags) Validate() error {
+	errs := []error{}
+
+	var fields spc.Id6Tags
+	for _, f := range []struct {
+		name  string
+		value string
+		size  int
+	}{
+		{"title", tags.Title, len(fields.SongTitle)},
+		{"game", tags.Game, len(fields.GameTitle)},
+		{"author", tags.Author, len(fields.Composer)},
+		{"dumpedBy", tags.DumpedBy, len(fields.DumpedBy)},
+		{"comments", tags.Comments, len(fields.Comments)},
+	} {
+		if len(f.value) > f.size {
+			errs = append(errs, fmt.Errorf("%w: %s tag is longer than %d bytes", ErrInvalidModuleOptions, f.name, f.size))
+		}
+	}
+
+	if tags.Date != "" {
+		if _, err := tags.ParseDate(); err != nil {
+			errs = append(errs, fmt.Errorf("%w: date tag must be YYYY-MM-DD: %s", ErrInvalidModuleOptions, tags.Date))
+		}
+	}
+	if tags.Length != nil {
+		errs = append(errs, checkOptionRange("length tag", *tags.Length, 1, kSpcMaxDuration))
+	}
+	if tags.Fade != nil {
+		errs = append(errs, checkOptionRange("fade tag", *tags.Fade, 0, kSpcMaxFade))
+	}
+
+	return errors.Join(errs...)
+}
+
+// The date tag as a time, or the zero time if it isn't set.
+func (tags *ModuleTags) ParseDate() (time.Time, error) {
+	if tags.Date == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(kTagDateFormat, tags.Date)
+}
+
+// Returns the tags with the fields that are set in `overrides` replaced.
+func (tags ModuleTags) Merge(overrides ModuleTags) ModuleTags {
+	replace := func(field *string, value string) {
+		if value != "" {
+			*field = value
+		}
+	}
+
+	replace(&tags.Title, overrides.Title)
+	replace(&tags.Game, overrides.Game)
+	replace(&tags.Author, overrides.Author)
+	replace(&tags.DumpedBy, overrides.DumpedBy)
+	replace(&tags.Comments, overrides.Comments)
+	replace(&tags.Date, overrides.Date)
+	if overrides.Length != nil {
+		tags.Length = overrides.Length
+	}
+	if overrides.Fade != nil {
+		tags.Fade = overrides.Fade
+	}
+	return tags
+}
+
+// The first line of the song message, for the comments tag.
+func messageSummary(message string) string {
+	for _, line := range strings.FieldsFunc(message, func(r rune) bool { return r == '\r' || r == '\n' }) {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+// Resolve the tags for an SPC of the module. `overrides` are the tags from the SPC
+// options.
+func (smm *SmModule) SpcTags(overrides ModuleTags) ModuleTags {
+	tags := ModuleTags{
+		Title:    smm.Title,
+		Game:     "SNESMOD",
+		Author:   smm.Author,
+		DumpedBy: "SNESMOD",
+		Comments: messageSummary(smm.SongMessage),
+	}
+	if smm.Game != "" {
+		tags.Game = smm.Game
+	}
+	return tags.Merge(smm.Tags).Merge(overrides)
+}
+
+// Replace the tags in an existing SPC file with the ones that are set, keeping the rest.
+// The ID666 tags are rewritten in the text format, and the extended tags are updated if
+// the file has them. Nothing else in the file is changed.
+func (tags *ModuleTags) ApplyToSpc(spcf *spc.SpcFile) error {
+	if err := tags.Validate(); err != nil {
+		return err
+	}
+	date, err := tags.ParseDate()
+	if err != nil {
+		return err
+	}
+
+	replace := func(fields []*string, value string) {
+		if value != "" {
+			for _, field := range fields {
+				*field = value
+			}
+		}
+	}
+
+	id666 := spcf.Id666()
+	xid6 := spcf.Xid6
+	if xid6 == nil {
+		// Edits to a throwaway copy, so the file doesn't gain an xid6 chunk.
+		xid6 = &spc.Xid6Tags{}
+	}
+
+	replace([]*string{&id666.SongTitle, &xid6.SongTitle}, tags.Title)
+	replace([]*string{&id666.GameTitle, &xid6.GameTitle}, tags.Game)
+	replace([]*string{&id666.Artist, &xid6.Artist}, tags.Author)
+	replace([]*string{&id666.DumpedBy, &xid6.Dumper}, tags.DumpedBy)
+	replace([]*string{&id666.Comments, &xid6.Comments}, tags.Comments)
+	if !date.IsZero() {
+		id666.DateDumped = date
+		xid6.DateDumped = date
+	}
+
+	// A fixed length replaces the loop points, like it does on export. Otherwise, players
+	// that read the xid6 tags would keep playing the old length.
+	if tags.Length != nil {
+		id666.Duration = *tags.Length
+		xid6.IntroLength = uint32(*tags.Length) * spc.Xid6TicksPerSecond
+		xid6.LoopLength = 0
+		xid6.EndLength = 0
+		xid6.LoopCount = 0
+	}
+	if tags.Fade != nil {
+		id666.FadeOut = *tags.Fade
+		xid6.FadeLength = uint32(*tags.Fade) * spc.Xid6TicksPerSecond / 1000
+	}
+
+	spcf.SetId666(id666)
+	return nil
+}
@@ -0,0 +1,157 @@
+// SNESMOD
+// (C) 2025 Mukunda Johnson (mukunda.com)
+// Licensed under MIT
+
+package smconv
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mukunda.com/modlib"
+	"go.mukunda.com/snesmod/smconv/spc"
+)
+
+func TestModuleTagsValidate(t *testing.T) {
+	length, fade := 90, 3000
+	tags := ModuleTags{Title: "Town", Date: "2025-03-14", Length: &length, Fade: &fade}
+	assert.NoError(t, tags.Validate())
+
+	date, err := tags.ParseDate()
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 3, 14, 0, 0, 0, 0, time.UTC), date)
+
+	length = 1000
+	tags.DumpedBy = strings.Repeat("x", 17)
+	tags.Date = "03/14/2025"
+	err = tags.Validate()
+	assert.ErrorIs(t, err, ErrInvalidModuleOptions)
+	assert.ErrorContains(t, err, "dumpedBy tag is longer than 16 bytes")
+	assert.ErrorContains(t, err, "date tag must be YYYY-MM-DD")
+	assert.ErrorContains(t, err, "length tag value out of range: 1000")
+}
+
+func TestModuleTagsMerge(t *testing.T) {
+	length := 60
+	base := ModuleTags{Title: "Town", Game: "Quest"}
+	merged := base.Merge(ModuleTags{Game: "Quest 2", Length: &length})
+	assert.Equal(t, ModuleTags{Title: "Town", Game: "Quest 2", Length: &length}, merged)
+	assert.Equal(t, "Quest", base.Game)
+}
+
+func TestSpcTags(t *testing.T) {
+	smm := &SmModule{
+		Title:       "Town",
+		SongMessage: "\n  Town theme  \nBy someone\n[[SNESMOD]]\nedl 1",
+	}
+	assert.Equal(t, ModuleTags{
+		Title:    "Town",
+		Game:     "SNESMOD",
+		DumpedBy: "SNESMOD",
+		Comments: "Town theme",
+	}, smm.SpcTags(ModuleTags{}))
+
+	smm.Author = "Someone"
+	smm.Game = "Quest"
+	smm.Tags = ModuleTags{Title: "Town (Day)", DumpedBy: "Me"}
+	tags := smm.SpcTags(ModuleTags{DumpedBy: "You"})
+	assert.Equal(t, "Town (Day)", tags.Title)
+	assert.Equal(t, "Quest", tags.Game)
+	assert.Equal(t, "Someone", tags.Author)
+	assert.Equal(t, "You", tags.DumpedBy)
+}
+
+func TestSongMessageTags(t *testing.T) {
+	smm := &SmModule{}
+	smm.parseSmOptions(&modlib.Module{Message: "Hello\n[[SNESMOD]]\nauthor  Mr. X \ngame Quest for SNES\n"})
+	assert.Empty(t, smm.Warnings)
+	assert.Equal(t, "Mr. X", smm.Author)
+	assert.Equal(t, "Quest for SNES", smm.Game)
+}
+
+func TestWriteSpcFileTags(t *testing.T) {
+	bank := buildRoundTripBank(t)
+	length, fade := 75, 2500
+	bank.Modules[1].Tags = ModuleTags{Author: "Someone", Date: "2024-12-31"}
+
+	err := bank.WriteSpcFileWithOptions(".testdata-tags.spc", SpcOptions{
+		Module: 1,
+		Tags:   ModuleTags{Game: "Quest", Comments: "Track 2", Length: &length, Fade: &fade},
+	})
+	assert.NoError(t, err)
+
+	f, err := os.Open(".testdata-tags.spc")
+	assert.NoError(t, err)
+	defer f.Close()
+	spcf := spc.NewSpcFile()
+	assert.NoError(t, spcf.Read(f))
+
+	field := func(data []byte) string {
+		return strings.TrimRight(string(data), "\x00")
+	}
+	id6 := spcf.Header.Tags
+	assert.Equal(t, bank.Modules[1].Title, field(id6.SongTitle[:]))
+	assert.Equal(t, "Quest", field(id6.GameTitle[:]))
+	assert.Equal(t, "Someone", field(id6.Composer[:]))
+	assert.Equal(t, "SNESMOD", field(id6.DumpedBy[:]))
+	assert.Equal(t, "Track 2", field(id6.Comments[:]))
+	assert.Equal(t, "12/31/2024", field(id6.DateDumped[:]))
+	assert.Equal(t, "75", field(id6.SongDuration[:]))
+	assert.Equal(t, "2500", field(id6.SongFadeOut[:]))
+
+	// A length tag replaces the measured loop.
+	assert.EqualValues(t, 75*64000, spcf.Xid6.IntroLength)
+	assert.Zero(t, spcf.Xid6.LoopLength)
+	assert.EqualValues(t, 2500*64, spcf.Xid6.FadeLength)
+
+	length = 0
+	err = bank.WriteSpcFileWithOptions(".testdata-tags.spc", SpcOptions{Module: 1, Tags: ModuleTags{Length: &length}})
+	assert.ErrorIs(t, err, ErrInvalidSpcOptions)
+}
+
+func TestApplyToSpc(t *testing.T) {
+	spcf := spc.NewSpcFile()
+	spcf.SetId666(spc.Id666{SongTitle: "Song", GameTitle: "Game", Duration: 100, FadeOut: 3000, ChannelDisables: 0x10})
+	spcf.Memory[0x1234] = 0xAB
+	spcf.DspRegisters[0x5D] = 0x02
+	spcf.Header.Tags.Reserved[0] = 0xCD
+	spcf.Xid6 = &spc.Xid6Tags{SongTitle: "A song title that is longer than 32 bytes",
+		IntroLength: 5 * 64000, LoopLength: 30 * 64000, LoopCount: 1}
+	spcf.Extended = []byte{1, 2, 3, 4}
+
+	length := 90
+	tags := ModuleTags{Game: "Quest", Author: "Someone", Date: "2025-03-14", Length: &length}
+	assert.NoError(t, tags.ApplyToSpc(spcf))
+
+	id666 := spcf.Id666()
+	assert.Equal(t, "Song", id666.SongTitle)
+	assert.Equal(t, "Quest", id666.GameTitle)
+	assert.Equal(t, "Someone", id666.Artist)
+	assert.Equal(t, time.Date(2025, 3, 14, 0, 0, 0, 0, time.UTC), id666.DateDumped)
+	assert.Equal(t, 90, id666.Duration)
+	assert.Equal(t, 3000, id666.FadeOut)
+	assert.EqualValues(t, 0x10, id666.ChannelDisables)
+
+	assert.Equal(t, "A song title that is longer than 32 bytes", spcf.Xid6.SongTitle)
+	assert.Equal(t, "Quest", spcf.Xid6.GameTitle)
+	assert.EqualValues(t, 90*64000, spcf.Xid6.IntroLength)
+	assert.Zero(t, spcf.Xid6.LoopLength)
+	assert.Zero(t, spcf.Xid6.LoopCount)
+
+	// Everything else is kept.
+	assert.EqualValues(t, 0xAB, spcf.Memory[0x1234])
+	assert.EqualValues(t, 0x02, spcf.DspRegisters[0x5D])
+	assert.EqualValues(t, 0xCD, spcf.Header.Tags.Reserved[0])
+	assert.Equal(t, []byte{1, 2, 3, 4}, spcf.Extended)
+
+	// Files without extended tags don't get them.
+	spcf.Xid6 = nil
+	assert.NoError(t, tags.ApplyToSpc(spcf))
+	assert.Nil(t, spcf.Xid6)
+
+	tags = ModuleTags{Date: "today"}
+	assert.ErrorIs(t, tags.ApplyToSpc(spcf), ErrInvalidModuleOptions)
+}
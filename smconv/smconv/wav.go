@@ -0,0 +1,376 @@
+// SNESMOD
+// (C) 2025 Mukunda Johnson (mukunda.com)
+// Licensed under MIT
+
+// This file reads WAV files to be used as soundbank sources. Sample loops and the unity
+// note are read from the "smpl" chunk when present. It also writes 16-bit WAV files for
+// rendered audio and decoded sources.
+
+package smconv
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"go.mukunda.com/errorcat"
+)
+
+const (
+	kWavFormatPcm        = 1
+	kWavFormatFloat      = 3
+	kWavFormatExtensible = 0xFFFE
+
+	// MIDI note that plays a sample at its own rate when no smpl chunk is present. This is
+	// C-5 in tracker terms.
+	kWavDefaultUnityNote = 60
+)
+
+var ErrInvalidWav = errors.New("invalid wav file")
+var ErrUnsupportedWav = errors.New("unsupported wav format")
+
+// A WAV file mixed down to mono 16-bit PCM.
+type WavSample struct {
+	// Samples per second.
+	Rate int
+
+	Data []int16
+
+	Loop     bool
+	PingPong bool
+
+	// Loop region in samples. LoopEnd is exclusive.
+	LoopStart int
+	LoopEnd   int
+
+	// MIDI note that is heard when the sample is played at Rate, including the pitch
+	// fraction from the smpl chunk. Without a smpl chunk, it's kWavDefaultUnityNote (C-5).
+	UnityNote float64
+}
+
+type wavFormat struct {
+	Format        uint16
+	Channels      uint16
+	Rate          uint32
+	ByteRate      uint32
+	BlockAlign    uint16
+	BitsPerSample uint16
+}
+
+// Size of the RIFF header, fmt chunk, and data chunk header.
+const kWavHeaderSize = 44
+
+// Write the header of a 16-bit PCM WAV file with `frames` samples per channel. The
+// sample data follows it. `chunks` are extra chunks placed before the data, already
+// encoded with their IDs and sizes.
+func writeWavHeader(cat eC, w io.Writer, channels int, rate int, frames int, chunks []byte) {
+	dataSize := frames * channels * 2
+	bwrite(cat, w, []byte("RIFF"))
+	bwrite(cat, w, uint32(kWavHeaderSize-8+len(chunks)+dataSize))
+	bwrite(cat, w, []byte("WAVEfmt "))
+	bwrite(cat, w, uint32(binary.Size(wavFormat{})))
+	bwrite(cat, w, wavFormat{
+		Format:        kWavFormatPcm,
+		Channels:      uint16(channels),
+		Rate:          uint32(rate),
+		ByteRate:      uint32(rate * channels * 2),
+		BlockAlign:    uint16(channels * 2),
+		BitsPerSample: 16,
+	})
+	bwrite(cat, w, chunks)
+	bwrite(cat, w, []byte("data"))
+	bwrite(cat, w, uint32(dataSize))
+}
+
+// Write 16-bit PCM to a WAV file. Channels are interleaved.
+func WriteWav(w io.Writer, pcm []int16, channels int, rate int) error {
+	return errorcat.Guard(func(cat eC) error {
+		writeWavHeader(cat, w, channels, rate, len(pcm)/channels, nil)
+		bwrite(cat, w, pcm)
+		return nil
+	})
+}
+
+// Write the sample to a mono 16-bit WAV file. A smpl chunk with the unity note and the
+// loop is added when they aren't the defaults, so ReadWav gives the same sample back.
+func (wav *WavSample) Write(w io.Writer) error {
+	return errorcat.Guard(func(cat eC) error {
+		var chunks []byte
+		if wav.Loop || wav.UnityNote != kWavDefaultUnityNote {
+			chunks = wav.smplChunk()
+		}
+		writeWavHeader(cat, w, 1, wav.Rate, len(wav.Data), chunks)
+		bwrite(cat, w, wav.Data)
+		return nil
+	})
+}
+
+// Encode the smpl chunk for the unity note and loop.
+func (wav *WavSample) smplChunk() []byte {
+	unityNote := math.Floor(wav.UnityNote)
+	fraction := math.Round((wav.UnityNote - unityNote) * (1 << 32))
+	numLoops := 0
+	if wav.Loop {
+		numLoops = 1
+	}
+
+	fields := []uint32{
+		0, 0, // manufacturer, product
+		uint32(math.Round(1e9 / float64(wav.Rate))), // sample period in nanoseconds
+		uint32(unityNote), uint32(min(fraction, math.MaxUint32)),
+		0, 0, // SMPTE format and offset
+		uint32(numLoops), 0,
+	}
+	if wav.Loop {
+		loopType := uint32(0)
+		if wav.PingPong {
+			loopType = 1
+		}
+		// The end point is inclusive.
+		fields = append(fields, 0, loopType, uint32(wav.LoopStart), uint32(wav.LoopEnd-1), 0, 0)
+	}
+
+	chunk := binary.LittleEndian.AppendUint32([]byte("smpl"), uint32(len(fields)*4))
+	for _, field := range fields {
+		chunk = binary.LittleEndian.AppendUint32(chunk, field)
+	}
+	return chunk
+}
+
+// Load a WAV file from disk.
+func LoadWav(filename string) (*WavSample, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadWav(f)
+}
+
+// Read a WAV file. Integer PCM of 8 to 32 bits and floating point PCM are supported.
+// Multiple channels are mixed down to mono.
+func ReadWav(r io.Reader) (*WavSample, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		return nil, fmt.Errorf("%w: missing RIFF/WAVE header", ErrInvalidWav)
+	}
+
+	var format *wavFormat
+	var pcm []byte
+	wav := &WavSample{UnityNote: kWavDefaultUnityNote}
+
+	for pos := 12; pos+8 <= len(data); {
+		id := string(data[pos : pos+4])
+		size := int(binary.LittleEndian.Uint32(data[pos+4:]))
+		pos += 8
+		if size > len(data)-pos {
+			return nil, fmt.Errorf("%w: chunk \"%s\" is truncated", ErrInvalidWav, id)
+		}
+		chunk := data[pos : pos+size]
+
+		switch id {
+		case "fmt ":
+			if format, err = readWavFormat(chunk); err != nil {
+				return nil, err
+			}
+		case "data":
+			pcm = chunk
+		case "smpl":
+			readWavSampler(chunk, wav)
+		}
+
+		// Chunks are padded to an even size.
+		pos += size + size&1
+	}
+
+	if format == nil {
+		return nil, fmt.Errorf("%w: missing fmt chunk", ErrInvalidWav)
+	}
+	if pcm == nil {
+		return nil, fmt.Errorf("%w: missing data chunk", ErrInvalidWav)
+	}
+
+	wav.Rate = int(format.Rate)
+	wav.Data = decodeWavPcm(format, pcm)
+
+	if wav.Loop {
+		wav.LoopEnd = min(wav.LoopEnd, len(wav.Data))
+		if wav.LoopStart >= wav.LoopEnd {
+			wav.Loop, wav.PingPong = false, false
+			wav.LoopStart, wav.LoopEnd = 0, 0
+		}
+	}
+
+	return wav, nil
+}
+
+func readWavFormat(chunk []byte) (*wavFormat, error) {
+	if len(chunk) < 16 {
+		return nil, fmt.Errorf("%w: fmt chunk is too small", ErrInvalidWav)
+	}
+
+	format := &wavFormat{
+		Format:        binary.LittleEndian.Uint16(chunk[0:]),
+		Channels:      binary.LittleEndian.Uint16(chunk[2:]),
+		Rate:          binary.LittleEndian.Uint32(chunk[4:]),
+		ByteRate:      binary.LittleEndian.Uint32(chunk[8:]),
+		BlockAlign:    binary.LittleEndian.Uint16(chunk[12:]),
+		BitsPerSample: binary.LittleEndian.Uint16(chunk[14:]),
+	}
+
+	if format.Format == kWavFormatExtensible {
+		// The real format is at the start of the subformat GUID.
+		if len(chunk) < 26 {
+			return nil, fmt.Errorf("%w: fmt chunk is too small", ErrInvalidWav)
+		}
+		format.Format = binary.LittleEndian.Uint16(chunk[24:])
+	}
+
+	switch {
+	case format.Format == kWavFormatPcm && format.BitsPerSample%8 == 0 &&
+		format.BitsPerSample >= 8 && format.BitsPerSample <= 32:
+	case format.Format == kWavFormatFloat && (format.BitsPerSample == 32 || format.BitsPerSample == 64):
+	default:
+		return nil, fmt.Errorf("%w: format %d with %d bits", ErrUnsupportedWav, format.Format, format.BitsPerSample)
+	}
+
+	if format.Channels == 0 || format.Rate == 0 {
+		return nil, fmt.Errorf("%w: no channels or sample rate", ErrInvalidWav)
+	}
+
+	return format, nil
+}
+
+// Read the unity note and first loop from a smpl chunk.
+func readWavSampler(chunk []byte, wav *WavSample) {
+	if len(chunk) < 36 {
+		return
+	}
+
+	unityNote := binary.LittleEndian.Uint32(chunk[12:])
+	pitchFraction := binary.LittleEndian.Uint32(chunk[16:])
+	numLoops := binary.LittleEndian.Uint32(chunk[28:])
+	wav.UnityNote = float64(unityNote) + float64(pitchFraction)/(1<<32)
+
+	// Loops follow the header. Only the first one is used.
+	if numLoops == 0 || len(chunk) < 36+24 {
+		return
+	}
+
+	loop := chunk[36:]
+	loopType := binary.LittleEndian.Uint32(loop[4:])
+	start := binary.LittleEndian.Uint32(loop[8:])
+	end := binary.LittleEndian.Uint32(loop[12:])
+
+	// 0 is forward and 1 is alternating. Backward loops can't be represented.
+	if loopType > 1 || end < start {
+		return
+	}
+
+	wav.Loop = true
+	wav.PingPong = loopType == 1
+	wav.LoopStart = int(start)
+	wav.LoopEnd = int(end) + 1 // The end point is inclusive.
+}
+
+// Convert PCM frames to mono 16-bit samples.
+func decodeWavPcm(format *wavFormat, pcm []byte) []int16 {
+	channels := int(format.Channels)
+	width := int(format.BitsPerSample) / 8
+	frameSize := channels * width
+	frames := len(pcm) / frameSize
+
+	result := make([]int16, frames)
+	for i := 0; i < frames; i++ {
+		sum := 0.0
+		for c := 0; c < channels; c++ {
+			sum += wavSampleValue(format, pcm[(i*channels+c)*width:])
+		}
+		value := math.Round(sum / float64(channels) * 32768)
+		result[i] = int16(max(min(value, 32767), -32768))
+	}
+
+	return result
+}
+
+// Returns one sample in the range -1 to 1.
+func wavSampleValue(format *wavFormat, b []byte) float64 {
+	if format.Format == kWavFormatFloat {
+		if format.BitsPerSample == 64 {
+			return math.Float64frombits(binary.LittleEndian.Uint64(b))
+		}
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+	}
+
+	switch format.BitsPerSample {
+	case 8:
+		// 8-bit WAV data is unsigned.
+		return float64(int(b[0])-128) / 128
+	case 16:
+		return float64(int16(binary.LittleEndian.Uint16(b))) / 32768
+	case 24:
+		v := int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24) >> 8
+		return float64(v) / (1 << 23)
+	default:
+		return float64(int32(binary.LittleEndian.Uint32(b))) / (1 << 31)
+	}
+}
+
+// The playback rate that sounds as C-5, taking the unity note into account.
+func (wav *WavSample) C5Rate() float64 {
+	return float64(wav.Rate) * math.Pow(2, (kWavDefaultUnityNote-wav.UnityNote)/12)
+}
+
+// Returns a copy of the sample converted to another rate with linear interpolation. The
+// loop points are scaled to match.
+func (wav *WavSample) Resample(rate int) *WavSample {
+	return wav.resample(float64(wav.Rate), rate)
+}
+
+// Returns a copy of the sample resampled so that it sounds as C-5 when played at `rate`.
+// The unity note of the result is C-5.
+func (wav *WavSample) Retune(rate int) *WavSample {
+	result := wav.resample(wav.C5Rate(), rate)
+	result.UnityNote = kWavDefaultUnityNote
+	return result
+}
+
+// Resample the data as if it was recorded at `from` samples per second.
+func (wav *WavSample) resample(from float64, rate int) *WavSample {
+	result := *wav
+	result.Rate = rate
+	if from == float64(rate) || len(wav.Data) == 0 {
+		result.Data = append([]int16(nil), wav.Data...)
+		return &result
+	}
+
+	ratio := from / float64(rate)
+	length := max(int(math.Round(float64(len(wav.Data))/ratio)), 1)
+	result.Data = make([]int16, length)
+
+	for i := range result.Data {
+		pos := float64(i) * ratio
+		index := int(pos)
+		if index >= len(wav.Data)-1 {
+			result.Data[i] = wav.Data[len(wav.Data)-1]
+			continue
+		}
+		frac := pos - float64(index)
+		a, b := float64(wav.Data[index]), float64(wav.Data[index+1])
+		result.Data[i] = int16(math.Round(addProduct(a, b-a, frac)))
+	}
+
+	if wav.Loop {
+		result.LoopStart = min(int(math.Round(float64(wav.LoopStart)/ratio)), length-1)
+		result.LoopEnd = max(min(int(math.Round(float64(wav.LoopEnd)/ratio)), length), result.LoopStart+1)
+	}
+
+	return &result
+}
@@ -0,0 +1,247 @@
+// SNESMOD
+// (C) 2025 Mukunda Johnson (mukunda.com)
+// Licensed under MIT
+
+package smconv
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Builds a WAV file from a fmt chunk, sample data, and an optional smpl chunk.
+func buildWav(format, channels, rate, bits int, data []byte, smpl []byte) []byte {
+	chunk := func(w *bytes.Buffer, id string, body []byte) {
+		w.WriteString(id)
+		binary.Write(w, binary.LittleEndian, uint32(len(body)))
+		w.Write(body)
+		if len(body)&1 != 0 {
+			w.WriteByte(0)
+		}
+	}
+
+	fmtChunk := &bytes.Buffer{}
+	blockAlign := channels * bits / 8
+	binary.Write(fmtChunk, binary.LittleEndian, wavFormat{
+		uint16(format), uint16(channels), uint32(rate), uint32(rate * blockAlign),
+		uint16(blockAlign), uint16(bits),
+	})
+
+	body := &bytes.Buffer{}
+	body.WriteString("WAVE")
+	chunk(body, "fmt ", fmtChunk.Bytes())
+	if smpl != nil {
+		chunk(body, "smpl", smpl)
+	}
+	chunk(body, "data", data)
+
+	file := &bytes.Buffer{}
+	chunk(file, "RIFF", body.Bytes())
+	return file.Bytes()
+}
+
+func buildSmpl(unityNote, fraction uint32, loopType, start, end uint32) []byte {
+	smpl := &bytes.Buffer{}
+	binary.Write(smpl, binary.LittleEndian, []uint32{
+		0, 0, 0, unityNote, fraction, 0, 0, 1, 0, // header
+		0, loopType, start, end, 0, 0, // loop
+	})
+	return smpl.Bytes()
+}
+
+func TestReadWav(t *testing.T) {
+	// 16-bit stereo, mixed down to mono.
+	pcm := &bytes.Buffer{}
+	binary.Write(pcm, binary.LittleEndian, []int16{1000, 3000, -2000, -4000, 0, 0, 32767, 32767})
+	wav, err := ReadWav(bytes.NewReader(buildWav(1, 2, 22050, 16, pcm.Bytes(), nil)))
+	assert.NoError(t, err)
+	assert.Equal(t, 22050, wav.Rate)
+	assert.Equal(t, []int16{2000, -3000, 0, 32767}, wav.Data)
+	assert.False(t, wav.Loop)
+	assert.EqualValues(t, 60, wav.UnityNote)
+	assert.InDelta(t, 22050, wav.C5Rate(), 0.001)
+
+	// 8-bit is unsigned.
+	wav, err = ReadWav(bytes.NewReader(buildWav(1, 1, 8000, 8, []byte{128, 255, 0}, nil)))
+	assert.NoError(t, err)
+	assert.Equal(t, []int16{0, 32512, -32768}, wav.Data)
+
+	// 24-bit
+	wav, err = ReadWav(bytes.NewReader(buildWav(1, 1, 8000, 24, []byte{0x00, 0x00, 0x40, 0x00, 0x00, 0xC0}, nil)))
+	assert.NoError(t, err)
+	assert.Equal(t, []int16{16384, -16384}, wav.Data)
+
+	// 32-bit float, clipped.
+	pcm.Reset()
+	binary.Write(pcm, binary.LittleEndian, []float32{0.5, -2.0})
+	wav, err = ReadWav(bytes.NewReader(buildWav(3, 1, 8000, 32, pcm.Bytes(), nil)))
+	assert.NoError(t, err)
+	assert.Equal(t, []int16{16384, -32768}, wav.Data)
+}
+
+func TestReadWavSampler(t *testing.T) {
+	pcm := make([]byte, 200)
+
+	// A ping-pong loop with an inclusive end, and a unity note an octave above C-5 with
+	// a quarter semitone of fraction.
+	smpl := buildSmpl(72, 1<<30, 1, 10, 49)
+	wav, err := ReadWav(bytes.NewReader(buildWav(1, 1, 32000, 16, pcm, smpl)))
+	assert.NoError(t, err)
+	assert.True(t, wav.Loop)
+	assert.True(t, wav.PingPong)
+	assert.Equal(t, 10, wav.LoopStart)
+	assert.Equal(t, 50, wav.LoopEnd)
+	assert.InDelta(t, 72.25, wav.UnityNote, 0.0001)
+	assert.InDelta(t, 32000*math.Pow(2, -12.25/12), wav.C5Rate(), 0.001)
+
+	// Loops past the end of the data are clipped, and empty loops are dropped.
+	wav, err = ReadWav(bytes.NewReader(buildWav(1, 1, 32000, 16, pcm, buildSmpl(60, 0, 0, 10, 500))))
+	assert.NoError(t, err)
+	assert.True(t, wav.Loop)
+	assert.False(t, wav.PingPong)
+	assert.Equal(t, 100, wav.LoopEnd)
+
+	wav, err = ReadWav(bytes.NewReader(buildWav(1, 1, 32000, 16, pcm, buildSmpl(60, 0, 0, 150, 160))))
+	assert.NoError(t, err)
+	assert.False(t, wav.Loop)
+}
+
+func TestWavSampleWrite(t *testing.T) {
+	// Without a loop or tuning, there's no smpl chunk.
+	wav := &WavSample{Rate: 16000, Data: []int16{1, -2, 3}, UnityNote: kWavDefaultUnityNote}
+	out := &bytes.Buffer{}
+	assert.NoError(t, wav.Write(out))
+	assert.Equal(t, kWavHeaderSize+6, out.Len())
+	read, err := ReadWav(bytes.NewReader(out.Bytes()))
+	assert.NoError(t, err)
+	assert.Equal(t, wav, read)
+
+	wav = &WavSample{
+		Rate: 32000, Data: make([]int16, 100), UnityNote: 59.3,
+		Loop: true, PingPong: true, LoopStart: 10, LoopEnd: 100,
+	}
+	out.Reset()
+	assert.NoError(t, wav.Write(out))
+	read, err = ReadWav(bytes.NewReader(out.Bytes()))
+	assert.NoError(t, err)
+	assert.InDelta(t, 59.3, read.UnityNote, 1e-9)
+	read.UnityNote = wav.UnityNote
+	assert.Equal(t, wav, read)
+}
+
+func TestReadWavErrors(t *testing.T) {
+	_, err := ReadWav(bytes.NewReader([]byte("RIFX")))
+	assert.ErrorIs(t, err, ErrInvalidWav)
+
+	_, err = ReadWav(bytes.NewReader(buildWav(2, 1, 8000, 4, []byte{0}, nil)))
+	assert.ErrorIs(t, err, ErrUnsupportedWav)
+
+	// Truncated data chunk.
+	file := buildWav(1, 1, 8000, 16, make([]byte, 16), nil)
+	_, err = ReadWav(bytes.NewReader(file[:len(file)-4]))
+	assert.ErrorIs(t, err, ErrInvalidWav)
+}
+
+func TestWavResample(t *testing.T) {
+	wav := &WavSample{
+		Rate:      32000,
+		Data:      []int16{0, 100, 200, 300, 400, 500, 600, 700},
+		Loop:      true,
+		LoopStart: 2,
+		LoopEnd:   8,
+	}
+
+	half := wav.Resample(16000)
+	assert.Equal(t, 16000, half.Rate)
+	assert.Equal(t, []int16{0, 200, 400, 600}, half.Data)
+	assert.Equal(t, 1, half.LoopStart)
+	assert.Equal(t, 4, half.LoopEnd)
+
+	// The original is untouched.
+	assert.Len(t, wav.Data, 8)
+	assert.Equal(t, 32000, wav.Rate)
+}
+
+func TestWavRetune(t *testing.T) {
+	wav := &WavSample{Rate: 32000, Data: []int16{0, 100, 200, 300}, UnityNote: 72}
+	assert.InDelta(t, 16000, wav.C5Rate(), 0.001)
+
+	// C-5 is an octave below the unity note, so the data is twice as long at the same rate.
+	retuned := wav.Retune(32000)
+	assert.Equal(t, 32000, retuned.Rate)
+	assert.Equal(t, float64(kWavDefaultUnityNote), retuned.UnityNote)
+	assert.Equal(t, []int16{0, 50, 100, 150, 200, 250, 300, 300}, retuned.Data)
+
+	// Without a smpl chunk, only the rate changes.
+	wav = &WavSample{Rate: 32000, Data: []int16{0, 100, 200, 300}, UnityNote: kWavDefaultUnityNote}
+	assert.Equal(t, wav.Resample(16000), wav.Retune(16000))
+}
+
+func TestAddWavSoundEffect(t *testing.T) {
+	bank := SoundBank{}
+
+	data := make([]int16, 1600)
+	for i := range data {
+		data[i] = int16(math.Sin(float64(i)/10) * 16000)
+	}
+	wav := &WavSample{Rate: 32000, Data: data, Loop: true, LoopStart: 800, LoopEnd: 1600, UnityNote: kWavDefaultUnityNote}
+
+	index, err := bank.AddWavSoundEffect(wav, "SFX_JUMP", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "SFX_JUMP", bank.Sources[index].Id)
+
+	// The loop is kept. It's already aligned to the BRR block size.
+	assert.True(t, bank.Sources[index].Loops())
+	assert.Equal(t, 800/16*kBrrBlockSize, bank.Sources[index].Loop)
+	assert.Empty(t, bank.Diagnostics())
+
+	// Resampling to half the rate halves the BRR data.
+	index, err = bank.AddWavSoundEffect(wav, "SFX_JUMP_SMALL", 16000)
+	assert.NoError(t, err)
+	assert.Equal(t, "SFX_JUMP_SMALL", bank.Sources[index].Id)
+	assert.Less(t, len(bank.Sources[index].Data), len(bank.Sources[0].Data)*6/10)
+
+	// A unity note an octave up plays C-5 at half the rate, so the data is stretched to
+	// twice the length.
+	high := &WavSample{Rate: 32000, Data: data, UnityNote: 72}
+	index, err = bank.AddWavSoundEffect(high, "SFX_JUMP_HIGH", 0)
+	assert.NoError(t, err)
+	assert.InDelta(t, len(bank.Sources[0].Data)*2, len(bank.Sources[index].Data), 2*kBrrBlockSize)
+
+	// A short loop is unrolled by the codec to align it, which doesn't change the pitch.
+	short := &WavSample{Rate: 32000, Data: data[:100], Loop: true, LoopEnd: 100, UnityNote: kWavDefaultUnityNote}
+	index, err = bank.AddWavSoundEffect(short, "SFX_HUM", 0)
+	assert.NoError(t, err)
+	assert.True(t, bank.Sources[index].Loops())
+	assert.Equal(t, 1.0, bank.Sources[index].TuningFactor)
+	assert.Empty(t, bank.Diagnostics())
+
+	_, err = bank.AddWavSoundEffect(&WavSample{Rate: 32000}, "SFX_EMPTY", 0)
+	assert.ErrorIs(t, err, ErrEmptySoundEffect)
+}
+
+func TestAddWavSoundEffectFile(t *testing.T) {
+	// A WAV file with a 1000-sample loop in its smpl chunk, which is too long to unroll
+	// and has to be resampled.
+	filename := filepath.Join(t.TempDir(), "hum.wav")
+	pcm := make([]byte, 4000)
+	assert.NoError(t, os.WriteFile(filename, buildWav(1, 1, 32000, 16, pcm, buildSmpl(60, 0, 0, 0, 999)), 0644))
+
+	bank := SoundBank{}
+	assert.NoError(t, bank.AddSoundEffectFile(filename, EffectOptions{}))
+	assert.True(t, bank.Sources[0].Loops())
+	assert.Equal(t, 1000.0/1008, bank.Sources[0].TuningFactor)
+
+	assert.Equal(t, []Diagnostic{{
+		Severity: SeverityWarning,
+		Code:     "effect-tuning",
+		Message:  "SFX_HUM: the loop is resampled to fit the BRR block size, which detunes the effect by -13.8 cents; use a loop length that's a multiple of 16",
+		Location: Location{File: filename, Pattern: -1, Row: -1, Channel: -1},
+	}}, bank.Diagnostics())
+}
@@ -0,0 +1,232 @@
+// SNESMOD
+// (C) 2025 Mukunda Johnson (mukunda.com)
+// Licensed under MIT
+
+package spc
+
+// The S-DSP has 128 registers. Each of the 8 voices has registers at voice*0x10 + $0-$9,
+// and the global registers are in the $C-$F columns.
+// https://snes.nesdev.org/wiki/S-DSP_registers
+
+type DspAddress = uint8
+
+// Voice registers, added to voice*0x10.
+const (
+	DspVolL   DspAddress = 0x00
+	DspVolR   DspAddress = 0x01
+	DspPitchL DspAddress = 0x02
+	DspPitchH DspAddress = 0x03 // 14-bit pitch, 0x1000 plays at 32000 Hz
+	DspSrcn   DspAddress = 0x04 // Source number in the sample directory
+	DspAdsr1  DspAddress = 0x05
+	DspAdsr2  DspAddress = 0x06
+	DspGain   DspAddress = 0x07
+	DspEnvx   DspAddress = 0x08 // Current envelope, read-only
+	DspOutx   DspAddress = 0x09 // Current sample, read-only
+)
+
+// Global registers.
+const (
+	DspMvolL DspAddress = 0x0C
+	DspMvolR DspAddress = 0x1C
+	DspEvolL DspAddress = 0x2C
+	DspEvolR DspAddress = 0x3C
+	DspKon   DspAddress = 0x4C
+	DspKof   DspAddress = 0x5C
+	DspFlg   DspAddress = 0x6C
+	DspEndx  DspAddress = 0x7C
+	DspEfb   DspAddress = 0x0D // Echo feedback
+	DspPmon  DspAddress = 0x2D // Pitch modulation
+	DspNon   DspAddress = 0x3D // Noise
+	DspEon   DspAddress = 0x4D // Echo enable
+	DspDir   DspAddress = 0x5D // Sample directory page
+	DspEsa   DspAddress = 0x6D // Echo buffer page
+	DspEdl   DspAddress = 0x7D // Echo delay, 16ms (2KB) steps
+	DspFir   DspAddress = 0x0F // FIR coefficient n is at n*0x10 + 0x0F
+)
+
+// FLG bits.
+const (
+	DspFlgReset       = 0x80 // Soft reset, keys off all voices
+	DspFlgMute        = 0x40
+	DspFlgEchoDisable = 0x20 // Disables writing to the echo buffer
+	DspFlgNoiseMask   = 0x1F // Noise frequency
+)
+
+// The S-DSP registers in an SPC file.
+type DspRegisters [128]byte
+
+// The registers of one voice.
+type DspVoice struct {
+	VolumeL int8
+	VolumeR int8
+	Pitch   uint16
+	Source  uint8
+	Adsr1   uint8
+	Adsr2   uint8
+	Gain    uint8
+	Envx    uint8 // Read-only, ignored by SetVoice
+	Outx    uint8 // Read-only, ignored by SetVoice
+}
+
+// Read the registers of voice 0-7.
+func (dsp *DspRegisters) Voice(v int) DspVoice {
+	r := dsp[v*0x10 : v*0x10+0x10]
+	return DspVoice{
+		VolumeL: int8(r[DspVolL]),
+		VolumeR: int8(r[DspVolR]),
+		Pitch:   uint16(r[DspPitchL]) | uint16(r[DspPitchH])<<8,
+		Source:  r[DspSrcn],
+		Adsr1:   r[DspAdsr1],
+		Adsr2:   r[DspAdsr2],
+		Gain:    r[DspGain],
+		Envx:    r[DspEnvx],
+		Outx:    r[DspOutx],
+	}
+}
+
+// Write the registers of voice 0-7. The pitch is limited to 14 bits.
+func (dsp *DspRegisters) SetVoice(v int, voice DspVoice) {
+	r := dsp[v*0x10 : v*0x10+0x10]
+	r[DspVolL] = byte(voice.VolumeL)
+	r[DspVolR] = byte(voice.VolumeR)
+	r[DspPitchL] = byte(voice.Pitch)
+	r[DspPitchH] = byte(voice.Pitch>>8) & 0x3F
+	r[DspSrcn] = voice.Source
+	r[DspAdsr1] = voice.Adsr1
+	r[DspAdsr2] = voice.Adsr2
+	r[DspGain] = voice.Gain
+}
+
+func (dsp *DspRegisters) MainVolume() (int8, int8) {
+	return int8(dsp[DspMvolL]), int8(dsp[DspMvolR])
+}
+
+func (dsp *DspRegisters) SetMainVolume(left, right int8) {
+	dsp[DspMvolL], dsp[DspMvolR] = byte(left), byte(right)
+}
+
+func (dsp *DspRegisters) EchoVolume() (int8, int8) {
+	return int8(dsp[DspEvolL]), int8(dsp[DspEvolR])
+}
+
+func (dsp *DspRegisters) SetEchoVolume(left, right int8) {
+	dsp[DspEvolL], dsp[DspEvolR] = byte(left), byte(right)
+}
+
+func (dsp *DspRegisters) EchoFeedback() int8 {
+	return int8(dsp[DspEfb])
+}
+
+func (dsp *DspRegisters) SetEchoFeedback(feedback int8) {
+	dsp[DspEfb] = byte(feedback)
+}
+
+// The FLG register. See DspFlgReset etc.
+func (dsp *DspRegisters) Flags() uint8 {
+	return dsp[DspFlg]
+}
+
+func (dsp *DspRegisters) SetFlags(flags uint8) {
+	dsp[DspFlg] = flags
+}
+
+// Voices being keyed on, one bit each.
+func (dsp *DspRegisters) KeyOn() uint8 {
+	return dsp[DspKon]
+}
+
+func (dsp *DspRegisters) SetKeyOn(voices uint8) {
+	dsp[DspKon] = voices
+}
+
+// Voices being keyed off, one bit each.
+func (dsp *DspRegisters) KeyOff() uint8 {
+	return dsp[DspKof]
+}
+
+func (dsp *DspRegisters) SetKeyOff(voices uint8) {
+	dsp[DspKof] = voices
+}
+
+// Voices that reached the end of their sample, one bit each.
+func (dsp *DspRegisters) EndedVoices() uint8 {
+	return dsp[DspEndx]
+}
+
+// Voices modulated by the previous voice's output, one bit each.
+func (dsp *DspRegisters) PitchModulation() uint8 {
+	return dsp[DspPmon]
+}
+
+func (dsp *DspRegisters) SetPitchModulation(voices uint8) {
+	dsp[DspPmon] = voices
+}
+
+// Voices that play noise instead of their sample, one bit each.
+func (dsp *DspRegisters) NoiseVoices() uint8 {
+	return dsp[DspNon]
+}
+
+func (dsp *DspRegisters) SetNoiseVoices(voices uint8) {
+	dsp[DspNon] = voices
+}
+
+// Voices sent to the echo buffer, one bit each.
+func (dsp *DspRegisters) EchoVoices() uint8 {
+	return dsp[DspEon]
+}
+
+func (dsp *DspRegisters) SetEchoVoices(voices uint8) {
+	dsp[DspEon] = voices
+}
+
+// Address of the sample directory. It's always page-aligned.
+func (dsp *DspRegisters) SampleDirectory() uint16 {
+	return uint16(dsp[DspDir]) << 8
+}
+
+func (dsp *DspRegisters) SetSampleDirectory(address uint16) {
+	dsp[DspDir] = byte(address >> 8)
+}
+
+// Address of the echo buffer. It's always page-aligned.
+func (dsp *DspRegisters) EchoStart() uint16 {
+	return uint16(dsp[DspEsa]) << 8
+}
+
+func (dsp *DspRegisters) SetEchoStart(address uint16) {
+	dsp[DspEsa] = byte(address >> 8)
+}
+
+// Echo delay, 0-15, in 16ms steps.
+func (dsp *DspRegisters) EchoDelay() uint8 {
+	return dsp[DspEdl] & 0x0F
+}
+
+func (dsp *DspRegisters) SetEchoDelay(delay uint8) {
+	dsp[DspEdl] = delay & 0x0F
+}
+
+// Size of the echo buffer in bytes. A delay of 0 still uses 4 bytes.
+func (dsp *DspRegisters) EchoBufferSize() int {
+	if delay := dsp.EchoDelay(); delay != 0 {
+		return int(delay) * 2048
+	}
+	return 4
+}
+
+// Echo FIR filter coefficient 0-7.
+func (dsp *DspRegisters) Fir(n int) int8 {
+	return int8(dsp[n*0x10+int(DspFir)])
+}
+
+func (dsp *DspRegisters) SetFir(n int, coefficient int8) {
+	dsp[n*0x10+int(DspFir)] = byte(coefficient)
+}
+
+// Set the registers to their state after power on: all voices off, muted, and the echo
+// buffer disabled so it doesn't overwrite memory before it's set up.
+func (dsp *DspRegisters) Reset() {
+	*dsp = DspRegisters{}
+	dsp.SetFlags(DspFlgReset | DspFlgMute | DspFlgEchoDisable)
+}
@@ -0,0 +1,94 @@
+// SNESMOD
+// (C) 2025 Mukunda Johnson (mukunda.com)
+// Licensed under MIT
+
+package spc
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDspVoice(t *testing.T) {
+	dsp := DspRegisters{}
+	voice := DspVoice{VolumeL: 127, VolumeR: -128, Pitch: 0x1000, Source: 3, Adsr1: 0x8F, Adsr2: 0xE0, Gain: 0x7F}
+	dsp.SetVoice(5, voice)
+	assert.Equal(t, voice, dsp.Voice(5))
+	assert.EqualValues(t, 0x80, dsp[0x51])
+	assert.EqualValues(t, 0x10, dsp[0x53])
+	assert.Equal(t, DspVoice{}, dsp.Voice(4))
+
+	// Only 14 bits of pitch.
+	dsp.SetVoice(0, DspVoice{Pitch: 0xFFFF})
+	assert.EqualValues(t, 0x3FFF, dsp.Voice(0).Pitch)
+
+	// ENVX and OUTX are read-only.
+	dsp[0x78], dsp[0x79] = 0x40, 0x12
+	dsp.SetVoice(7, DspVoice{})
+	assert.EqualValues(t, 0x40, dsp.Voice(7).Envx)
+	assert.EqualValues(t, 0x12, dsp.Voice(7).Outx)
+}
+
+func TestDspGlobals(t *testing.T) {
+	dsp := DspRegisters{}
+	dsp.SetMainVolume(100, -100)
+	dsp.SetEchoVolume(-1, 50)
+	dsp.SetEchoFeedback(-64)
+	dsp.SetKeyOn(0x81)
+	dsp.SetKeyOff(0x02)
+	dsp.SetPitchModulation(0x04)
+	dsp.SetNoiseVoices(0x08)
+	dsp.SetEchoVoices(0xF0)
+	dsp.SetSampleDirectory(0x0200)
+	dsp.SetEchoStart(0xD800)
+	dsp.SetEchoDelay(5)
+	for i := range 8 {
+		dsp.SetFir(i, int8(i*16-64))
+	}
+
+	expected := map[DspAddress]byte{
+		0x0C: 100, 0x1C: 0x9C, 0x2C: 0xFF, 0x3C: 50, 0x0D: 0xC0, 0x4C: 0x81, 0x5C: 0x02,
+		0x2D: 0x04, 0x3D: 0x08, 0x4D: 0xF0, 0x5D: 0x02, 0x6D: 0xD8, 0x7D: 5,
+		0x0F: 0xC0, 0x1F: 0xD0, 0x7F: 0x30,
+	}
+	for address, value := range expected {
+		assert.Equal(t, value, dsp[address], "register %02X", address)
+	}
+
+	left, right := dsp.MainVolume()
+	assert.Equal(t, []int8{100, -100}, []int8{left, right})
+	left, right = dsp.EchoVolume()
+	assert.Equal(t, []int8{-1, 50}, []int8{left, right})
+	assert.EqualValues(t, -64, dsp.EchoFeedback())
+	assert.EqualValues(t, 0x0200, dsp.SampleDirectory())
+	assert.EqualValues(t, 0xD800, dsp.EchoStart())
+	assert.Equal(t, 5*2048, dsp.EchoBufferSize())
+	assert.EqualValues(t, 48, dsp.Fir(7))
+
+	dsp.SetEchoDelay(0)
+	assert.Equal(t, 4, dsp.EchoBufferSize())
+
+	dsp.Reset()
+	assert.EqualValues(t, 0xE0, dsp.Flags())
+	assert.Zero(t, dsp.KeyOn())
+	assert.Zero(t, dsp.EchoStart())
+}
+
+func TestBootState(t *testing.T) {
+	spcf := NewSpcFile()
+	assert.Equal(t, IplRomData[:], spcf.Memory[0xFFC0:])
+	assert.Equal(t, IplRomData, spcf.IplRom)
+	assert.EqualValues(t, 0x80, spcf.Memory[0xF1])
+	assert.EqualValues(t, 0xE0, spcf.DspRegisters.Flags())
+
+	// The IPL ROM ends with the reset vector pointing to its start.
+	assert.EqualValues(t, 0xFFC0, uint16(spcf.Memory[0xFFFE])|uint16(spcf.Memory[0xFFFF])<<8)
+
+	// The registers and the ROM are in the file.
+	buf := &bytes.Buffer{}
+	assert.NoError(t, spcf.Write(buf))
+	assert.Equal(t, IplRomData[:], buf.Bytes()[0x101C0:0x10200])
+	assert.EqualValues(t, 0xE0, buf.Bytes()[0x10100+0x6C])
+}
@@ -0,0 +1,686 @@
+// SNESMOD
+// (C) 2025 Mukunda Johnson (mukunda.com)
+// Licensed under MIT
+
+package emu
+
+// This file emulates the SPC700 instruction set. Each instruction runs at once and takes
+// the cycle count from the table below. Dummy reads aren't emulated.
+
+// PSW flags.
+const (
+	kFlagC = 0x01
+	kFlagZ = 0x02
+	kFlagI = 0x04
+	kFlagH = 0x08
+	kFlagB = 0x10
+	kFlagP = 0x20 // Direct page is $100
+	kFlagV = 0x40
+	kFlagN = 0x80
+)
+
+// Cycles for each opcode. Taken branches add 2.
+var cycleTable = [256]uint8{
+	//0 1  2  3  4  5  6  7  8  9  A  B  C  D  E   F
+	2, 8, 4, 5, 3, 4, 3, 6, 2, 6, 5, 4, 5, 4, 6, 8, // 0
+	2, 8, 4, 5, 4, 5, 5, 6, 5, 5, 6, 5, 2, 2, 4, 6, // 1
+	2, 8, 4, 5, 3, 4, 3, 6, 2, 6, 5, 4, 5, 4, 5, 4, // 2
+	2, 8, 4, 5, 4, 5, 5, 6, 5, 5, 6, 5, 2, 2, 3, 8, // 3
+	2, 8, 4, 5, 3, 4, 3, 6, 2, 6, 4, 4, 5, 4, 6, 6, // 4
+	2, 8, 4, 5, 4, 5, 5, 6, 5, 5, 4, 5, 2, 2, 4, 3, // 5
+	2, 8, 4, 5, 3, 4, 3, 6, 2, 6, 4, 4, 5, 4, 5, 5, // 6
+	2, 8, 4, 5, 4, 5, 5, 6, 5, 5, 5, 5, 2, 2, 3, 6, // 7
+	2, 8, 4, 5, 3, 4, 3, 6, 2, 6, 5, 4, 5, 2, 4, 5, // 8
+	2, 8, 4, 5, 4, 5, 5, 6, 5, 5, 5, 5, 2, 2, 12, 5, // 9
+	3, 8, 4, 5, 3, 4, 3, 6, 2, 6, 4, 4, 5, 2, 4, 4, // A
+	2, 8, 4, 5, 4, 5, 5, 6, 5, 5, 5, 5, 2, 2, 3, 4, // B
+	3, 8, 4, 5, 4, 5, 4, 7, 2, 5, 6, 4, 5, 2, 4, 9, // C
+	2, 8, 4, 5, 5, 6, 6, 7, 4, 5, 5, 5, 2, 2, 6, 3, // D
+	2, 8, 4, 5, 3, 4, 3, 6, 2, 4, 5, 3, 4, 3, 4, 3, // E
+	2, 8, 4, 5, 4, 5, 5, 6, 3, 4, 5, 4, 2, 2, 4, 3, // F
+}
+
+type cpu struct {
+	a, x, y uint8
+	sp      uint8
+	psw     uint8
+	pc      uint16
+
+	// Set by SLEEP and STOP. Only a reset wakes the CPU, so it stays stopped.
+	stopped bool
+}
+
+// Fetch the next byte of the instruction.
+func (e *Emulator) fetch() uint8 {
+	value := e.read(e.cpu.pc)
+	e.cpu.pc++
+	return value
+}
+
+func (e *Emulator) fetch16() uint16 {
+	lo := e.fetch()
+	return uint16(lo) | uint16(e.fetch())<<8
+}
+
+func (e *Emulator) read16(addr uint16) uint16 {
+	return uint16(e.read(addr)) | uint16(e.read(addr+1))<<8
+}
+
+// Address in the direct page.
+func (e *Emulator) dp(offset uint8) uint16 {
+	if e.cpu.psw&kFlagP != 0 {
+		return 0x100 + uint16(offset)
+	}
+	return uint16(offset)
+}
+
+// Read a 16-bit value from the direct page. The high byte wraps within the page.
+func (e *Emulator) readDp16(offset uint8) uint16 {
+	return uint16(e.read(e.dp(offset))) | uint16(e.read(e.dp(offset+1)))<<8
+}
+
+func (e *Emulator) writeDp16(offset uint8, value uint16) {
+	e.write(e.dp(offset), uint8(value))
+	e.write(e.dp(offset+1), uint8(value>>8))
+}
+
+func (e *Emulator) push(value uint8) {
+	e.write(0x100+uint16(e.cpu.sp), value)
+	e.cpu.sp--
+}
+
+func (e *Emulator) pop() uint8 {
+	e.cpu.sp++
+	return e.read(0x100 + uint16(e.cpu.sp))
+}
+
+func (e *Emulator) push16(value uint16) {
+	e.push(uint8(value >> 8))
+	e.push(uint8(value))
+}
+
+func (e *Emulator) pop16() uint16 {
+	lo := e.pop()
+	return uint16(lo) | uint16(e.pop())<<8
+}
+
+func (e *Emulator) setFlag(flag uint8, set bool) {
+	if set {
+		e.cpu.psw |= flag
+	} else {
+		e.cpu.psw &^= flag
+	}
+}
+
+func (e *Emulator) setNZ(value uint8) {
+	e.setFlag(kFlagN, value&0x80 != 0)
+	e.setFlag(kFlagZ, value == 0)
+}
+
+func (e *Emulator) setNZ16(value uint16) {
+	e.setFlag(kFlagN, value&0x8000 != 0)
+	e.setFlag(kFlagZ, value == 0)
+}
+
+func (e *Emulator) carry() uint8 {
+	return e.cpu.psw & kFlagC
+}
+
+func (e *Emulator) adc(a, b uint8) uint8 {
+	sum := int(a) + int(b) + int(e.carry())
+	result := uint8(sum)
+	e.setFlag(kFlagC, sum > 0xFF)
+	e.setFlag(kFlagV, ^(a^b)&(a^result)&0x80 != 0)
+	e.setFlag(kFlagH, (a^b^result)&0x10 != 0)
+	e.setNZ(result)
+	return result
+}
+
+func (e *Emulator) compare(a, b uint8) {
+	e.setFlag(kFlagC, a >= b)
+	e.setNZ(a - b)
+}
+
+// The OR, AND, EOR, CMP, ADC, and SBC operations, selected by the top 3 bits of the
+// opcode. CMP returns `a` unchanged.
+func (e *Emulator) alu(op uint8, a, b uint8) uint8 {
+	var result uint8
+	switch op >> 5 {
+	case 0:
+		result = a | b
+	case 1:
+		result = a & b
+	case 2:
+		result = a ^ b
+	case 3:
+		e.compare(a, b)
+		return a
+	case 4:
+		return e.adc(a, b)
+	case 5:
+		return e.adc(a, ^b)
+	}
+	e.setNZ(result)
+	return result
+}
+
+// The ASL, ROL, LSR, and ROR operations, selected by the top 3 bits of the opcode.
+func (e *Emulator) shift(op uint8, value uint8) uint8 {
+	var result uint8
+	switch op >> 5 {
+	case 0: // ASL
+		result = value << 1
+		e.setFlag(kFlagC, value&0x80 != 0)
+	case 1: // ROL
+		result = value<<1 | e.carry()
+		e.setFlag(kFlagC, value&0x80 != 0)
+	case 2: // LSR
+		result = value >> 1
+		e.setFlag(kFlagC, value&1 != 0)
+	case 3: // ROR
+		result = value>>1 | e.carry()<<7
+		e.setFlag(kFlagC, value&1 != 0)
+	}
+	e.setNZ(result)
+	return result
+}
+
+// 16-bit add for ADDW and SUBW. The flags are set like two 8-bit ADCs.
+func (e *Emulator) addw(a, b uint16, carry int) uint16 {
+	sum := int(a) + int(b) + carry
+	result := uint16(sum)
+	e.setFlag(kFlagC, sum > 0xFFFF)
+	e.setFlag(kFlagV, ^(a^b)&(a^result)&0x8000 != 0)
+	e.setFlag(kFlagH, (a^b^result)&0x1000 != 0)
+	e.setNZ16(result)
+	return result
+}
+
+func (e *Emulator) ya() uint16 {
+	return uint16(e.cpu.y)<<8 | uint16(e.cpu.a)
+}
+
+func (e *Emulator) setYa(value uint16) {
+	e.cpu.a, e.cpu.y = uint8(value), uint8(value>>8)
+}
+
+// Branch if the condition is true. Returns the extra cycles.
+func (e *Emulator) branch(condition bool) int {
+	offset := int8(e.fetch())
+	if !condition {
+		return 0
+	}
+	e.cpu.pc += uint16(offset)
+	return 2
+}
+
+// Operand for the absolute bit instructions: a 13-bit address and a bit number.
+func (e *Emulator) memBit() (uint16, uint8) {
+	operand := e.fetch16()
+	return operand & 0x1FFF, uint8(operand >> 13)
+}
+
+// Address of the operand for the ALU instructions in columns 4-7. Odd rows use the
+// indexed forms.
+func (e *Emulator) aluAddress(op uint8) uint16 {
+	odd := op&0x10 != 0
+	switch op & 0x0F {
+	case 0x04:
+		if odd {
+			return e.dp(e.fetch() + e.cpu.x)
+		}
+		return e.dp(e.fetch())
+	case 0x05:
+		if odd {
+			return e.fetch16() + uint16(e.cpu.x)
+		}
+		return e.fetch16()
+	case 0x06:
+		if odd {
+			return e.fetch16() + uint16(e.cpu.y)
+		}
+		return e.dp(e.cpu.x)
+	default: // 0x07
+		if odd {
+			return e.readDp16(e.fetch()) + uint16(e.cpu.y)
+		}
+		return e.readDp16(e.fetch() + e.cpu.x)
+	}
+}
+
+// Run one instruction and return the cycles it took.
+func (e *Emulator) step() int {
+	c := &e.cpu
+	op := e.fetch()
+	cycles := int(cycleTable[op])
+
+	row, column := op>>4, op&0x0F
+
+	// OR, AND, EOR, CMP, ADC, and SBC in rows 0-B.
+	if row < 0xC && column >= 0x04 && column <= 0x09 {
+		switch {
+		case column <= 0x07:
+			c.a = e.alu(op, c.a, e.read(e.aluAddress(op)))
+		case column == 0x08 && row&1 == 0: // A, #imm
+			c.a = e.alu(op, c.a, e.fetch())
+		case column == 0x08: // dp, #imm
+			value := e.fetch()
+			addr := e.dp(e.fetch())
+			e.writeAlu(op, addr, value)
+		case row&1 == 0: // dp, dp
+			value := e.read(e.dp(e.fetch()))
+			addr := e.dp(e.fetch())
+			e.writeAlu(op, addr, value)
+		default: // (X), (Y)
+			value := e.read(e.dp(c.y))
+			e.writeAlu(op, e.dp(c.x), value)
+		}
+		return cycles
+	}
+
+	switch column {
+	case 0x01: // TCALL n
+		e.push16(c.pc)
+		c.pc = e.read16(0xFFDE - uint16(row)*2)
+		return cycles
+
+	case 0x02: // SET1/CLR1 dp.bit
+		addr := e.dp(e.fetch())
+		bit := uint8(1) << (row >> 1)
+		if row&1 == 0 {
+			e.write(addr, e.read(addr)|bit)
+		} else {
+			e.write(addr, e.read(addr)&^bit)
+		}
+		return cycles
+
+	case 0x03: // BBS/BBC dp.bit, rel
+		value := e.read(e.dp(e.fetch()))
+		set := value&(1<<(row>>1)) != 0
+		return cycles + e.branch(set == (row&1 == 0))
+	}
+
+	// Branches in column 0.
+	if column == 0x00 && row&1 == 1 {
+		flags := [4]uint8{kFlagN, kFlagV, kFlagC, kFlagZ}
+		flag := flags[row>>2]
+		set := c.psw&flag != 0
+		return cycles + e.branch(set == (row&2 != 0))
+	}
+
+	// Shifts, INC, and DEC on memory and A.
+	if row < 0xC && (column == 0x0B || column == 0x0C) {
+		var addr uint16
+		switch {
+		case column == 0x0C && row&1 == 1:
+			c.a = e.shiftOrCount(op, c.a)
+			return cycles
+		case column == 0x0C:
+			addr = e.fetch16()
+		case row&1 == 1:
+			addr = e.dp(e.fetch() + c.x)
+		default:
+			addr = e.dp(e.fetch())
+		}
+		e.write(addr, e.shiftOrCount(op, e.read(addr)))
+		return cycles
+	}
+
+	switch op {
+	case 0x00: // NOP
+	case 0x20: // CLRP
+		c.psw &^= kFlagP
+	case 0x40: // SETP
+		c.psw |= kFlagP
+	case 0x60: // CLRC
+		c.psw &^= kFlagC
+	case 0x80: // SETC
+		c.psw |= kFlagC
+	case 0xA0: // EI
+		c.psw |= kFlagI
+	case 0xC0: // DI
+		c.psw &^= kFlagI
+	case 0xE0: // CLRV
+		c.psw &^= kFlagV | kFlagH
+	case 0xED: // NOTC
+		c.psw ^= kFlagC
+
+	// MOV to memory.
+	case 0xC4:
+		e.write(e.dp(e.fetch()), c.a)
+	case 0xD4:
+		e.write(e.dp(e.fetch()+c.x), c.a)
+	case 0xC5:
+		e.write(e.fetch16(), c.a)
+	case 0xD5:
+		e.write(e.fetch16()+uint16(c.x), c.a)
+	case 0xC6:
+		e.write(e.dp(c.x), c.a)
+	case 0xD6:
+		e.write(e.fetch16()+uint16(c.y), c.a)
+	case 0xC7:
+		e.write(e.readDp16(e.fetch()+c.x), c.a)
+	case 0xD7:
+		e.write(e.readDp16(e.fetch())+uint16(c.y), c.a)
+	case 0xAF: // MOV (X)+, A
+		e.write(e.dp(c.x), c.a)
+		c.x++
+	case 0xD8:
+		e.write(e.dp(e.fetch()), c.x)
+	case 0xD9:
+		e.write(e.dp(e.fetch()+c.y), c.x)
+	case 0xC9:
+		e.write(e.fetch16(), c.x)
+	case 0xCB:
+		e.write(e.dp(e.fetch()), c.y)
+	case 0xDB:
+		e.write(e.dp(e.fetch()+c.x), c.y)
+	case 0xCC:
+		e.write(e.fetch16(), c.y)
+	case 0xFA: // MOV dp, dp
+		value := e.read(e.dp(e.fetch()))
+		e.write(e.dp(e.fetch()), value)
+	case 0x8F: // MOV dp, #imm
+		value := e.fetch()
+		e.write(e.dp(e.fetch()), value)
+
+	// MOV to registers.
+	case 0xE8:
+		c.a = e.fetch()
+		e.setNZ(c.a)
+	case 0xE4:
+		c.a = e.read(e.dp(e.fetch()))
+		e.setNZ(c.a)
+	case 0xF4:
+		c.a = e.read(e.dp(e.fetch() + c.x))
+		e.setNZ(c.a)
+	case 0xE5:
+		c.a = e.read(e.fetch16())
+		e.setNZ(c.a)
+	case 0xF5:
+		c.a = e.read(e.fetch16() + uint16(c.x))
+		e.setNZ(c.a)
+	case 0xE6:
+		c.a = e.read(e.dp(c.x))
+		e.setNZ(c.a)
+	case 0xF6:
+		c.a = e.read(e.fetch16() + uint16(c.y))
+		e.setNZ(c.a)
+	case 0xE7:
+		c.a = e.read(e.readDp16(e.fetch() + c.x))
+		e.setNZ(c.a)
+	case 0xF7:
+		c.a = e.read(e.readDp16(e.fetch()) + uint16(c.y))
+		e.setNZ(c.a)
+	case 0xBF: // MOV A, (X)+
+		c.a = e.read(e.dp(c.x))
+		c.x++
+		e.setNZ(c.a)
+	case 0xCD:
+		c.x = e.fetch()
+		e.setNZ(c.x)
+	case 0xF8:
+		c.x = e.read(e.dp(e.fetch()))
+		e.setNZ(c.x)
+	case 0xF9:
+		c.x = e.read(e.dp(e.fetch() + c.y))
+		e.setNZ(c.x)
+	case 0xE9:
+		c.x = e.read(e.fetch16())
+		e.setNZ(c.x)
+	case 0x8D:
+		c.y = e.fetch()
+		e.setNZ(c.y)
+	case 0xEB:
+		c.y = e.read(e.dp(e.fetch()))
+		e.setNZ(c.y)
+	case 0xFB:
+		c.y = e.read(e.dp(e.fetch() + c.x))
+		e.setNZ(c.y)
+	case 0xEC:
+		c.y = e.read(e.fetch16())
+		e.setNZ(c.y)
+	case 0x7D: // MOV A, X
+		c.a = c.x
+		e.setNZ(c.a)
+	case 0xDD: // MOV A, Y
+		c.a = c.y
+		e.setNZ(c.a)
+	case 0x5D: // MOV X, A
+		c.x = c.a
+		e.setNZ(c.x)
+	case 0xFD: // MOV Y, A
+		c.y = c.a
+		e.setNZ(c.y)
+	case 0x9D: // MOV X, SP
+		c.x = c.sp
+		e.setNZ(c.x)
+	case 0xBD: // MOV SP, X
+		c.sp = c.x
+
+	// Compare X and Y.
+	case 0xC8:
+		e.compare(c.x, e.fetch())
+	case 0x3E:
+		e.compare(c.x, e.read(e.dp(e.fetch())))
+	case 0x1E:
+		e.compare(c.x, e.read(e.fetch16()))
+	case 0xAD:
+		e.compare(c.y, e.fetch())
+	case 0x7E:
+		e.compare(c.y, e.read(e.dp(e.fetch())))
+	case 0x5E:
+		e.compare(c.y, e.read(e.fetch16()))
+
+	// Register increments.
+	case 0x1D:
+		c.x--
+		e.setNZ(c.x)
+	case 0x3D:
+		c.x++
+		e.setNZ(c.x)
+	case 0xDC:
+		c.y--
+		e.setNZ(c.y)
+	case 0xFC:
+		c.y++
+		e.setNZ(c.y)
+
+	// 16-bit operations.
+	case 0x1A, 0x3A: // DECW, INCW
+		offset := e.fetch()
+		value := e.readDp16(offset)
+		if op == 0x1A {
+			value--
+		} else {
+			value++
+		}
+		e.writeDp16(offset, value)
+		e.setNZ16(value)
+	case 0x5A: // CMPW YA, dp
+		value := e.readDp16(e.fetch())
+		e.setFlag(kFlagC, e.ya() >= value)
+		e.setNZ16(e.ya() - value)
+	case 0x7A: // ADDW YA, dp
+		e.setYa(e.addw(e.ya(), e.readDp16(e.fetch()), 0))
+	case 0x9A: // SUBW YA, dp
+		e.setYa(e.addw(e.ya(), ^e.readDp16(e.fetch()), 1))
+	case 0xBA: // MOVW YA, dp
+		e.setYa(e.readDp16(e.fetch()))
+		e.setNZ16(e.ya())
+	case 0xDA: // MOVW dp, YA
+		e.writeDp16(e.fetch(), e.ya())
+
+	case 0xCF: // MUL YA
+		e.setYa(uint16(c.y) * uint16(c.a))
+		e.setNZ(c.y)
+	case 0x9E: // DIV YA, X
+		e.div()
+	case 0xDF: // DAA
+		if c.a > 0x99 || c.psw&kFlagC != 0 {
+			c.a += 0x60
+			c.psw |= kFlagC
+		}
+		if c.a&0x0F > 9 || c.psw&kFlagH != 0 {
+			c.a += 0x06
+		}
+		e.setNZ(c.a)
+	case 0xBE: // DAS
+		if c.a > 0x99 || c.psw&kFlagC == 0 {
+			c.a -= 0x60
+			c.psw &^= kFlagC
+		}
+		if c.a&0x0F > 9 || c.psw&kFlagH == 0 {
+			c.a -= 0x06
+		}
+		e.setNZ(c.a)
+	case 0x9F: // XCN
+		c.a = c.a>>4 | c.a<<4
+		e.setNZ(c.a)
+
+	// Bit operations on absolute addresses.
+	case 0x0A, 0x2A, 0x4A, 0x6A, 0x8A, 0xAA: // OR1, AND1, EOR1, MOV1 C
+		addr, bit := e.memBit()
+		value := e.read(addr)>>bit&1 != 0
+		if op == 0x2A || op == 0x6A {
+			value = !value
+		}
+		carry := c.psw&kFlagC != 0
+		switch op {
+		case 0x0A, 0x2A:
+			carry = carry || value
+		case 0x4A, 0x6A:
+			carry = carry && value
+		case 0x8A:
+			carry = carry != value
+		case 0xAA:
+			carry = value
+		}
+		e.setFlag(kFlagC, carry)
+	case 0xCA: // MOV1 mem.bit, C
+		addr, bit := e.memBit()
+		value := e.read(addr) &^ (1 << bit)
+		e.write(addr, value|e.carry()<<bit)
+	case 0xEA: // NOT1 mem.bit
+		addr, bit := e.memBit()
+		e.write(addr, e.read(addr)^(1<<bit))
+	case 0x0E, 0x4E: // TSET1, TCLR1
+		addr := e.fetch16()
+		value := e.read(addr)
+		e.setNZ(c.a - value)
+		if op == 0x0E {
+			e.write(addr, value|c.a)
+		} else {
+			e.write(addr, value&^c.a)
+		}
+
+	// Stack.
+	case 0x0D:
+		e.push(c.psw)
+	case 0x2D:
+		e.push(c.a)
+	case 0x4D:
+		e.push(c.x)
+	case 0x6D:
+		e.push(c.y)
+	case 0x8E:
+		c.psw = e.pop()
+	case 0xAE:
+		c.a = e.pop()
+	case 0xCE:
+		c.x = e.pop()
+	case 0xEE:
+		c.y = e.pop()
+
+	// Jumps and branches.
+	case 0x2F: // BRA
+		cycles += e.branch(true) - 2
+	case 0x2E: // CBNE dp, rel
+		value := e.read(e.dp(e.fetch()))
+		cycles += e.branch(c.a != value)
+	case 0xDE: // CBNE dp+X, rel
+		value := e.read(e.dp(e.fetch() + c.x))
+		cycles += e.branch(c.a != value)
+	case 0x6E: // DBNZ dp, rel
+		addr := e.dp(e.fetch())
+		value := e.read(addr) - 1
+		e.write(addr, value)
+		cycles += e.branch(value != 0)
+	case 0xFE: // DBNZ Y, rel
+		c.y--
+		cycles += e.branch(c.y != 0)
+	case 0x5F: // JMP !abs
+		c.pc = e.fetch16()
+	case 0x1F: // JMP [!abs+X]
+		c.pc = e.read16(e.fetch16() + uint16(c.x))
+	case 0x3F: // CALL !abs
+		addr := e.fetch16()
+		e.push16(c.pc)
+		c.pc = addr
+	case 0x4F: // PCALL up
+		addr := 0xFF00 + uint16(e.fetch())
+		e.push16(c.pc)
+		c.pc = addr
+	case 0x0F: // BRK
+		e.push16(c.pc)
+		e.push(c.psw)
+		c.psw = c.psw&^kFlagI | kFlagB
+		c.pc = e.read16(0xFFDE)
+	case 0x6F: // RET
+		c.pc = e.pop16()
+	case 0x7F: // RETI
+		c.psw = e.pop()
+		c.pc = e.pop16()
+
+	case 0xEF, 0xFF: // SLEEP, STOP
+		c.stopped = true
+		c.pc--
+	}
+
+	return cycles
+}
+
+// Write the result of an ALU operation to memory, except for CMP.
+func (e *Emulator) writeAlu(op uint8, addr uint16, value uint8) {
+	result := e.alu(op, e.read(addr), value)
+	if op>>5 != 3 {
+		e.write(addr, result)
+	}
+}
+
+// Shifts in rows 0-7 and DEC/INC in rows 8-B of columns B and C.
+func (e *Emulator) shiftOrCount(op uint8, value uint8) uint8 {
+	switch op >> 5 {
+	case 4: // DEC
+		value--
+	case 5: // INC
+		value++
+	default:
+		return e.shift(op, value)
+	}
+	e.setNZ(value)
+	return value
+}
+
+// DIV YA, X. The quotient is only correct if it fits in 9 bits, and overflows are
+// emulated like the hardware's algorithm.
+func (e *Emulator) div() {
+	c := &e.cpu
+	ya := int(e.ya())
+	x := int(c.x)
+
+	e.setFlag(kFlagH, c.y&0x0F >= c.x&0x0F)
+	e.setFlag(kFlagV, int(c.y) >= x)
+
+	var quotient, remainder int
+	if int(c.y) < x*2 {
+		quotient = ya / x
+		remainder = ya - quotient*x
+	} else {
+		quotient = 255 - (ya-x*0x200)/(256-x)
+		remainder = x + (ya-x*0x200)%(256-x)
+	}
+
+	c.a, c.y = uint8(quotient), uint8(remainder)
+	e.setNZ(c.a)
+}
@@ -0,0 +1,126 @@
+// SNESMOD
+// (C) 2025 Mukunda Johnson (mukunda.com)
+// Licensed under MIT
+
+package emu
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mukunda.com/snesmod/smconv/spc"
+)
+
+const kTestProgram = 0x0400
+
+// Run a program at $0400 until it stops. It must end with STOP ($FF).
+func runProgram(t *testing.T, program []byte) *Emulator {
+	spcf := spc.NewSpcFile()
+	spcf.Header.PC = kTestProgram
+	spcf.Header.SP = 0xEF
+	copy(spcf.Memory[kTestProgram:], program)
+
+	e := New(spcf)
+	for range 1000 {
+		if e.Stopped() {
+			return e
+		}
+		e.Render(make([]int16, 64))
+	}
+	assert.Fail(t, "program didn't stop")
+	return e
+}
+
+func TestCpuArithmetic(t *testing.T) {
+	e := runProgram(t, []byte{
+		0xE8, 0x7F, // mov a, #$7F
+		0x60,       // clrc
+		0x88, 0x01, // adc a, #1      ; $80, V set
+		0xC4, 0x10, // mov $10, a
+		0x0D,       // push psw
+		0xAE,       // pop a
+		0xC4, 0x11, // mov $11, a
+		0x80,       // setc
+		0xA8, 0x90, // sbc a, #$90    ; psw - $90
+		0xC4, 0x12, // mov $12, a
+		0x8F, 0x05, 0x20, // mov $20, #5
+		0x8F, 0x03, 0x21, // mov $21, #3
+		0x60,             // clrc
+		0x89, 0x20, 0x21, // adc $21, $20
+		0xE4, 0x21, // mov a, $21
+		0xC4, 0x13, // mov $13, a
+		0xE8, 0x0C, // mov a, #12
+		0x8D, 0x0A, // mov y, #10
+		0xCF,       // mul ya         ; 120
+		0xCB, 0x14, // mov $14, y
+		0xC4, 0x15, // mov $15, a
+		0xCD, 0x07, // mov x, #7
+		0x9E,       // div ya, x      ; 120 / 7
+		0xC4, 0x16, // mov $16, a
+		0xCB, 0x17, // mov $17, y
+		0xFF, // stop
+	})
+
+	ram := e.Ram()
+	assert.EqualValues(t, 0x80, ram[0x10])
+	assert.EqualValues(t, kFlagN|kFlagV|kFlagH, ram[0x11])
+	assert.EqualValues(t, 0xC8-0x90, ram[0x12])
+	assert.EqualValues(t, 8, ram[0x13])
+	assert.EqualValues(t, 0, ram[0x14])
+	assert.EqualValues(t, 120, ram[0x15])
+	assert.EqualValues(t, 17, ram[0x16])
+	assert.EqualValues(t, 1, ram[0x17])
+}
+
+func TestCpu16Bit(t *testing.T) {
+	e := runProgram(t, []byte{
+		0x8F, 0xFF, 0x20, // mov $20, #$FF
+		0x8F, 0x00, 0x21, // mov $21, #$00
+		0x3A, 0x20, // incw $20        ; $0100
+		0xBA, 0x20, // movw ya, $20
+		0x7A, 0x20, // addw ya, $20    ; $0200
+		0xDA, 0x22, // movw $22, ya
+		0x8F, 0x01, 0x24, // mov $24, #1
+		0x8F, 0x00, 0x25, // mov $25, #0
+		0x9A, 0x24, // subw ya, $24    ; $01FF
+		0xDA, 0x26, // movw $26, ya
+		0x5A, 0x22, // cmpw ya, $22    ; C clear
+		0xE8, 0x00, // mov a, #0
+		0x3C,       // rol a
+		0xC4, 0x28, // mov $28, a
+		0xFF,
+	})
+
+	ram := e.Ram()
+	assert.Equal(t, []byte{0x00, 0x01, 0x00, 0x02}, ram[0x20:0x24])
+	assert.Equal(t, []byte{0xFF, 0x01}, ram[0x26:0x28])
+	assert.EqualValues(t, 0, ram[0x28])
+}
+
+func TestCpuBranches(t *testing.T) {
+	e := runProgram(t, []byte{
+		0xCD, 0x00, // mov x, #0
+		0x8D, 0x05, // mov y, #5
+		0x3D,       // loop: inc x
+		0xFE, 0xFD, // dbnz y, loop
+		0xD8, 0x10, // mov $10, x      ; 5
+		0x3F, 0x20, 0x04, // call $0420
+		0xE8, 0x03, // mov a, #3
+		0x8F, 0x03, 0x13, // mov $13, #3
+		0x2E, 0x13, 0x03, // cbne $13, skip
+		0x8F, 0x01, 0x14, // mov $14, #1  ; not skipped
+		0xE2, 0x15, // skip: set1 $15.7
+		0x13, 0x15, 0x03, // bbc $15.0, +3
+		0x8F, 0x01, 0x16, // mov $16, #1  ; skipped
+		0xFF,
+		0x8F, 0x01, 0x12, // $0420: mov $12, #1
+		0x6F, // ret
+	})
+
+	ram := e.Ram()
+	assert.EqualValues(t, 5, ram[0x10])
+	assert.EqualValues(t, 1, ram[0x12])
+	assert.EqualValues(t, 1, ram[0x14])
+	assert.EqualValues(t, 0x80, ram[0x15])
+	assert.EqualValues(t, 0, ram[0x16])
+}
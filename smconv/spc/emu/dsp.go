@@ -0,0 +1,542 @@
+// SNESMOD
+// (C) 2025 Mukunda Johnson (mukunda.com)
+// Licensed under MIT
+
+package emu
+
+import (
+	"go.mukunda.com/snesmod/smconv/spc"
+)
+
+// This file emulates the S-DSP: BRR decoding, Gaussian interpolation, the ADSR and GAIN
+// envelopes, noise, pitch modulation, and echo with the FIR filter. The integer math
+// follows the hardware, including where it truncates and clamps, so the output should
+// match a real SNES bit for bit as long as register writes land on the same sample.
+// https://snes.nesdev.org/wiki/S-DSP
+
+const (
+	kBrrBlockSize  = 9
+	kBrrBufferSize = 12 // Decoded samples kept for interpolation
+	kVoiceCount    = 8
+
+	// Period of the global counter that paces envelopes and noise.
+	kCounterRange = 2048 * 5 * 3
+)
+
+type envelopeMode int
+
+const (
+	envRelease envelopeMode = iota
+	envAttack
+	envDecay
+	envSustain
+)
+
+// How often each envelope or noise rate fires, in samples. Rate 0 never fires.
+var counterRates = [32]int{
+	kCounterRange + 1, 2048, 1536,
+	1280, 1024, 768,
+	640, 512, 384,
+	320, 256, 192,
+	160, 128, 96,
+	80, 64, 48,
+	40, 32, 24,
+	20, 16, 12,
+	10, 8, 6,
+	5, 4, 3,
+	2,
+	1,
+}
+
+// Offsets that make rates with the same period fire on different samples.
+var counterOffsets = [32]int{
+	1, 0, 1040,
+	536, 0, 1040,
+	536, 0, 1040,
+	536, 0, 1040,
+	536, 0, 1040,
+	536, 0, 1040,
+	536, 0, 1040,
+	536, 0, 1040,
+	536, 0, 1040,
+	536, 0, 1040,
+	0,
+	0,
+}
+
+// The interpolation table from the DSP's ROM. Each output is the sum of four samples
+// weighted by entries that are 256 apart.
+var gaussTable = [512]int{
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+	1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 2, 2, 2, 2, 2,
+	2, 2, 3, 3, 3, 3, 3, 4, 4, 4, 4, 4, 5, 5, 5, 5,
+	6, 6, 6, 6, 7, 7, 7, 8, 8, 8, 9, 9, 9, 10, 10, 10,
+	11, 11, 11, 12, 12, 13, 13, 14, 14, 15, 15, 15, 16, 16, 17, 17,
+	18, 19, 19, 20, 20, 21, 21, 22, 23, 23, 24, 24, 25, 26, 27, 27,
+	28, 29, 29, 30, 31, 32, 32, 33, 34, 35, 36, 36, 37, 38, 39, 40,
+	41, 42, 43, 44, 45, 46, 47, 48, 49, 50, 51, 52, 53, 54, 55, 56,
+	58, 59, 60, 61, 62, 64, 65, 66, 67, 69, 70, 71, 73, 74, 76, 77,
+	78, 80, 81, 83, 84, 86, 87, 89, 90, 92, 94, 95, 97, 99, 100, 102,
+	104, 106, 107, 109, 111, 113, 115, 117, 118, 120, 122, 124, 126, 128, 130, 132,
+	134, 137, 139, 141, 143, 145, 147, 150, 152, 154, 156, 159, 161, 163, 166, 168,
+	171, 173, 175, 178, 180, 183, 186, 188, 191, 193, 196, 199, 201, 204, 207, 210,
+	212, 215, 218, 221, 224, 227, 230, 233, 236, 239, 242, 245, 248, 251, 254, 257,
+	260, 263, 267, 270, 273, 276, 280, 283, 286, 290, 293, 297, 300, 304, 307, 311,
+	314, 318, 321, 325, 328, 332, 336, 339, 343, 347, 351, 354, 358, 362, 366, 370,
+	374, 378, 381, 385, 389, 393, 397, 401, 405, 410, 414, 418, 422, 426, 430, 434,
+	439, 443, 447, 451, 456, 460, 464, 469, 473, 477, 482, 486, 491, 495, 499, 504,
+	508, 513, 517, 522, 527, 531, 536, 540, 545, 550, 554, 559, 563, 568, 573, 577,
+	582, 587, 592, 596, 601, 606, 611, 615, 620, 625, 630, 635, 640, 644, 649, 654,
+	659, 664, 669, 674, 678, 683, 688, 693, 698, 703, 708, 713, 718, 723, 728, 732,
+	737, 742, 747, 752, 757, 762, 767, 772, 777, 782, 787, 792, 797, 802, 806, 811,
+	816, 821, 826, 831, 836, 841, 846, 851, 855, 860, 865, 870, 875, 880, 884, 889,
+	894, 899, 904, 908, 913, 918, 923, 927, 932, 937, 941, 946, 951, 955, 960, 965,
+	969, 974, 978, 983, 988, 992, 997, 1001, 1005, 1010, 1014, 1019, 1023, 1027, 1032, 1036,
+	1040, 1045, 1049, 1053, 1057, 1061, 1066, 1070, 1074, 1078, 1082, 1086, 1090, 1094, 1098, 1102,
+	1106, 1109, 1113, 1117, 1121, 1125, 1128, 1132, 1136, 1139, 1143, 1146, 1150, 1153, 1157, 1160,
+	1164, 1167, 1170, 1174, 1177, 1180, 1183, 1186, 1190, 1193, 1196, 1199, 1202, 1205, 1207, 1210,
+	1213, 1216, 1219, 1221, 1224, 1227, 1229, 1232, 1234, 1237, 1239, 1241, 1244, 1246, 1248, 1251,
+	1253, 1255, 1257, 1259, 1261, 1263, 1265, 1267, 1269, 1270, 1272, 1274, 1275, 1277, 1279, 1280,
+	1282, 1283, 1284, 1286, 1287, 1288, 1290, 1291, 1292, 1293, 1294, 1295, 1296, 1297, 1297, 1298,
+	1299, 1300, 1300, 1301, 1302, 1302, 1303, 1303, 1303, 1304, 1304, 1304, 1304, 1304, 1305, 1305,
+}
+
+type voice struct {
+	// Decoded samples. The second half is a copy of the first so that interpolation
+	// doesn't need to wrap.
+	buf    [kBrrBufferSize * 2]int
+	bufPos int // Where the next four samples are decoded
+
+	interpPos int // Position in the buffer, 12-bit fraction
+	brrAddr   uint16
+	brrOffset int // Byte of the block that's decoded next
+	konDelay  int // Samples left in the key on sequence
+
+	envMode   envelopeMode
+	env       int // 11-bit envelope level
+	hiddenEnv int // Level before clamping, used by GAIN bent line
+}
+
+type dsp struct {
+	regs spc.DspRegisters
+	ram  *[0x10000]byte
+
+	voices [kVoiceCount]voice
+
+	newKon           uint8 // Written to KON, waiting to be read
+	kon              uint8
+	kof              uint8
+	everyOtherSample bool
+
+	counter int
+	noise   int
+
+	// FIR history for each channel. It's doubled like the BRR buffer.
+	echoHist    [16][2]int
+	echoHistPos int
+	echoOffset  int
+	echoLength  int
+	echoPage    uint8 // ESA, read a sample before it's used
+}
+
+// Set up the DSP from saved registers.
+func (d *dsp) load(regs *spc.DspRegisters, ram *[0x10000]byte) {
+	d.regs = *regs
+	d.ram = ram
+	d.newKon = regs[spc.DspKon]
+	d.echoPage = regs[spc.DspEsa]
+	d.noise = 0x4000
+	d.everyOtherSample = true
+	for i := range d.voices {
+		d.voices[i].brrOffset = 1
+	}
+}
+
+func (d *dsp) read(addr uint8) uint8 {
+	return d.regs[addr&0x7F]
+}
+
+func (d *dsp) write(addr uint8, value uint8) {
+	// $80-$FF are read-only mirrors.
+	if addr >= 0x80 {
+		return
+	}
+	d.regs[addr] = value
+	switch addr {
+	case spc.DspKon:
+		d.newKon = value
+	case spc.DspEndx:
+		// Any write clears it.
+		d.regs[addr] = 0
+	}
+}
+
+func clamp16(value int) int {
+	return min(max(value, -0x8000), 0x7FFF)
+}
+
+func (d *dsp) read16(addr uint16) int {
+	return int(int16(uint16(d.ram[addr]) | uint16(d.ram[addr+1])<<8))
+}
+
+// True if something paced at `rate` happens on this sample.
+func (d *dsp) counterFires(rate int) bool {
+	return (d.counter+counterOffsets[rate])%counterRates[rate] == 0
+}
+
+// Decode one BRR sample from its nybble, the block's header, and the previous two
+// decoded samples. Samples are 15-bit values stored doubled, as the DSP keeps them.
+func decodeBrrSample(nybble int, header uint8, p1 int, p2 int) int {
+	shift := int(header >> 4)
+	s := (nybble << shift) >> 1
+	if shift >= 0xD {
+		// Invalid shifts give 0 or -2048.
+		if s < 0 {
+			s = -0x800
+		} else {
+			s = 0
+		}
+	}
+
+	p2 >>= 1
+	switch header & 0x0C {
+	case 0x04:
+		s += p1 >> 1
+		s += -p1 >> 5
+	case 0x08:
+		s += p1 - p2
+		s += p2 >> 4
+		s += (p1 * -3) >> 6
+	case 0x0C:
+		s += p1 - p2
+		s += (p1 * -13) >> 7
+		s += (p2 * 3) >> 4
+	}
+
+	return int(int16(clamp16(s) * 2))
+}
+
+// Decode the next four samples of a voice's BRR data.
+func (d *dsp) decodeBrr(v *voice, header uint8, first uint8) {
+	nybbles := int(first)<<8 | int(d.ram[v.brrAddr+uint16(v.brrOffset)+1])
+
+	pos := v.bufPos
+	for i := 0; i < 4; i, nybbles = i+1, nybbles<<4 {
+		s := decodeBrrSample(int(int16(nybbles))>>12, header,
+			v.buf[pos+kBrrBufferSize-1], v.buf[pos+kBrrBufferSize-2])
+		v.buf[pos] = s
+		v.buf[pos+kBrrBufferSize] = s
+		pos++
+	}
+
+	v.bufPos = pos % kBrrBufferSize
+}
+
+// Decode BRR data to 16-bit PCM, as the DSP plays it. Decoding stops after the block
+// with the end flag.
+func DecodeBrr(data []byte) []int16 {
+	pcm := []int16{}
+	p1, p2 := 0, 0
+	for pos := 0; pos+kBrrBlockSize <= len(data); pos += kBrrBlockSize {
+		header := data[pos]
+		for _, b := range data[pos+1 : pos+kBrrBlockSize] {
+			for _, nybble := range []int{int(int8(b)) >> 4, int(int8(b<<4)) >> 4} {
+				s := decodeBrrSample(nybble, header, p1, p2)
+				p1, p2 = s, p1
+				pcm = append(pcm, int16(s))
+			}
+		}
+		if header&1 != 0 {
+			break
+		}
+	}
+	return pcm
+}
+
+// Gaussian interpolation between the four samples around the voice's position.
+func (d *dsp) interpolate(v *voice) int {
+	offset := v.interpPos >> 4 & 0xFF
+	in := v.buf[(v.interpPos>>12)+v.bufPos:]
+
+	out := (gaussTable[255-offset] * in[0]) >> 11
+	out += (gaussTable[511-offset] * in[1]) >> 11
+	out += (gaussTable[256+offset] * in[2]) >> 11
+	out = int(int16(out))
+	out += (gaussTable[offset] * in[3]) >> 11
+	return clamp16(out) &^ 1
+}
+
+// Advance a voice's envelope by one sample. `adsr1` is read at the start of the sample.
+func (d *dsp) runEnvelope(v *voice, base int, adsr1 uint8) {
+	env := v.env
+	if v.envMode == envRelease {
+		v.env = max(env-8, 0)
+		return
+	}
+
+	var rate int
+	var envData uint8
+	if adsr1&0x80 != 0 {
+		// ADSR
+		envData = d.regs[base+int(spc.DspAdsr2)]
+		if v.envMode >= envDecay {
+			env--
+			env -= env >> 8
+			rate = int(envData & 0x1F)
+			if v.envMode == envDecay {
+				rate = int(adsr1>>3&0x0E) + 0x10
+			}
+		} else {
+			rate = int(adsr1&0x0F)*2 + 1
+			if rate < 31 {
+				env += 0x20
+			} else {
+				env += 0x400
+			}
+		}
+	} else {
+		// GAIN
+		envData = d.regs[base+int(spc.DspGain)]
+		mode := envData >> 5
+		if mode < 4 {
+			// Direct
+			env = int(envData) * 0x10
+			rate = 31
+		} else {
+			rate = int(envData & 0x1F)
+			switch mode {
+			case 4: // Linear decrease
+				env -= 0x20
+			case 5: // Exponential decrease
+				env--
+				env -= env >> 8
+			case 6: // Linear increase
+				env += 0x20
+			case 7: // Bent line increase
+				env += 0x20
+				if v.hiddenEnv >= 0x600 {
+					env += 0x8 - 0x20
+				}
+			}
+		}
+	}
+
+	// Sustain level
+	if env>>8 == int(envData>>5) && v.envMode == envDecay {
+		v.envMode = envSustain
+	}
+
+	v.hiddenEnv = env
+
+	// Linear decreases going negative are caught by this too.
+	if env < 0 || env > 0x7FF {
+		env = min(max(env, 0), 0x7FF)
+		if v.envMode == envAttack {
+			v.envMode = envDecay
+		}
+	}
+
+	if d.counterFires(rate) {
+		v.env = env
+	}
+}
+
+// Run one voice for a sample. `prevOutput` is the previous voice's output for pitch
+// modulation. Returns this voice's output.
+func (d *dsp) runVoice(index int, prevOutput int, mainOut, echoOut *[2]int) int {
+	v := &d.voices[index]
+	base := index * 0x10
+	bit := uint8(1) << index
+	regs := &d.regs
+
+	// The sample directory entry: the start address during key on, then the loop.
+	entry := uint16(regs[spc.DspDir])<<8 + uint16(regs[base+int(spc.DspSrcn)])*4
+	if v.konDelay == 0 {
+		entry += 2
+	}
+	nextAddr := uint16(d.ram[entry]) | uint16(d.ram[entry+1])<<8
+
+	adsr1 := regs[base+int(spc.DspAdsr1)]
+	pitch := (int(regs[base+int(spc.DspPitchL)]) | int(regs[base+int(spc.DspPitchH)])<<8) & 0x3FFF
+	header := d.ram[v.brrAddr]
+	brrByte := d.ram[v.brrAddr+uint16(v.brrOffset)]
+
+	// Voice 0 can't be modulated.
+	if regs[spc.DspPmon]&bit&0xFE != 0 {
+		pitch += ((prevOutput >> 5) * pitch) >> 10
+	}
+
+	if v.konDelay > 0 {
+		if v.konDelay == 5 {
+			v.brrAddr = nextAddr
+			v.brrOffset = 1
+			v.bufPos = 0
+			header = 0 // Ignored on this sample
+		}
+
+		// The envelope and pitch don't run during key on, and the first three samples
+		// after the start fill the BRR buffer.
+		v.env = 0
+		v.hiddenEnv = 0
+		v.interpPos = 0
+		v.konDelay--
+		if v.konDelay&3 != 0 {
+			v.interpPos = 0x4000
+		}
+		pitch = 0
+	}
+
+	output := d.interpolate(v)
+	if regs[spc.DspNon]&bit != 0 {
+		output = int(int16(d.noise * 2))
+	}
+	output = (output * v.env) >> 11 &^ 1
+	envx := uint8(v.env >> 4)
+
+	// Soft reset, or the end of a sample that doesn't loop, silences the voice at once.
+	if regs[spc.DspFlg]&spc.DspFlgReset != 0 || header&3 == 1 {
+		v.envMode = envRelease
+		v.env = 0
+	}
+
+	if d.everyOtherSample {
+		if d.kof&bit != 0 {
+			v.envMode = envRelease
+		}
+		if d.kon&bit != 0 {
+			v.konDelay = 5
+			v.envMode = envAttack
+		}
+	}
+
+	if v.konDelay == 0 {
+		d.runEnvelope(v, base, adsr1)
+	}
+
+	// Decode BRR when the position passes the buffered samples.
+	looped := false
+	if v.interpPos >= 0x4000 {
+		d.decodeBrr(v, header, brrByte)
+		v.brrOffset += 2
+		if v.brrOffset >= kBrrBlockSize {
+			v.brrAddr += kBrrBlockSize
+			if header&1 != 0 {
+				v.brrAddr = nextAddr
+				looped = true
+			}
+			v.brrOffset = 1
+		}
+	}
+
+	// Pitch modulation can push the position further than the buffer allows.
+	v.interpPos = min((v.interpPos&0x3FFF)+pitch, 0x7FFF)
+
+	for ch := range 2 {
+		amp := (output * int(int8(regs[base+int(spc.DspVolL)+ch]))) >> 7
+		mainOut[ch] = clamp16(mainOut[ch] + amp)
+		if regs[spc.DspEon]&bit != 0 {
+			echoOut[ch] = clamp16(echoOut[ch] + amp)
+		}
+	}
+
+	endx := regs[spc.DspEndx]
+	if looped {
+		endx |= bit
+	}
+	if v.konDelay == 5 {
+		endx &^= bit
+	}
+	regs[spc.DspEndx] = endx
+	regs[base+int(spc.DspEnvx)] = envx
+	regs[base+int(spc.DspOutx)] = uint8(output >> 8)
+
+	return output
+}
+
+// Run the DSP for one sample and return the output.
+func (d *dsp) run() (int16, int16) {
+	regs := &d.regs
+
+	// Key on and key off are read every other sample. A key on is cleared once it's
+	// been read.
+	d.everyOtherSample = !d.everyOtherSample
+	if d.everyOtherSample {
+		d.newKon &^= d.kon
+		d.kon = d.newKon
+		d.kof = regs[spc.DspKof]
+	}
+
+	d.counter--
+	if d.counter < 0 {
+		d.counter = kCounterRange - 1
+	}
+
+	if d.counterFires(int(regs[spc.DspFlg] & spc.DspFlgNoiseMask)) {
+		feedback := d.noise<<13 ^ d.noise<<14
+		d.noise = feedback&0x4000 ^ d.noise>>1
+	}
+
+	var mainOut, echoOut [2]int
+	output := 0
+	for i := range d.voices {
+		output = d.runVoice(i, output, &mainOut, &echoOut)
+	}
+
+	return d.runEcho(mainOut, echoOut)
+}
+
+// Mix the echo into the voice output, and write the new echo data. Returns the final
+// output.
+func (d *dsp) runEcho(mainOut, echoOut [2]int) (int16, int16) {
+	regs := &d.regs
+
+	d.echoHistPos = (d.echoHistPos + 1) & 7
+	ptr := uint16(d.echoPage)<<8 + uint16(d.echoOffset)
+
+	var echoIn, out [2]int
+	for ch := range 2 {
+		s := d.read16(ptr+uint16(ch)*2) >> 1
+		d.echoHist[d.echoHistPos][ch] = s
+		d.echoHist[d.echoHistPos+8][ch] = s
+
+		// FIR filter. The newest sample uses the last coefficient.
+		sum := 0
+		for i := range 7 {
+			sum += (d.echoHist[d.echoHistPos+i+1][ch] * int(int8(regs[i*0x10+int(spc.DspFir)]))) >> 6
+		}
+		sum = int(int16(sum))
+		sum += int(int16((d.echoHist[d.echoHistPos+8][ch] * int(int8(regs[0x70+int(spc.DspFir)]))) >> 6))
+		echoIn[ch] = clamp16(sum) &^ 1
+
+		mvol := int(int8(regs[int(spc.DspMvolL)+ch*0x10]))
+		evol := int(int8(regs[int(spc.DspEvolL)+ch*0x10]))
+		out[ch] = clamp16(int(int16((mainOut[ch]*mvol)>>7)) + int(int16((echoIn[ch]*evol)>>7)))
+
+		feedback := int(int16((echoIn[ch] * int(int8(regs[spc.DspEfb]))) >> 7))
+		echoOut[ch] = clamp16(echoOut[ch]+feedback) &^ 1
+	}
+
+	if regs[spc.DspFlg]&spc.DspFlgMute != 0 {
+		out = [2]int{}
+	}
+
+	// The buffer length is read when the position wraps.
+	if d.echoOffset == 0 {
+		d.echoLength = int(regs[spc.DspEdl]&0x0F) * 0x800
+	}
+	d.echoOffset += 4
+	if d.echoOffset >= d.echoLength {
+		d.echoOffset = 0
+	}
+
+	if regs[spc.DspFlg]&spc.DspFlgEchoDisable == 0 {
+		for ch := range 2 {
+			addr := ptr + uint16(ch)*2
+			d.ram[addr] = uint8(echoOut[ch])
+			d.ram[addr+1] = uint8(echoOut[ch] >> 8)
+		}
+	}
+	d.echoPage = regs[spc.DspEsa]
+
+	return int16(out[0]), int16(out[1])
+}
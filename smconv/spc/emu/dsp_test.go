@@ -0,0 +1,206 @@
+// SNESMOD
+// (C) 2025 Mukunda Johnson (mukunda.com)
+// Licensed under MIT
+
+package emu
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mukunda.com/snesmod/smconv/spc"
+)
+
+const (
+	kTestDirectory = 0x0200
+	kTestSample    = 0x1000
+	kTestEcho      = 0x8000
+)
+
+// An SPC with the CPU stopped and voice 0 keyed on, playing BRR blocks at kTestSample.
+func makeVoiceTest(blocks ...[]byte) *spc.SpcFile {
+	spcf := spc.NewSpcFile()
+	spcf.Header.PC = kTestProgram
+	spcf.Memory[kTestProgram] = 0xFF // stop
+
+	addr := kTestSample
+	for _, block := range blocks {
+		copy(spcf.Memory[addr:], block)
+		addr += kBrrBlockSize
+	}
+	copy(spcf.Memory[kTestDirectory:], []byte{0x00, 0x10, 0x00, 0x10})
+
+	dsp := &spcf.DspRegisters
+	dsp.Reset()
+	dsp.SetFlags(spc.DspFlgEchoDisable)
+	dsp.SetSampleDirectory(kTestDirectory)
+	dsp.SetMainVolume(127, 127)
+	dsp.SetVoice(0, spc.DspVoice{VolumeL: 127, VolumeR: 127, Pitch: 0x1000, Gain: 0x7F})
+	dsp.SetKeyOn(0x01)
+	return spcf
+}
+
+// A BRR block where every sample is `nybble`.
+func brrBlock(header byte, nybble byte) []byte {
+	block := []byte{header}
+	for range 8 {
+		block = append(block, nybble<<4|nybble)
+	}
+	return block
+}
+
+func TestGaussTable(t *testing.T) {
+	// The four weights for any position add up to about 1.0 (2048).
+	for offset := range 256 {
+		sum := gaussTable[255-offset] + gaussTable[511-offset] + gaussTable[256+offset] + gaussTable[offset]
+		assert.InDelta(t, 2048, sum, 1, "offset %d", offset)
+	}
+}
+
+func TestDecodeBrr(t *testing.T) {
+	d := &dsp{ram: &[0x10000]byte{}}
+	v := &voice{}
+
+	// Shift 12, no filter: each nybble is multiplied by 2048, then doubled.
+	copy(d.ram[:], []byte{0xC0, 0x1F, 0x70, 0x80, 0x00})
+	v.brrOffset = 1
+	d.decodeBrr(v, 0xC0, d.ram[1])
+	assert.Equal(t, []int{4096, -4096, 28672, 0}, v.buf[0:4])
+	assert.Equal(t, v.buf[0:4], v.buf[12:16])
+	assert.Equal(t, 4, v.bufPos)
+
+	// Invalid shifts clamp to 0 or -2048.
+	v.brrOffset = 3
+	d.decodeBrr(v, 0xF0, d.ram[3])
+	assert.Equal(t, []int{-4096, 0, 0, 0}, v.buf[4:8])
+
+	// Filter 1 adds 15/16 of the previous sample.
+	v = &voice{brrOffset: 1}
+	d.ram[1], d.ram[2] = 0x10, 0x00
+	d.decodeBrr(v, 0xC4, d.ram[1])
+	assert.Equal(t, []int{4096, 3840, 3600, 3374}, v.buf[0:4])
+
+	// Whole samples decode the same way, and stop at the end flag.
+	data := append(brrBlock(0xC4, 0), brrBlock(0xC5, 0)...)
+	data[1] = 0x10
+	data = append(data, brrBlock(0xC0, 1)...)
+	pcm := DecodeBrr(data)
+	assert.Len(t, pcm, 32)
+	assert.Equal(t, []int16{4096, 3840, 3600, 3374}, pcm[0:4])
+	assert.Less(t, pcm[31], pcm[16])
+	assert.Greater(t, pcm[31], int16(0))
+}
+
+func TestVoicePlayback(t *testing.T) {
+	e := New(makeVoiceTest(brrBlock(0xC3, 1)))
+	out := make([]int16, 200*2)
+	e.Render(out)
+
+	// 2048*2 * envelope 0x7F0, then full voice and main volume.
+	assert.InDelta(t, 4000, out[len(out)-2], 4)
+	assert.Equal(t, out[len(out)-2], out[len(out)-1])
+
+	dsp := e.DspRegisters()
+	assert.EqualValues(t, 1, dsp.EndedVoices())
+	assert.EqualValues(t, 0x7F, dsp.Voice(0).Envx)
+	assert.NotZero(t, dsp.Voice(0).Outx)
+
+	// ENDX is cleared by writing to it.
+	e.dsp.write(spc.DspEndx, 0xFF)
+	assert.Zero(t, dsp.EndedVoices())
+}
+
+func TestVoiceEnd(t *testing.T) {
+	e := New(makeVoiceTest(brrBlock(0xC0, 1), brrBlock(0xC1, 1)))
+	out := make([]int16, 200*2)
+	e.Render(out)
+
+	assert.NotZero(t, out[20])
+	assert.Zero(t, out[len(out)-2])
+	assert.Zero(t, e.DspRegisters().Voice(0).Envx)
+	assert.EqualValues(t, 1, e.DspRegisters().EndedVoices())
+}
+
+func TestEnvelope(t *testing.T) {
+	spcf := makeVoiceTest(brrBlock(0xC3, 1))
+	// Fastest attack and decay to a sustain level of 6/8, no sustain release.
+	spcf.DspRegisters.SetVoice(0, spc.DspVoice{VolumeL: 127, VolumeR: 127, Pitch: 0x1000, Adsr1: 0xFF, Adsr2: 0xC0})
+	e := New(spcf)
+
+	e.Render(make([]int16, 10*2))
+	assert.EqualValues(t, 0x7F, e.DspRegisters().Voice(0).Envx)
+
+	e.Render(make([]int16, 1000*2))
+	envx := e.DspRegisters().Voice(0).Envx
+	assert.GreaterOrEqual(t, envx, uint8(0x60))
+	assert.LessOrEqual(t, envx, uint8(0x70))
+	assert.Equal(t, envSustain, e.dsp.voices[0].envMode)
+
+	// Release lowers the envelope by 8 every sample.
+	e.dsp.write(spc.DspKof, 0x01)
+	e.Render(make([]int16, 300*2))
+	assert.Zero(t, e.DspRegisters().Voice(0).Envx)
+	assert.Equal(t, envRelease, e.dsp.voices[0].envMode)
+}
+
+func TestNoise(t *testing.T) {
+	spcf := makeVoiceTest(brrBlock(0xC3, 0))
+	spcf.DspRegisters.SetNoiseVoices(0x01)
+	spcf.DspRegisters.SetFlags(spc.DspFlgEchoDisable | 0x1F)
+	e := New(spcf)
+	out := make([]int16, 100*2)
+	e.Render(out)
+
+	values := map[int16]bool{}
+	for i := 20; i < len(out); i += 2 {
+		values[out[i]] = true
+	}
+	assert.Greater(t, len(values), 50)
+}
+
+func TestEcho(t *testing.T) {
+	run := func(flags uint8) *Emulator {
+		spcf := makeVoiceTest(brrBlock(0xC3, 1))
+		dsp := &spcf.DspRegisters
+		dsp.SetFlags(flags)
+		dsp.SetEchoStart(kTestEcho)
+		dsp.SetEchoDelay(1)
+		dsp.SetEchoVoices(0x01)
+		dsp.SetEchoVolume(127, 127)
+		dsp.SetFir(7, 127)
+		e := New(spcf)
+		e.Render(make([]int16, 1000*2))
+		return e
+	}
+
+	// The voice is written to the echo buffer, and wraps after 2KB.
+	e := run(0)
+	ram := e.Ram()
+	assert.NotZero(t, ram[kTestEcho+0x100])
+	assert.Zero(t, ram[kTestEcho+0x800])
+
+	// The echo is mixed into the output.
+	dry := run(spc.DspFlgEchoDisable)
+	wet := make([]int16, 2)
+	dryOut := make([]int16, 2)
+	e.Render(wet)
+	dry.Render(dryOut)
+	assert.Greater(t, wet[0], dryOut[0])
+
+	// Writes to the buffer can be disabled.
+	assert.Zero(t, dry.Ram()[kTestEcho+0x100])
+}
+
+func TestMuteAndReset(t *testing.T) {
+	spcf := makeVoiceTest(brrBlock(0xC3, 1))
+	spcf.DspRegisters.SetFlags(spc.DspFlgMute | spc.DspFlgEchoDisable)
+	e := New(spcf)
+	out := make([]int16, 100*2)
+	e.Render(out)
+	assert.Zero(t, out[len(out)-2])
+	assert.EqualValues(t, 0x7F, e.DspRegisters().Voice(0).Envx)
+
+	e.dsp.write(spc.DspFlg, spc.DspFlgReset)
+	e.Render(out)
+	assert.Zero(t, e.DspRegisters().Voice(0).Envx)
+}
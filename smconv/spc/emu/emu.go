@@ -0,0 +1,253 @@
+// SNESMOD
+// (C) 2025 Mukunda Johnson (mukunda.com)
+// Licensed under MIT
+
+/*
+Emulates the SNES audio unit to play SPC files offline: the SPC700 CPU, its timers and
+I/O ports, and the S-DSP.
+
+The DSP is emulated a sample at a time rather than a cycle at a time, so writes to DSP
+registers take effect on the next sample. That's inaudible for music, and the driver
+doesn't depend on anything finer.
+*/
+package emu
+
+import (
+	"go.mukunda.com/snesmod/smconv/spc"
+)
+
+const (
+	// Output rate of the S-DSP.
+	SampleRate = 32000
+
+	// SPC700 clock rate.
+	CpuRate = 1024000
+
+	kCyclesPerSample = CpuRate / SampleRate
+
+	// Timers 0 and 1 count at 8000 Hz, and timer 2 counts at 64000 Hz.
+	kSlowTimerCycles = CpuRate / 8000
+	kFastTimerCycles = CpuRate / 64000
+)
+
+// I/O registers at $F0-$FF.
+const (
+	kRegControl  = 0xF1
+	kRegDspAddr  = 0xF2
+	kRegDspData  = 0xF3
+	kRegPort0    = 0xF4
+	kRegPort3    = 0xF7
+	kRegTimer0   = 0xFA
+	kRegTimer2   = 0xFC
+	kRegCounter0 = 0xFD
+	kRegCounter2 = 0xFF
+
+	kIplRomAddress = 0xFFC0
+)
+
+// CONTROL bits.
+const (
+	kControlClearPort0 = 0x10 // Clears input ports 0 and 1
+	kControlClearPort2 = 0x20 // Clears input ports 2 and 3
+	kControlIplRom     = 0x80
+)
+
+type timer struct {
+	enabled bool
+	target  uint8 // 0 means 256
+	stage   int   // Internal 8-bit count towards the target
+	counter uint8 // 4-bit output, cleared when read
+	cycles  int   // Cycles until the next stage tick
+	period  int
+}
+
+// Run the timer for some cycles.
+func (t *timer) run(cycles int) {
+	if !t.enabled {
+		return
+	}
+	t.cycles -= cycles
+	for t.cycles <= 0 {
+		t.cycles += t.period
+		t.stage = (t.stage + 1) & 0xFF
+		if t.stage == int(t.target) {
+			t.stage = 0
+			t.counter = (t.counter + 1) & 0x0F
+		}
+	}
+}
+
+// An SPC700 and S-DSP running a program.
+type Emulator struct {
+	cpu cpu
+	dsp dsp
+
+	ram [0x10000]byte
+
+	// The RAM at $FFC0-$FFFF that's hidden while the IPL ROM is mapped is kept in ram.
+	// Reads go to the ROM instead.
+	iplRomEnabled bool
+
+	dspAddr     uint8
+	inPorts     [4]uint8 // From the SNES CPU
+	outPorts    [4]uint8 // To the SNES CPU
+	timers      [3]timer
+	cycles      int // Cycles run towards the next sample
+	totalCycles uint64
+}
+
+// Create an emulator in the state saved in an SPC file.
+func New(spcf *spc.SpcFile) *Emulator {
+	e := &Emulator{}
+	e.ram = spcf.Memory
+
+	control := spcf.Memory[kRegControl]
+	e.iplRomEnabled = control&kControlIplRom != 0
+	if e.iplRomEnabled {
+		// The RAM image has the ROM here, and the RAM under it is saved separately.
+		copy(e.ram[kIplRomAddress:], spcf.IplRom[:])
+	}
+
+	e.dspAddr = spcf.Memory[kRegDspAddr]
+	copy(e.inPorts[:], spcf.Memory[kRegPort0:kRegPort3+1])
+	for i := range e.timers {
+		t := &e.timers[i]
+		t.enabled = control&(1<<i) != 0
+		t.target = spcf.Memory[kRegTimer0+i]
+		t.counter = spcf.Memory[kRegCounter0+i] & 0x0F
+		t.period = kSlowTimerCycles
+		if i == 2 {
+			t.period = kFastTimerCycles
+		}
+		t.cycles = t.period
+	}
+
+	h := &spcf.Header
+	e.cpu = cpu{a: h.A, x: h.X, y: h.Y, sp: h.SP, psw: h.PSW, pc: h.PC}
+	e.dsp.load(&spcf.DspRegisters, &e.ram)
+	return e
+}
+
+// Write a value to an input port, as if the SNES CPU wrote it to $2140-$2143.
+func (e *Emulator) WritePort(port int, value uint8) {
+	e.inPorts[port&3] = value
+}
+
+// Read an output port, as the SNES CPU would read it from $2140-$2143.
+func (e *Emulator) ReadPort(port int) uint8 {
+	return e.outPorts[port&3]
+}
+
+// Direct access to the SPC700 RAM.
+func (e *Emulator) Ram() *[0x10000]byte {
+	return &e.ram
+}
+
+// The current S-DSP registers.
+func (e *Emulator) DspRegisters() *spc.DspRegisters {
+	return &e.dsp.regs
+}
+
+// CPU cycles run since the emulator was created.
+func (e *Emulator) Cycles() uint64 {
+	return e.totalCycles
+}
+
+// True if the CPU executed SLEEP or STOP. The DSP keeps running.
+func (e *Emulator) Stopped() bool {
+	return e.cpu.stopped
+}
+
+// Run the emulator and fill `out` with stereo samples (left, right, ...) at SampleRate.
+func (e *Emulator) Render(out []int16) {
+	for i := 0; i+1 < len(out); i += 2 {
+		for e.cycles < kCyclesPerSample {
+			cycles := kCyclesPerSample
+			if !e.cpu.stopped {
+				cycles = e.step()
+			}
+			e.cycles += cycles
+			e.totalCycles += uint64(cycles)
+			for t := range e.timers {
+				e.timers[t].run(cycles)
+			}
+		}
+		e.cycles -= kCyclesPerSample
+		out[i], out[i+1] = e.dsp.run()
+	}
+}
+
+// Render a number of seconds.
+func (e *Emulator) RenderSeconds(seconds float64) []int16 {
+	out := make([]int16, int(seconds*SampleRate)*2)
+	e.Render(out)
+	return out
+}
+
+func (e *Emulator) read(addr uint16) uint8 {
+	if addr >= 0xF0 && addr <= 0xFF {
+		return e.readIo(addr)
+	}
+	if addr >= kIplRomAddress && e.iplRomEnabled {
+		return spc.IplRomData[addr-kIplRomAddress]
+	}
+	return e.ram[addr]
+}
+
+func (e *Emulator) write(addr uint16, value uint8) {
+	// Writes always go to RAM, even under the I/O registers and the IPL ROM.
+	e.ram[addr] = value
+	if addr >= 0xF0 && addr <= 0xFF {
+		e.writeIo(addr, value)
+	}
+}
+
+func (e *Emulator) readIo(addr uint16) uint8 {
+	switch {
+	case addr == kRegDspAddr:
+		return e.dspAddr
+	case addr == kRegDspData:
+		return e.dsp.read(e.dspAddr)
+	case addr >= kRegPort0 && addr <= kRegPort3:
+		return e.inPorts[addr-kRegPort0]
+	case addr >= kRegTimer0 && addr <= kRegTimer2:
+		return 0 // Write-only
+	case addr >= kRegCounter0 && addr <= kRegCounter2:
+		t := &e.timers[addr-kRegCounter0]
+		value := t.counter
+		t.counter = 0
+		return value
+	}
+	return e.ram[addr]
+}
+
+func (e *Emulator) writeIo(addr uint16, value uint8) {
+	switch {
+	case addr == kRegControl:
+		for i := range e.timers {
+			t := &e.timers[i]
+			enable := value&(1<<i) != 0
+			if enable && !t.enabled {
+				t.stage = 0
+				t.counter = 0
+				t.cycles = t.period
+			}
+			t.enabled = enable
+		}
+		if value&kControlClearPort0 != 0 {
+			e.inPorts[0], e.inPorts[1] = 0, 0
+		}
+		if value&kControlClearPort2 != 0 {
+			e.inPorts[2], e.inPorts[3] = 0, 0
+		}
+		e.iplRomEnabled = value&kControlIplRom != 0
+	case addr == kRegDspAddr:
+		e.dspAddr = value
+	case addr == kRegDspData:
+		e.dsp.write(e.dspAddr, value)
+	case addr >= kRegPort0 && addr <= kRegPort3:
+		e.outPorts[addr-kRegPort0] = value
+	case addr >= kRegTimer0 && addr <= kRegTimer2:
+		e.timers[addr-kRegTimer0].target = value
+	}
+}
@@ -0,0 +1,93 @@
+// SNESMOD
+// (C) 2025 Mukunda Johnson (mukunda.com)
+// Licensed under MIT
+
+package emu
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mukunda.com/snesmod/smconv/spc"
+)
+
+func TestTimers(t *testing.T) {
+	e := runProgram(t, []byte{
+		0x8F, 0x04, 0xFA, // mov $FA, #4    ; 2000 Hz
+		0x8F, 0x01, 0xF1, // mov $F1, #1
+		0xFF,
+	})
+
+	// The counter ticks every 4 timer steps, 16 samples at 32000 Hz.
+	e.read(0xFD)
+	e.Render(make([]int16, 16*5*2))
+	assert.InDelta(t, 5, e.read(0xFD), 1)
+	assert.EqualValues(t, 0, e.read(0xFD), "reading clears the counter")
+
+	// The counter is only 4 bits.
+	e.Render(make([]int16, 16*20*2))
+	assert.Less(t, e.read(0xFD), uint8(16))
+
+	// Timer 1 isn't running.
+	assert.EqualValues(t, 0, e.read(0xFE))
+}
+
+func TestPorts(t *testing.T) {
+	spcf := spc.NewSpcFile()
+	spcf.Header.PC = kTestProgram
+	copy(spcf.Memory[kTestProgram:], []byte{
+		0xE4, 0xF4, // loop: mov a, $F4
+		0xF0, 0xFC, // beq loop
+		0xBC,       // inc a
+		0xC4, 0xF5, // mov $F5, a
+		0xFF,
+	})
+
+	e := New(spcf)
+	e.Render(make([]int16, 20))
+	assert.False(t, e.Stopped())
+
+	e.WritePort(0, 0x41)
+	e.Render(make([]int16, 20))
+	assert.True(t, e.Stopped())
+	assert.EqualValues(t, 0x42, e.ReadPort(1))
+
+	// The CPU reads the input port, not what it wrote.
+	assert.EqualValues(t, 0x41, e.read(0xF4))
+}
+
+func TestIplRom(t *testing.T) {
+	spcf := spc.NewSpcFile()
+	spcf.IplRom[0] = 0x12
+	spcf.Header.PC = kTestProgram
+	copy(spcf.Memory[kTestProgram:], []byte{
+		0xE5, 0xC0, 0xFF, // mov a, $FFC0
+		0xC4, 0x10, // mov $10, a
+		0x8F, 0x00, 0xF1, // mov $F1, #0      ; unmap the ROM
+		0xE5, 0xC0, 0xFF, // mov a, $FFC0
+		0xC4, 0x11, // mov $11, a
+		0xFF,
+	})
+
+	e := New(spcf)
+	e.Render(make([]int16, 20))
+	ram := e.Ram()
+	assert.Equal(t, spc.IplRomData[0], ram[0x10])
+	assert.EqualValues(t, 0x12, ram[0x11])
+}
+
+func TestDspAccess(t *testing.T) {
+	e := runProgram(t, []byte{
+		0x8F, 0x5D, 0xF2, // mov $F2, #$5D
+		0x8F, 0x34, 0xF3, // mov $F3, #$34
+		0x8F, 0xDD, 0xF2, // mov $F2, #$DD  ; read-only mirror
+		0x8F, 0x56, 0xF3, // mov $F3, #$56
+		0xE4, 0xF3, // mov a, $F3
+		0xC4, 0x10, // mov $10, a
+		0xFF,
+	})
+
+	assert.EqualValues(t, 0x34, e.DspRegisters().SampleDirectory()>>8)
+	assert.EqualValues(t, 0x34, e.Ram()[0x10])
+	assert.Greater(t, e.Cycles(), uint64(0))
+}
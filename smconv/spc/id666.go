@@ -0,0 +1,199 @@
+// SNESMOD
+// (C) 2025 Mukunda Johnson (mukunda.com)
+// Licensed under MIT
+
+package spc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Which layout the ID666 tags in the header use.
+type TagFormat int
+
+const (
+	TagFormatNone TagFormat = iota
+	TagFormatText
+	TagFormatBinary
+)
+
+func (f TagFormat) String() string {
+	switch f {
+	case TagFormatText:
+		return "text"
+	case TagFormatBinary:
+		return "binary"
+	}
+	return "none"
+}
+
+// HasTags values in the header.
+const (
+	kHasTags = 26
+	kNoTags  = 27
+)
+
+// Offsets of the fields that differ between the formats, from the start of the tags.
+const (
+	kTagDate           = 0x70
+	kTagDuration       = 0x7B
+	kTagFade           = 0x7E
+	kTextTagArtist     = 0x83
+	kTextTagEmulator   = 0xA4
+	kBinaryTagArtist   = 0x82
+	kBinaryTagEmulator = 0xA3
+)
+
+// ID666 tags in either format.
+type Id666 struct {
+	SongTitle  string
+	GameTitle  string
+	DumpedBy   string
+	Comments   string
+	DateDumped time.Time // Zero if it's missing or unreadable
+	Duration   int       // Seconds before fading out
+	FadeOut    int       // Milliseconds
+	Artist     string
+
+	// Bit for each voice that starts muted.
+	ChannelDisables uint8
+	Emulator        FromEmulatorType
+}
+
+// Date formats found in text tags. MM/DD/YYYY is the standard.
+var textTagDateFormats = []string{"01/02/2006", "1/2/2006", "2006/01/02", "2006-01-02", "01-02-2006"}
+
+func (spc *SpcFile) rawTags() []byte {
+	buf := &bytes.Buffer{}
+	binary.Write(buf, binary.LittleEndian, &spc.Header.Tags)
+	return buf.Bytes()
+}
+
+// A NUL-terminated string.
+func tagString(data []byte) string {
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		data = data[:i]
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// True if the field is empty or a number in ASCII, padded with NUL.
+func isTextNumber(data []byte) bool {
+	end := false
+	for _, b := range data {
+		if b == 0 {
+			end = true
+		} else if end || b < '0' || b > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// True if the field is empty or looks like a date in ASCII.
+func isTextDate(data []byte) bool {
+	for _, b := range tagString(data) {
+		if (b < '0' || b > '9') && b != '/' && b != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// Guess the tag format from the header. Nothing marks which one a file uses, but the
+// numeric fields are ASCII digits in the text format and small binary numbers in the
+// binary format, and the binary date isn't printable.
+func (spc *SpcFile) DetectTagFormat() TagFormat {
+	if spc.Header.HasTags == kNoTags {
+		return TagFormatNone
+	}
+
+	raw := spc.rawTags()
+	if isTextNumber(raw[kTagDuration:kTagFade]) &&
+		isTextNumber(raw[kTagFade:kTextTagArtist]) &&
+		isTextDate(raw[kTagDate:kTagDuration]) {
+		return TagFormatText
+	}
+	return TagFormatBinary
+}
+
+// Decode the tags in the format that was detected by Read (TagFormat).
+func (spc *SpcFile) Id666() Id666 {
+	raw := spc.rawTags()
+	tags := Id666{
+		SongTitle: tagString(spc.Header.Tags.SongTitle[:]),
+		GameTitle: tagString(spc.Header.Tags.GameTitle[:]),
+		DumpedBy:  tagString(spc.Header.Tags.DumpedBy[:]),
+		Comments:  tagString(spc.Header.Tags.Comments[:]),
+	}
+
+	switch spc.TagFormat {
+	case TagFormatText:
+		date := tagString(raw[kTagDate:kTagDuration])
+		for _, format := range textTagDateFormats {
+			if t, err := time.Parse(format, date); err == nil {
+				tags.DateDumped = t
+				break
+			}
+		}
+		tags.Duration, _ = strconv.Atoi(tagString(raw[kTagDuration:kTagFade]))
+		tags.FadeOut, _ = strconv.Atoi(tagString(raw[kTagFade:kTextTagArtist]))
+		tags.Artist = tagString(raw[kTextTagArtist : kTextTagArtist+32])
+		tags.ChannelDisables = raw[kTextTagEmulator-1]
+		tags.Emulator = raw[kTextTagEmulator]
+
+		// Some tools store the emulator as a digit.
+		if tags.Emulator >= '0' && tags.Emulator <= '9' {
+			tags.Emulator -= '0'
+		}
+
+	case TagFormatBinary:
+		day, month := int(raw[kTagDate]), int(raw[kTagDate+1])
+		year := int(binary.LittleEndian.Uint16(raw[kTagDate+2:]))
+		if day >= 1 && day <= 31 && month >= 1 && month <= 12 && year > 0 {
+			tags.DateDumped = time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+		}
+		tags.Duration = int(raw[kTagDuration]) | int(raw[kTagDuration+1])<<8 | int(raw[kTagDuration+2])<<16
+		tags.FadeOut = int(binary.LittleEndian.Uint32(raw[kTagFade:]))
+		tags.Artist = tagString(raw[kBinaryTagArtist : kBinaryTagArtist+32])
+		tags.ChannelDisables = raw[kBinaryTagEmulator-1]
+		tags.Emulator = raw[kBinaryTagEmulator]
+
+	default:
+		return Id666{}
+	}
+
+	return tags
+}
+
+// Replace the tags in the header, in the text format. Strings that don't fit are cut,
+// and numbers are limited to the field sizes. The reserved bytes after the tags are kept.
+func (spc *SpcFile) SetId666(tags Id666) {
+	t := &spc.Header.Tags
+	*t = Id6Tags{Reserved: t.Reserved}
+
+	copy(t.SongTitle[:], tags.SongTitle)
+	copy(t.GameTitle[:], tags.GameTitle)
+	copy(t.DumpedBy[:], tags.DumpedBy)
+	copy(t.Comments[:], tags.Comments)
+	if !tags.DateDumped.IsZero() {
+		copy(t.DateDumped[:], tags.DateDumped.Format("01/02/2006"))
+	}
+	if tags.Duration > 0 {
+		copy(t.SongDuration[:], fmt.Sprint(min(tags.Duration, 999)))
+	}
+	if tags.FadeOut > 0 {
+		copy(t.SongFadeOut[:], fmt.Sprint(min(tags.FadeOut, 99999)))
+	}
+	copy(t.Composer[:], tags.Artist)
+	t.InitialChannelDisabled = tags.ChannelDisables
+	t.FromEmulator = tags.Emulator
+
+	spc.Header.HasTags = kHasTags
+	spc.TagFormat = TagFormatText
+}
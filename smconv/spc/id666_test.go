@@ -0,0 +1,124 @@
+// SNESMOD
+// (C) 2025 Mukunda Johnson (mukunda.com)
+// Licensed under MIT
+
+package spc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Write the file and read it back, so the format is detected.
+func rereadSpc(t *testing.T, spcf *SpcFile) *SpcFile {
+	buf := &bytes.Buffer{}
+	assert.NoError(t, spcf.Write(buf))
+	read := &SpcFile{}
+	assert.NoError(t, read.Read(buf))
+	return read
+}
+
+// Tags in the binary layout, as written by older tools.
+func makeBinaryTags(t *testing.T) *SpcFile {
+	raw := make([]byte, binary.Size(Id6Tags{}))
+	copy(raw[0x00:], "Song")
+	copy(raw[0x20:], "Game")
+	copy(raw[0x40:], "Dumper")
+	copy(raw[0x50:], "Comment")
+	raw[kTagDate] = 14
+	raw[kTagDate+1] = 3
+	binary.LittleEndian.PutUint16(raw[kTagDate+2:], 1998)
+	raw[kTagDuration] = 180
+	binary.LittleEndian.PutUint32(raw[kTagFade:], 10000)
+	copy(raw[kBinaryTagArtist:], "Artist")
+	raw[kBinaryTagEmulator-1] = 0x02
+	raw[kBinaryTagEmulator] = FromEmulatorZSNESW
+
+	spcf := NewSpcFile()
+	assert.NoError(t, binary.Read(bytes.NewReader(raw), binary.LittleEndian, &spcf.Header.Tags))
+	return spcf
+}
+
+func TestId666Text(t *testing.T) {
+	tags := Id666{
+		SongTitle:       "Song",
+		GameTitle:       "Game",
+		DumpedBy:        "Dumper",
+		Comments:        "Comment",
+		DateDumped:      time.Date(2025, 3, 14, 0, 0, 0, 0, time.UTC),
+		Duration:        95,
+		FadeOut:         5000,
+		Artist:          "Artist",
+		ChannelDisables: 0x80,
+		Emulator:        FromEmulatorSnesmod,
+	}
+
+	spcf := NewSpcFile()
+	spcf.SetId666(tags)
+	assert.Equal(t, "03/14/2025", string(spcf.Header.Tags.DateDumped[:10]))
+
+	read := rereadSpc(t, spcf)
+	assert.Equal(t, TagFormatText, read.TagFormat)
+	assert.Equal(t, tags, read.Id666())
+
+	// Empty tags are still text.
+	spcf.SetId666(Id666{SongTitle: "Song"})
+	read = rereadSpc(t, spcf)
+	assert.Equal(t, TagFormatText, read.TagFormat)
+	assert.Equal(t, Id666{SongTitle: "Song"}, read.Id666())
+}
+
+func TestId666Binary(t *testing.T) {
+	read := rereadSpc(t, makeBinaryTags(t))
+	assert.Equal(t, TagFormatBinary, read.TagFormat)
+
+	tags := read.Id666()
+	assert.Equal(t, Id666{
+		SongTitle:       "Song",
+		GameTitle:       "Game",
+		DumpedBy:        "Dumper",
+		Comments:        "Comment",
+		DateDumped:      time.Date(1998, 3, 14, 0, 0, 0, 0, time.UTC),
+		Duration:        180,
+		FadeOut:         10000,
+		Artist:          "Artist",
+		ChannelDisables: 0x02,
+		Emulator:        FromEmulatorZSNESW,
+	}, tags)
+
+	// Convert to text tags.
+	read.SetId666(tags)
+	converted := rereadSpc(t, read)
+	assert.Equal(t, TagFormatText, converted.TagFormat)
+	assert.Equal(t, tags, converted.Id666())
+	assert.Equal(t, "180", string(converted.Header.Tags.SongDuration[:]))
+}
+
+func TestId666Detection(t *testing.T) {
+	spcf := NewSpcFile()
+	spcf.Header.HasTags = kNoTags
+	read := rereadSpc(t, spcf)
+	assert.Equal(t, TagFormatNone, read.TagFormat)
+	assert.Equal(t, Id666{}, read.Id666())
+
+	// A binary duration of 50 is the digit '2', but the fade gives it away.
+	spcf = makeBinaryTags(t)
+	spcf.Header.Tags.SongDuration = [3]byte{50, 0, 0}
+	spcf.Header.Tags.DateDumped = [11]byte{}
+	assert.Equal(t, TagFormatBinary, spcf.DetectTagFormat())
+
+	// Text tags with other date formats and the emulator as a digit.
+	spcf = NewSpcFile()
+	copy(spcf.Header.Tags.DateDumped[:], "2001-02-03")
+	copy(spcf.Header.Tags.SongDuration[:], "60")
+	spcf.Header.Tags.FromEmulator = '2'
+	read = rereadSpc(t, spcf)
+	assert.Equal(t, TagFormatText, read.TagFormat)
+	assert.Equal(t, time.Date(2001, 2, 3, 0, 0, 0, 0, time.UTC), read.Id666().DateDumped)
+	assert.Equal(t, 60, read.Id666().Duration)
+	assert.Equal(t, FromEmulatorSNES9x, read.Id666().Emulator)
+}
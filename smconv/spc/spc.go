@@ -8,6 +8,7 @@ For working with SNES SPC files.
 package spc
 
 import (
+	"bytes"
 	"encoding/binary"
 	"io"
 
@@ -29,11 +30,20 @@ func bwrite(cat eC, w io.Writer, data any) {
 type SpcFile struct {
 	Header       SpcHeader
 	Memory       [65536]byte
-	DspRegisters [128]byte
+	DspRegisters DspRegisters
 	Reserved     [64]byte
-	IplRom       [64]byte
 
-	// Extended Id666 data, copied directly.
+	// The RAM under the IPL ROM ($FFC0-$FFFF). Most SPC files have the IPL ROM here.
+	IplRom [64]byte
+
+	// Layout of the ID666 tags in the header, detected by Read. See Id666.
+	TagFormat TagFormat
+
+	// Extended ID666 tags, or nil if the file doesn't have them.
+	Xid6 *Xid6Tags
+
+	// Anything after the xid6 chunk, copied directly. If the extended area isn't a valid
+	// xid6 chunk, this is all of it.
 	Extended []byte
 }
 
@@ -49,9 +59,9 @@ type SpcHeader struct {
 	Y            uint8
 	PSW          uint8
 	SP           uint8
-	_            [2]byte // Reserved
+	Reserved     [2]byte
 
-	// This data is only valid if in TEXT mode and HasTags = 27
+	// The tags in the TEXT layout. Use Id666 to read them in either layout.
 	Tags Id6Tags
 }
 
@@ -75,10 +85,8 @@ const (
 //
 // It seems to me like some people have been putting whatever the hell they felt like in
 // this space, e.g., I've seen other data in the fields that doesn't align with either
-// format. I'm going to do my part for the future's sanity and pretend like the BINARY
-// version doesn't exist. Let's stick with TEXT and let the other die.
-//
-// -Maybe- we could support reading the BINARY format.
+// format. We only write TEXT, but older tools wrote BINARY, so Read guesses which one a
+// file uses and Id666 decodes both. This struct is the TEXT layout.
 type Id6Tags struct {
 	SongTitle              [32]byte
 	GameTitle              [32]byte
@@ -90,7 +98,7 @@ type Id6Tags struct {
 	Composer               [32]byte
 	InitialChannelDisabled byte
 	FromEmulator           FromEmulatorType
-	_                      [45]byte
+	Reserved               [45]byte
 }
 
 func (spc *SpcFile) Read(r io.Reader) error {
@@ -101,12 +109,22 @@ func (spc *SpcFile) Read(r io.Reader) error {
 		bread(cat, r, &spc.DspRegisters)
 		bread(cat, r, &spc.Reserved)
 		bread(cat, r, &spc.IplRom)
+		spc.TagFormat = spc.DetectTagFormat()
 
 		var err error
 		if spc.Extended, err = io.ReadAll(r); err != nil {
 			return err
 		}
 
+		spc.Xid6 = nil
+		if bytes.HasPrefix(spc.Extended, []byte("xid6")) {
+			reader := bytes.NewReader(spc.Extended)
+			if xid6, err := ReadXid6(reader); err == nil {
+				spc.Xid6 = xid6
+				spc.Extended = spc.Extended[len(spc.Extended)-reader.Len():]
+			}
+		}
+
 		return nil
 	})
 }
@@ -119,21 +137,50 @@ func (spc *SpcFile) Write(w io.Writer) error {
 		bwrite(cat, w, &spc.DspRegisters)
 		bwrite(cat, w, &spc.Reserved)
 		bwrite(cat, w, &spc.IplRom)
+		if spc.Xid6 != nil {
+			cat.Catch(spc.Xid6.Write(w))
+		}
 		bwrite(cat, w, spc.Extended)
 
 		return nil
 	})
 }
 
+// The SPC700 boot program at $FFC0, which receives a program from the SNES and jumps to
+// it.
+var IplRomData = [64]byte{
+	0xCD, 0xEF, 0xBD, 0xE8, 0x00, 0xC6, 0x1D, 0xD0, 0xFC, 0x8F, 0xAA, 0xF4, 0x8F, 0xBB, 0xF5, 0x78,
+	0xCC, 0xF4, 0xD0, 0xFB, 0x2F, 0x19, 0xEB, 0xF4, 0xD0, 0xFC, 0x7E, 0xF4, 0xD0, 0x0B, 0xE4, 0xF5,
+	0xCB, 0xF4, 0xD7, 0x00, 0xFC, 0xD0, 0xF3, 0xAB, 0x01, 0x10, 0xEF, 0x7E, 0xF4, 0x10, 0xEB, 0xBA,
+	0xF6, 0xDA, 0x00, 0xBA, 0xF4, 0xC4, 0xF4, 0xDD, 0x5D, 0xD0, 0xDB, 0x1F, 0x00, 0x00, 0xC0, 0xFF,
+}
+
+// Address of the CONTROL register, and its bit that maps the IPL ROM at $FFC0.
+const (
+	kControlRegister = 0xF1
+	kControlIplRom   = 0x80
+)
+
 func NewSpcFile() *SpcFile {
 	spcf := &SpcFile{}
 	spcf.SetHeaderDefaults()
+	spcf.SetBootState()
 	return spcf
 }
 
 func (spc *SpcFile) SetHeaderDefaults() {
 	copy(spc.Header.Signature[:], "SNES-SPC700 Sound File Data v0.30")
 	spc.Header.FixedBytes = [2]byte{26, 26}
-	spc.Header.HasTags = 26
+	spc.Header.HasTags = kHasTags
 	spc.Header.VersionMinor = 30
+	spc.TagFormat = TagFormatText
+}
+
+// Set the state that the IPL ROM leaves when it jumps to an uploaded program: the ROM is
+// mapped at $FFC0 and the DSP is still in its power-on state.
+func (spc *SpcFile) SetBootState() {
+	copy(spc.Memory[0xFFC0:], IplRomData[:])
+	spc.IplRom = IplRomData
+	spc.Memory[kControlRegister] = kControlIplRom
+	spc.DspRegisters.Reset()
 }
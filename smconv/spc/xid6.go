@@ -0,0 +1,316 @@
+// SNESMOD
+// (C) 2025 Mukunda Johnson (mukunda.com)
+// Licensed under MIT
+
+package spc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"go.mukunda.com/errorcat"
+)
+
+// The extended ID666 (xid6) chunk follows the IPL ROM area at $10200. It's a RIFF-like
+// chunk of items, each with an ID, a type, and a 16-bit length. Small values are stored
+// in the length field itself. Items are padded to 4 bytes.
+// https://www.johnloomis.org/ece320/sound/SPCFormat.pdf
+
+var ErrInvalidXid6 = errors.New("invalid xid6 chunk")
+
+// Lengths in xid6 are counted in ticks of 1/64000 second.
+const Xid6TicksPerSecond = 64000
+
+type Xid6Id = uint8
+
+const (
+	Xid6SongTitle     Xid6Id = 0x01
+	Xid6GameTitle     Xid6Id = 0x02
+	Xid6Artist        Xid6Id = 0x03
+	Xid6Dumper        Xid6Id = 0x04
+	Xid6DateDumped    Xid6Id = 0x05
+	Xid6Emulator      Xid6Id = 0x06
+	Xid6Comments      Xid6Id = 0x07
+	Xid6OstTitle      Xid6Id = 0x10
+	Xid6OstDisc       Xid6Id = 0x11
+	Xid6OstTrack      Xid6Id = 0x12
+	Xid6Publisher     Xid6Id = 0x13
+	Xid6CopyrightYear Xid6Id = 0x14
+	Xid6IntroLength   Xid6Id = 0x30
+	Xid6LoopLength    Xid6Id = 0x31
+	Xid6EndLength     Xid6Id = 0x32
+	Xid6FadeLength    Xid6Id = 0x33
+	Xid6MutedVoices   Xid6Id = 0x34
+	Xid6LoopCount     Xid6Id = 0x35
+	Xid6Amplification Xid6Id = 0x36
+)
+
+type Xid6Type = uint8
+
+const (
+	Xid6TypeData    Xid6Type = 0 // Value is in the length field
+	Xid6TypeString  Xid6Type = 1 // Null-terminated
+	Xid6TypeInteger Xid6Type = 4 // 32-bit
+)
+
+// An xid6 item that isn't one of the known fields, kept as is.
+type Xid6Item struct {
+	Id   Xid6Id
+	Type Xid6Type
+
+	// For data items, this is the 16-bit value. Otherwise, it's the item's data.
+	Value uint16
+	Data  []byte
+}
+
+// The extended tags. Zero values aren't written.
+type Xid6Tags struct {
+	SongTitle string
+	GameTitle string
+	Artist    string
+	Dumper    string
+	Comments  string
+
+	DateDumped time.Time // Zero if it's missing or unreadable
+	Emulator   FromEmulatorType
+
+	OstTitle       string
+	OstDisc        uint8
+	OstTrack       uint8
+	OstTrackSuffix byte // Optional character after the track number, e.g. 'a'
+	Publisher      string
+	CopyrightYear  uint16
+
+	// Play time in ticks (Xid6TicksPerSecond). A player plays the intro, the loop
+	// LoopCount times, then the end, while fading for FadeLength.
+	IntroLength uint32
+	LoopLength  uint32
+	EndLength   uint32
+	FadeLength  uint32
+
+	MutedVoices uint8 // Bit for each voice
+	LoopCount   uint8
+
+	// Amplification level, with 65536 as 1.0.
+	Amplification uint32
+
+	// Items with unknown IDs.
+	Other []Xid6Item
+}
+
+// Convert a duration to xid6 ticks.
+func Xid6Ticks(d time.Duration) uint32 {
+	return uint32(d * Xid6TicksPerSecond / time.Second)
+}
+
+// Convert xid6 ticks to a duration.
+func Xid6Duration(ticks uint32) time.Duration {
+	return time.Duration(ticks) * time.Second / Xid6TicksPerSecond
+}
+
+type xid6ItemHeader struct {
+	Id     Xid6Id
+	Type   Xid6Type
+	Length uint16
+}
+
+// Parse an xid6 chunk, starting with the "xid6" signature.
+func ReadXid6(r io.Reader) (*Xid6Tags, error) {
+	var chunk struct {
+		Signature [4]byte
+		Size      uint32
+	}
+	if err := binary.Read(r, binary.LittleEndian, &chunk); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidXid6, err)
+	}
+	if string(chunk.Signature[:]) != "xid6" {
+		return nil, fmt.Errorf("%w: missing signature", ErrInvalidXid6)
+	}
+
+	// The size comes from the file, so read what's there rather than allocating it up
+	// front.
+	data, err := io.ReadAll(io.LimitReader(r, int64(chunk.Size)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidXid6, err)
+	}
+	if len(data) != int(chunk.Size) {
+		return nil, fmt.Errorf("%w: chunk size %d is past the end of the data", ErrInvalidXid6, chunk.Size)
+	}
+
+	tags := &Xid6Tags{}
+	for pos := 0; pos+4 <= len(data); {
+		header := xid6ItemHeader{data[pos], data[pos+1], binary.LittleEndian.Uint16(data[pos+2:])}
+		pos += 4
+
+		item := Xid6Item{Id: header.Id, Type: header.Type}
+		if header.Type == Xid6TypeData {
+			item.Value = header.Length
+		} else {
+			end := pos + int(header.Length)
+			if end > len(data) {
+				return nil, fmt.Errorf("%w: item %02X is past the end of the chunk", ErrInvalidXid6, header.Id)
+			}
+			item.Data = data[pos:end]
+			pos = (end + 3) &^ 3
+		}
+
+		if err := tags.set(item); err != nil {
+			return nil, err
+		}
+	}
+
+	return tags, nil
+}
+
+func (item *Xid6Item) string() string {
+	if i := bytes.IndexByte(item.Data, 0); i >= 0 {
+		return string(item.Data[:i])
+	}
+	return string(item.Data)
+}
+
+// Load an item into the tags.
+func (tags *Xid6Tags) set(item Xid6Item) error {
+	stringFields := map[Xid6Id]*string{
+		Xid6SongTitle: &tags.SongTitle,
+		Xid6GameTitle: &tags.GameTitle,
+		Xid6Artist:    &tags.Artist,
+		Xid6Dumper:    &tags.Dumper,
+		Xid6Comments:  &tags.Comments,
+		Xid6OstTitle:  &tags.OstTitle,
+		Xid6Publisher: &tags.Publisher,
+	}
+	integerFields := map[Xid6Id]*uint32{
+		Xid6IntroLength:   &tags.IntroLength,
+		Xid6LoopLength:    &tags.LoopLength,
+		Xid6EndLength:     &tags.EndLength,
+		Xid6FadeLength:    &tags.FadeLength,
+		Xid6Amplification: &tags.Amplification,
+	}
+	byteFields := map[Xid6Id]*uint8{
+		Xid6Emulator:    &tags.Emulator,
+		Xid6OstDisc:     &tags.OstDisc,
+		Xid6MutedVoices: &tags.MutedVoices,
+		Xid6LoopCount:   &tags.LoopCount,
+	}
+
+	wrongType := fmt.Errorf("%w: item %02X has the wrong type %d", ErrInvalidXid6, item.Id, item.Type)
+
+	if field, ok := stringFields[item.Id]; ok {
+		if item.Type != Xid6TypeString {
+			return wrongType
+		}
+		*field = item.string()
+	} else if field, ok := integerFields[item.Id]; ok {
+		if item.Type != Xid6TypeInteger || len(item.Data) != 4 {
+			return wrongType
+		}
+		*field = binary.LittleEndian.Uint32(item.Data)
+	} else if field, ok := byteFields[item.Id]; ok {
+		if item.Type != Xid6TypeData {
+			return wrongType
+		}
+		*field = uint8(item.Value)
+	} else if item.Id == Xid6OstTrack {
+		if item.Type != Xid6TypeData {
+			return wrongType
+		}
+		tags.OstTrack = uint8(item.Value >> 8)
+		tags.OstTrackSuffix = uint8(item.Value)
+	} else if item.Id == Xid6CopyrightYear {
+		if item.Type != Xid6TypeData {
+			return wrongType
+		}
+		tags.CopyrightYear = item.Value
+	} else if item.Id == Xid6DateDumped {
+		if item.Type != Xid6TypeInteger || len(item.Data) != 4 {
+			return wrongType
+		}
+		date := int(binary.LittleEndian.Uint32(item.Data))
+		year, month, day := date/10000, date/100%100, date%100
+		if day >= 1 && day <= 31 && month >= 1 && month <= 12 && year > 0 {
+			tags.DateDumped = time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+		}
+	} else {
+		tags.Other = append(tags.Other, item)
+	}
+
+	return nil
+}
+
+// All items to write, in ID order, followed by the unknown items.
+func (tags *Xid6Tags) items() []Xid6Item {
+	items := []Xid6Item{}
+
+	addString := func(id Xid6Id, value string) {
+		if value != "" {
+			items = append(items, Xid6Item{Id: id, Type: Xid6TypeString, Data: append([]byte(value), 0)})
+		}
+	}
+	addInteger := func(id Xid6Id, value uint32) {
+		if value != 0 {
+			items = append(items, Xid6Item{Id: id, Type: Xid6TypeInteger, Data: binary.LittleEndian.AppendUint32(nil, value)})
+		}
+	}
+	addData := func(id Xid6Id, value uint16) {
+		if value != 0 {
+			items = append(items, Xid6Item{Id: id, Type: Xid6TypeData, Value: value})
+		}
+	}
+
+	addString(Xid6SongTitle, tags.SongTitle)
+	addString(Xid6GameTitle, tags.GameTitle)
+	addString(Xid6Artist, tags.Artist)
+	addString(Xid6Dumper, tags.Dumper)
+	if !tags.DateDumped.IsZero() {
+		d := tags.DateDumped
+		addInteger(Xid6DateDumped, uint32(d.Year()*10000+int(d.Month())*100+d.Day()))
+	}
+	addData(Xid6Emulator, uint16(tags.Emulator))
+	addString(Xid6Comments, tags.Comments)
+	addString(Xid6OstTitle, tags.OstTitle)
+	addData(Xid6OstDisc, uint16(tags.OstDisc))
+	addData(Xid6OstTrack, uint16(tags.OstTrack)<<8|uint16(tags.OstTrackSuffix))
+	addString(Xid6Publisher, tags.Publisher)
+	addData(Xid6CopyrightYear, tags.CopyrightYear)
+	addInteger(Xid6IntroLength, tags.IntroLength)
+	addInteger(Xid6LoopLength, tags.LoopLength)
+	addInteger(Xid6EndLength, tags.EndLength)
+	addInteger(Xid6FadeLength, tags.FadeLength)
+	addData(Xid6MutedVoices, uint16(tags.MutedVoices))
+	addData(Xid6LoopCount, uint16(tags.LoopCount))
+	addInteger(Xid6Amplification, tags.Amplification)
+
+	return append(items, tags.Other...)
+}
+
+// Write the xid6 chunk with its signature.
+func (tags *Xid6Tags) Write(w io.Writer) error {
+	return errorcat.Guard(func(cat eC) error {
+		data := &bytes.Buffer{}
+		for _, item := range tags.items() {
+			if item.Type == Xid6TypeData {
+				bwrite(cat, data, xid6ItemHeader{item.Id, item.Type, item.Value})
+				continue
+			}
+
+			if len(item.Data) > 0xFFFF {
+				return fmt.Errorf("%w: item %02X is too long", ErrInvalidXid6, item.Id)
+			}
+			bwrite(cat, data, xid6ItemHeader{item.Id, item.Type, uint16(len(item.Data))})
+			bwrite(cat, data, item.Data)
+			for data.Len()%4 != 0 {
+				data.WriteByte(0)
+			}
+		}
+
+		bwrite(cat, w, []byte("xid6"))
+		bwrite(cat, w, uint32(data.Len()))
+		bwrite(cat, w, data.Bytes())
+		return nil
+	})
+}
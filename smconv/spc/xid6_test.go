@@ -0,0 +1,162 @@
+// SNESMOD
+// (C) 2025 Mukunda Johnson (mukunda.com)
+// Licensed under MIT
+
+package spc
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestXid6RoundTrip(t *testing.T) {
+	tags := &Xid6Tags{
+		SongTitle:      "A song title that is longer than the ID666 field",
+		GameTitle:      "Quest",
+		Artist:         "Someone",
+		Dumper:         "Me",
+		Comments:       "Track 2",
+		DateDumped:     time.Date(2025, 3, 14, 0, 0, 0, 0, time.UTC),
+		Emulator:       FromEmulatorSnesmod,
+		OstTitle:       "Quest OST",
+		OstDisc:        1,
+		OstTrack:       12,
+		OstTrackSuffix: 'b',
+		Publisher:      "Company",
+		CopyrightYear:  1995,
+		IntroLength:    5 * Xid6TicksPerSecond,
+		LoopLength:     60 * Xid6TicksPerSecond,
+		EndLength:      100,
+		FadeLength:     10 * Xid6TicksPerSecond,
+		MutedVoices:    0x81,
+		LoopCount:      2,
+		Amplification:  65536,
+		Other:          []Xid6Item{{Id: 0x40, Type: Xid6TypeString, Data: []byte("abc\x00")}},
+	}
+
+	buf := &bytes.Buffer{}
+	assert.NoError(t, tags.Write(buf))
+	assert.Equal(t, "xid6", string(buf.Bytes()[:4]))
+	assert.Zero(t, buf.Len()%4)
+
+	read, err := ReadXid6(bytes.NewReader(buf.Bytes()))
+	assert.NoError(t, err)
+	assert.Equal(t, tags, read)
+}
+
+func TestXid6Errors(t *testing.T) {
+	_, err := ReadXid6(bytes.NewReader([]byte("xid5\x00\x00\x00\x00")))
+	assert.ErrorIs(t, err, ErrInvalidXid6)
+
+	// Loop length with the string type.
+	_, err = ReadXid6(bytes.NewReader([]byte("xid6\x08\x00\x00\x00\x31\x01\x04\x00abc\x00")))
+	assert.ErrorIs(t, err, ErrInvalidXid6)
+
+	// Item data past the end of the chunk.
+	_, err = ReadXid6(bytes.NewReader([]byte("xid6\x08\x00\x00\x00\x01\x01\x10\x00abc\x00")))
+	assert.ErrorIs(t, err, ErrInvalidXid6)
+
+	_, err = ReadXid6(bytes.NewReader([]byte("xid6\x10\x00\x00\x00\x01\x01")))
+	assert.ErrorIs(t, err, ErrInvalidXid6)
+}
+
+// Counts the bytes read and the reads made after the end of the data.
+type countingReader struct {
+	r          io.Reader
+	n          int
+	pastTheEnd int
+	eof        bool
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	if c.eof {
+		c.pastTheEnd++
+	}
+	n, err := c.r.Read(p)
+	c.n += n
+	if err == io.EOF {
+		c.eof = true
+	}
+	return n, err
+}
+
+func TestXid6CorruptSize(t *testing.T) {
+	// A chunk size far past the end of the data is an error, and reading stops at the
+	// end of the data.
+	for _, input := range []string{"xid6\xF0\xFF\xFF\x7F", "xid6\xFF\xFF\xFF\xFF\x01\x01\x04\x00"} {
+		r := &countingReader{r: bytes.NewReader([]byte(input))}
+		_, err := ReadXid6(r)
+
+		assert.ErrorIs(t, err, ErrInvalidXid6)
+		assert.ErrorContains(t, err, "past the end of the data")
+		assert.Equal(t, len(input), r.n)
+		assert.True(t, r.eof)
+		assert.Zero(t, r.pastTheEnd)
+	}
+
+	// SPC files with a corrupt chunk are still read, with the chunk left in the
+	// extended data.
+	spcf := &SpcFile{}
+	data := make([]byte, 0x10200)
+	copy(data, "SNES-SPC700 Sound File Data v0.30")
+	data = append(data, "xid6\xFF\xFF\xFF\xFF"...)
+	assert.NoError(t, spcf.Read(bytes.NewReader(data)))
+	assert.Nil(t, spcf.Xid6)
+	assert.Equal(t, []byte("xid6\xFF\xFF\xFF\xFF"), spcf.Extended)
+}
+
+func TestXid6NoDate(t *testing.T) {
+	// A date of 0, or one that isn't a real date, is the same as no date.
+	for _, input := range []string{
+		"xid6\x08\x00\x00\x00\x05\x04\x04\x00\x00\x00\x00\x00",
+		"xid6\x08\x00\x00\x00\x05\x04\x04\x00\x63\x00\x00\x00",
+	} {
+		tags, err := ReadXid6(bytes.NewReader([]byte(input)))
+		assert.NoError(t, err)
+		assert.True(t, tags.DateDumped.IsZero())
+	}
+
+	// Tags without a date don't write one.
+	buf := &bytes.Buffer{}
+	assert.NoError(t, (&Xid6Tags{SongTitle: "Song"}).Write(buf))
+	read, err := ReadXid6(bytes.NewReader(buf.Bytes()))
+	assert.NoError(t, err)
+	assert.Equal(t, &Xid6Tags{SongTitle: "Song"}, read)
+}
+
+func TestXid6Ticks(t *testing.T) {
+	assert.EqualValues(t, 64000, Xid6Ticks(time.Second))
+	assert.EqualValues(t, 32, Xid6Ticks(500*time.Microsecond))
+	assert.Equal(t, 1500*time.Millisecond, Xid6Duration(96000))
+}
+
+func TestSpcFileXid6(t *testing.T) {
+	spcf := NewSpcFile()
+	spcf.Xid6 = &Xid6Tags{SongTitle: "Song", LoopLength: 1000}
+	spcf.Extended = []byte("trailing data")
+
+	buf := &bytes.Buffer{}
+	assert.NoError(t, spcf.Write(buf))
+
+	read := &SpcFile{}
+	assert.NoError(t, read.Read(bytes.NewReader(buf.Bytes())))
+	assert.Equal(t, spcf.Xid6, read.Xid6)
+	assert.Equal(t, []byte("trailing data"), read.Extended)
+
+	// Something that isn't xid6 is kept as is.
+	spcf.Xid6 = nil
+	spcf.Extended = []byte("xid6 but not really")
+	buf.Reset()
+	assert.NoError(t, spcf.Write(buf))
+	assert.NoError(t, read.Read(bytes.NewReader(buf.Bytes())))
+	assert.Nil(t, read.Xid6)
+	assert.Equal(t, spcf.Extended, read.Extended)
+
+	out := &bytes.Buffer{}
+	assert.NoError(t, read.Write(out))
+	assert.Equal(t, buf.Bytes(), out.Bytes())
+}
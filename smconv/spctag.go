@@ -0,0 +1,90 @@
+// SNESMOD
+// (C) 2025 Mukunda Johnson (mukunda.com)
+// Licensed under MIT
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+
+	"go.mukunda.com/snesmod/smconv/clog"
+	"go.mukunda.com/snesmod/smconv/smconv"
+	"go.mukunda.com/snesmod/smconv/spc"
+)
+
+// Entry point for "smconv spc-tag". Sets the tags in existing SPC files, keeping
+// everything else in them. Returns 1 if any file can't be updated.
+func spcTagCli(args []string) int {
+	flags := flag.NewFlagSet(os.Args[0]+" spc-tag", flag.ContinueOnError)
+	tags := smconv.ModuleTags{}
+	addTagFlags(flags, &tags)
+
+	// Options can come before or after the files.
+	inputFiles := []string{}
+	for {
+		if err := flags.Parse(args); err != nil {
+			clog.Errorf("%v\n", err)
+			return 1
+		}
+		if flags.NArg() == 0 {
+			break
+		}
+		inputFiles = append(inputFiles, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+
+	if err := tags.Validate(); err != nil {
+		clog.Errorf("%v\n", err)
+		return 1
+	}
+
+	if (tags == smconv.ModuleTags{}) {
+		clog.Errorln("No tags to set.")
+		return 1
+	}
+
+	if len(inputFiles) == 0 {
+		clog.Errorln("No input files specified.")
+		return 1
+	}
+
+	status := 0
+	for _, inputFile := range inputFiles {
+		if err := retagSpcFile(inputFile, &tags); err != nil {
+			clog.Errorf("Error tagging %s: %v\n", inputFile, err)
+			status = 1
+			continue
+		}
+		clog.Infoln("Tagged:", inputFile)
+	}
+
+	return status
+}
+
+// Rewrite an SPC file with new tags.
+func retagSpcFile(filename string, tags *smconv.ModuleTags) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	spcf := &spc.SpcFile{}
+	if err := spcf.Read(bytes.NewReader(data)); err != nil {
+		return fmt.Errorf("not an SPC file: %w", err)
+	}
+	if !bytes.HasPrefix(spcf.Header.Signature[:], []byte("SNES-SPC700 Sound File Data")) {
+		return fmt.Errorf("not an SPC file: missing signature")
+	}
+	if err := tags.ApplyToSpc(spcf); err != nil {
+		return err
+	}
+
+	output := &bytes.Buffer{}
+	if err := spcf.Write(output); err != nil {
+		return err
+	}
+	return os.WriteFile(filename, output.Bytes(), 0644)
+}
@@ -0,0 +1,40 @@
+// SNESMOD
+// (C) 2025 Mukunda Johnson (mukunda.com)
+// Licensed under MIT
+
+package main
+
+import (
+	"flag"
+	"strconv"
+
+	"go.mukunda.com/snesmod/smconv/smconv"
+)
+
+// Add the SPC tag flags to a command. Unset tags keep the module's own tags.
+func addTagFlags(flags *flag.FlagSet, tags *smconv.ModuleTags) {
+	flags.StringVar(&tags.Title, "title", "", "SPC song title")
+	flags.StringVar(&tags.Game, "game", "", "SPC game title")
+	flags.StringVar(&tags.Author, "artist", "", "SPC artist/composer")
+	flags.StringVar(&tags.DumpedBy, "dumper", "", "SPC dumper name")
+	flags.StringVar(&tags.Comments, "comment", "", "SPC comments")
+	flags.StringVar(&tags.Date, "date", "", "SPC date (YYYY-MM-DD)")
+
+	addPlayTimeFlags(flags, tags)
+}
+
+// Add only the --length and --fade flags.
+func addPlayTimeFlags(flags *flag.FlagSet, tags *smconv.ModuleTags) {
+	intFlag := func(name string, usage string, value **int) {
+		flags.Func(name, usage, func(s string) error {
+			v, err := strconv.Atoi(s)
+			if err != nil {
+				return err
+			}
+			*value = &v
+			return nil
+		})
+	}
+	intFlag("length", "SPC play time in seconds before fading out", &tags.Length)
+	intFlag("fade", "SPC fade out time in milliseconds", &tags.Fade)
+}